@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
 	"assetronics-agent/collector"
 	"assetronics-agent/config"
+	"assetronics-agent/metering"
+	"assetronics-agent/sbom"
 	"assetronics-agent/scanner"
 )
 
@@ -31,6 +34,8 @@ type CheckInRequest struct {
 	Username     string `json:"username"`
 	SerialNumber string `json:"serial_number"`
 	OS           string `json:"os"`
+	DistroID     string `json:"distro_id,omitempty"`
+	DistroVersion string `json:"distro_version,omitempty"`
 	Platform     string `json:"platform"`
 	IPAddress    string `json:"ip_address"`
 	MACAddress   string `json:"mac_address"`
@@ -42,14 +47,37 @@ type CheckInRequest struct {
 	DiskTotalGB  int    `json:"disk_total_gb"`
 	DiskFreeGB   int    `json:"disk_free_gb"`
 	InstalledSoftware []collector.Software `json:"installed_software"`
+	Security     *collector.SecurityInfo `json:"security,omitempty"`
+	Updates      *collector.UpdateStatus `json:"updates,omitempty"`
+	Containers   []collector.Container   `json:"containers,omitempty"`
+	collector.VirtualizationInfo
+	Peripherals  []collector.Peripheral  `json:"peripherals,omitempty"`
+	Monitors     []collector.Monitor     `json:"monitors,omitempty"`
+	PrimaryUser  string                   `json:"primary_user,omitempty"`
+	LocalUsers   []collector.UserAccount  `json:"local_users,omitempty"`
+	ActiveSessions []collector.LoginSession `json:"active_sessions,omitempty"`
+	Services     []collector.Service       `json:"services,omitempty"`
+	ListeningPorts []collector.ListeningPort `json:"listening_ports,omitempty"`
+	BrowserExtensions []collector.BrowserExtension `json:"browser_extensions,omitempty"`
+	Certificates   []collector.Certificate   `json:"certificates,omitempty"`
+	SoftwareUsage  []collector.SoftwareUsage `json:"software_usage,omitempty"`
+	Vulnerabilities []collector.Vulnerability `json:"vulnerabilities,omitempty"`
 }
 
-func (c *Client) CheckIn(info *collector.SystemInfo) error {
+// CheckInResponse carries settings the backend pushes to the agent. Older
+// backends reply with an empty body, which leaves everything unset.
+type CheckInResponse struct {
+	MeteredApps []metering.App `json:"metered_apps"` // nil when the backend did not send a list
+}
+
+func (c *Client) CheckIn(info *collector.SystemInfo) (*CheckInResponse, error) {
 	payload := CheckInRequest{
 		Hostname:     info.Hostname,
 		Username:     info.Username,
 		SerialNumber: info.SerialNumber,
 		OS:           info.OS,
+		DistroID:     info.DistroID,
+		DistroVersion: info.DistroVersion,
 		Platform:     info.Platform,
 		IPAddress:    info.IPAddress,
 		MACAddress:   info.MACAddress,
@@ -61,17 +89,32 @@ func (c *Client) CheckIn(info *collector.SystemInfo) error {
 		DiskTotalGB:  info.DiskTotalGB,
 		DiskFreeGB:   info.DiskFreeGB,
 		InstalledSoftware: info.InstalledSoftware,
+		Security:     info.Security,
+		Updates:      info.Updates,
+		Containers:   info.Containers,
+		VirtualizationInfo: info.VirtualizationInfo,
+		Peripherals:  info.Peripherals,
+		Monitors:     info.Monitors,
+		PrimaryUser:  info.PrimaryUser,
+		LocalUsers:   info.LocalUsers,
+		ActiveSessions: info.ActiveSessions,
+		Services:     info.Services,
+		ListeningPorts: info.ListeningPorts,
+		BrowserExtensions: info.BrowserExtensions,
+		Certificates:   info.Certificates,
+		SoftwareUsage:  info.SoftwareUsage,
+		Vulnerabilities: info.Vulnerabilities,
 	}
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
 	url := fmt.Sprintf("%s/agent/checkin", c.Config.APIURL)
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -85,27 +128,41 @@ func (c *Client) CheckIn(info *collector.SystemInfo) error {
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send check-in request: %w", err)
+		return nil, fmt.Errorf("failed to send check-in request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("check-in failed with status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("check-in failed with status: %d", resp.StatusCode)
 	}
 
-	return nil
+	// The check-in was accepted, so an unreadable body is not an error
+	result := &CheckInResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil && err != io.EOF {
+		fmt.Printf("Warning: ignoring unreadable check-in response: %v\n", err)
+		return &CheckInResponse{}, nil
+	}
+	return result, nil
+}
+
+// ScanResponse carries the scanner policy the backend pushes to scanner
+// agents. Older backends reply with an empty body.
+type ScanResponse struct {
+	// Cron expression replacing the configured schedule; "" reverts to the
+	// configured one, and a reply without the field changes nothing
+	Schedule *string `json:"scan_schedule"`
 }
 
-func (c *Client) SendScanResults(result *scanner.ScanResult) error {
+func (c *Client) SendScanResults(result *scanner.ScanResult) (*ScanResponse, error) {
 	jsonData, err := json.Marshal(result)
 	if err != nil {
-		return fmt.Errorf("failed to marshal scan result: %w", err)
+		return nil, fmt.Errorf("failed to marshal scan result: %w", err)
 	}
 
 	url := fmt.Sprintf("%s/agent/scan", c.Config.APIURL)
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -118,12 +175,47 @@ func (c *Client) SendScanResults(result *scanner.ScanResult) error {
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send scan results: %w", err)
+		return nil, fmt.Errorf("failed to send scan results: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("scan upload failed with status: %d", resp.StatusCode)
+	}
+
+	// The upload was accepted, so an unreadable body is not an error
+	policy := &ScanResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(policy); err != nil && err != io.EOF {
+		fmt.Printf("Warning: ignoring unreadable scan upload response: %v\n", err)
+		return &ScanResponse{}, nil
+	}
+	return policy, nil
+}
+
+// SendSBOM uploads a CycloneDX document for this machine.
+func (c *Client) SendSBOM(document []byte) error {
+	url := fmt.Sprintf("%s/agent/sbom", c.Config.APIURL)
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(document))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", sbom.MediaType)
+	if c.Config.TenantID != "" {
+		req.Header.Set("X-Tenant-ID", c.Config.TenantID)
+	}
+	if c.Config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Config.APIKey)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SBOM: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("scan upload failed with status: %d", resp.StatusCode)
+		return fmt.Errorf("SBOM upload failed with status: %d", resp.StatusCode)
 	}
 
 	return nil
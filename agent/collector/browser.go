@@ -0,0 +1,310 @@
+package collector
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// BrowserExtension is an extension installed in one browser profile.
+type BrowserExtension struct {
+	Browser     string   `json:"browser"` // "chrome", "chromium", "edge", "brave", "firefox"
+	User        string   `json:"user"`    // Owner of the home directory holding the profile
+	Profile     string   `json:"profile"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Version     string   `json:"version,omitempty"`
+	Permissions []string `json:"permissions,omitempty"` // API permissions and host patterns
+	Enabled     bool     `json:"enabled"`
+}
+
+// Chromium-based browsers' user data directories, relative to the home
+// directory. On Linux the Snap and Flatpak builds keep theirs inside the
+// sandbox.
+func chromiumUserDataDirs() map[string][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return map[string][]string{
+			"chrome":   {"Library/Application Support/Google/Chrome"},
+			"chromium": {"Library/Application Support/Chromium"},
+			"edge":     {"Library/Application Support/Microsoft Edge"},
+			"brave":    {"Library/Application Support/BraveSoftware/Brave-Browser"},
+		}
+	case "windows":
+		return map[string][]string{
+			"chrome":   {`AppData\Local\Google\Chrome\User Data`},
+			"chromium": {`AppData\Local\Chromium\User Data`},
+			"edge":     {`AppData\Local\Microsoft\Edge\User Data`},
+			"brave":    {`AppData\Local\BraveSoftware\Brave-Browser\User Data`},
+		}
+	default:
+		return map[string][]string{
+			"chrome": {
+				".config/google-chrome",
+				".var/app/com.google.Chrome/config/google-chrome",
+			},
+			"chromium": {
+				".config/chromium",
+				"snap/chromium/common/chromium",
+				".var/app/org.chromium.Chromium/config/chromium",
+			},
+			"edge": {
+				".config/microsoft-edge",
+				".var/app/com.microsoft.Edge/config/microsoft-edge",
+			},
+			"brave": {
+				".config/BraveSoftware/Brave-Browser",
+				"snap/brave/current/.config/BraveSoftware/Brave-Browser",
+				".var/app/com.brave.Browser/config/BraveSoftware/Brave-Browser",
+			},
+		}
+	}
+}
+
+// firefoxProfilesDirs are where Firefox keeps its profiles, relative to the
+// home directory. Ubuntu ships Firefox as a Snap.
+func firefoxProfilesDirs() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"Library/Application Support/Firefox/Profiles"}
+	case "windows":
+		return []string{`AppData\Roaming\Mozilla\Firefox\Profiles`}
+	default:
+		return []string{
+			".mozilla/firefox",
+			"snap/firefox/common/.mozilla/firefox",
+			".var/app/org.mozilla.firefox/.mozilla/firefox",
+		}
+	}
+}
+
+// firefoxProfiles returns the paths of the named file in every Firefox
+// profile under home.
+func firefoxProfiles(home, file string) []string {
+	var paths []string
+	for _, dir := range firefoxProfilesDirs() {
+		matches, _ := filepath.Glob(filepath.Join(home, dir, "*", file))
+		paths = append(paths, matches...)
+	}
+	return paths
+}
+
+// GetBrowserExtensions walks every user's browser profiles.
+func GetBrowserExtensions() []BrowserExtension {
+	extensions := []BrowserExtension{}
+	for _, home := range userHomeDirs() {
+		user := filepath.Base(home)
+		for browser, dirs := range chromiumUserDataDirs() {
+			for _, dir := range dirs {
+				profiles, _ := filepath.Glob(filepath.Join(home, dir, "*", "Extensions"))
+				for _, extDir := range profiles {
+					extensions = append(extensions, getChromiumExtensions(browser, user, filepath.Dir(extDir))...)
+				}
+			}
+		}
+		for _, path := range firefoxProfiles(home, "extensions.json") {
+			extensions = append(extensions, getFirefoxExtensions(user, path)...)
+		}
+	}
+
+	sort.SliceStable(extensions, func(i, j int) bool {
+		a, b := extensions[i], extensions[j]
+		if a.User != b.User {
+			return a.User < b.User
+		}
+		if a.Browser != b.Browser {
+			return a.Browser < b.Browser
+		}
+		return a.Profile < b.Profile
+	})
+	return extensions
+}
+
+// getChromiumExtensions reads <profile>/Extensions/<id>/<version>/manifest.json
+// and takes the enabled state from the profile's preferences.
+func getChromiumExtensions(browser, user, profileDir string) []BrowserExtension {
+	states := chromiumExtensionStates(profileDir)
+
+	var extensions []BrowserExtension
+	idDirs, _ := os.ReadDir(filepath.Join(profileDir, "Extensions"))
+	for _, idDir := range idDirs {
+		// Chrome stages downloads in "Temp"
+		if !idDir.IsDir() || idDir.Name() == "Temp" {
+			continue
+		}
+		versions, _ := filepath.Glob(filepath.Join(profileDir, "Extensions", idDir.Name(), "*", "manifest.json"))
+		if len(versions) == 0 {
+			continue
+		}
+		// An update can leave the old version behind until restart; the
+		// newest manifest is the one in use
+		latest := versions[0]
+		for _, v := range versions[1:] {
+			if modifiedAfter(v, latest) {
+				latest = v
+			}
+		}
+		versionDir := filepath.Dir(latest)
+
+		var manifest struct {
+			Name            string            `json:"name"`
+			Version         string            `json:"version"`
+			DefaultLocale   string            `json:"default_locale"`
+			Permissions     []json.RawMessage `json:"permissions"`
+			HostPermissions []string          `json:"host_permissions"`
+		}
+		content, err := os.ReadFile(filepath.Join(versionDir, "manifest.json"))
+		if err != nil || json.Unmarshal(content, &manifest) != nil {
+			continue
+		}
+
+		ext := BrowserExtension{
+			Browser: browser,
+			User:    user,
+			Profile: filepath.Base(profileDir),
+			ID:      idDir.Name(),
+			Name:    resolveChromiumMessage(manifest.Name, versionDir, manifest.DefaultLocale),
+			Version: manifest.Version,
+			Enabled: true,
+		}
+		for _, raw := range manifest.Permissions {
+			// Most permissions are strings; a few legacy ones are objects
+			// such as {"fileSystem": ["write"]}
+			var s string
+			var obj map[string]json.RawMessage
+			if json.Unmarshal(raw, &s) == nil {
+				ext.Permissions = append(ext.Permissions, s)
+			} else if json.Unmarshal(raw, &obj) == nil {
+				for k := range obj {
+					ext.Permissions = append(ext.Permissions, k)
+				}
+			}
+		}
+		ext.Permissions = append(ext.Permissions, manifest.HostPermissions...)
+		if enabled, ok := states[ext.ID]; ok {
+			ext.Enabled = enabled
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions
+}
+
+func modifiedAfter(a, b string) bool {
+	fa, errA := os.Stat(a)
+	fb, errB := os.Stat(b)
+	return errA == nil && errB == nil && fa.ModTime().After(fb.ModTime())
+}
+
+// chromiumExtensionStates reads extensions.settings from Preferences and
+// Secure Preferences (which one holds it varies by platform and version).
+// Older builds use "state" (0 disabled, 1 enabled); newer ones drop it
+// and list "disable_reasons" instead.
+func chromiumExtensionStates(profileDir string) map[string]bool {
+	states := map[string]bool{}
+	for _, name := range []string{"Preferences", "Secure Preferences"} {
+		var prefs struct {
+			Extensions struct {
+				Settings map[string]struct {
+					State          *int            `json:"state"`
+					DisableReasons json.RawMessage `json:"disable_reasons"`
+				} `json:"settings"`
+			} `json:"extensions"`
+		}
+		content, err := os.ReadFile(filepath.Join(profileDir, name))
+		if err != nil || json.Unmarshal(content, &prefs) != nil {
+			continue
+		}
+		for id, s := range prefs.Extensions.Settings {
+			reasons := strings.TrimSpace(string(s.DisableReasons))
+			switch {
+			case s.State != nil:
+				states[id] = *s.State == 1
+			case reasons != "" && reasons != "0" && reasons != "[]":
+				states[id] = false
+			}
+		}
+	}
+	return states
+}
+
+// resolveChromiumMessage replaces a "__MSG_name__" placeholder with the
+// default locale's translation. Message keys are case-insensitive.
+func resolveChromiumMessage(value, extDir, locale string) string {
+	if !strings.HasPrefix(value, "__MSG_") || !strings.HasSuffix(value, "__") {
+		return value
+	}
+	key := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(value, "__MSG_"), "__"))
+	if locale == "" {
+		locale = "en"
+	}
+
+	content, err := os.ReadFile(filepath.Join(extDir, "_locales", locale, "messages.json"))
+	if err != nil {
+		return value
+	}
+	var messages map[string]struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(content, &messages) != nil {
+		return value
+	}
+	for k, m := range messages {
+		if strings.ToLower(k) == key {
+			return m.Message
+		}
+	}
+	return value
+}
+
+// getFirefoxExtensions parses a profile's extensions.json. Add-ons shipped
+// with Firefox itself (built-in themes, system add-ons) are skipped.
+func getFirefoxExtensions(user, path string) []BrowserExtension {
+	var data struct {
+		Addons []struct {
+			ID            string `json:"id"`
+			Type          string `json:"type"`
+			Version       string `json:"version"`
+			Active        bool   `json:"active"`
+			Location      string `json:"location"`
+			DefaultLocale struct {
+				Name string `json:"name"`
+			} `json:"defaultLocale"`
+			UserPermissions *struct {
+				Permissions []string `json:"permissions"`
+				Origins     []string `json:"origins"`
+			} `json:"userPermissions"`
+		} `json:"addons"`
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(content, &data) != nil {
+		return nil
+	}
+
+	var extensions []BrowserExtension
+	for _, a := range data.Addons {
+		if a.Type != "extension" {
+			continue
+		}
+		switch a.Location {
+		case "app-builtin", "app-system-defaults", "app-system-addons":
+			continue
+		}
+		ext := BrowserExtension{
+			Browser: "firefox",
+			User:    user,
+			Profile: filepath.Base(filepath.Dir(path)),
+			ID:      a.ID,
+			Name:    a.DefaultLocale.Name,
+			Version: a.Version,
+			Enabled: a.Active,
+		}
+		if a.UserPermissions != nil {
+			ext.Permissions = append(append(ext.Permissions, a.UserPermissions.Permissions...), a.UserPermissions.Origins...)
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions
+}
@@ -0,0 +1,320 @@
+package collector
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Certificate is an X.509 certificate found in a trust or key store.
+type Certificate struct {
+	Store         string   `json:"store"`          // File path, keychain, NSS database or Windows store
+	User          string   `json:"user,omitempty"` // Owner, for per-user stores
+	Subject       string   `json:"subject"`
+	Issuer        string   `json:"issuer"`
+	SerialNumber  string   `json:"serial_number"` // Hex
+	SANs          []string `json:"sans,omitempty"`
+	KeyType       string   `json:"key_type"` // e.g. "RSA 2048", "ECDSA P-256", "Ed25519"
+	NotBefore     string   `json:"not_before"`
+	NotAfter      string   `json:"not_after"`
+	IsCA          bool     `json:"is_ca"`
+	HasPrivateKey bool     `json:"has_private_key"`
+	SHA256        string   `json:"sha256"` // Fingerprint of the DER encoding
+}
+
+// Largest file considered when walking certificate directories
+const maxCertFileSize = 1 << 20
+
+// certInventory accumulates certificates, reporting each one once per
+// owner (trust directories and bundles hold the same CA several times).
+type certInventory struct {
+	certs []Certificate
+	seen  map[string]bool
+}
+
+func newCertInventory() *certInventory {
+	return &certInventory{seen: map[string]bool{}}
+}
+
+func (inv *certInventory) add(cert *x509.Certificate, store, user string, hasKey bool) {
+	c := describeCertificate(cert)
+	key := c.SHA256 + "|" + user
+	if inv.seen[key] {
+		return
+	}
+	inv.seen[key] = true
+	c.Store, c.User, c.HasPrivateKey = store, user, hasKey
+	inv.certs = append(inv.certs, c)
+}
+
+func (inv *certInventory) sorted() []Certificate {
+	sort.SliceStable(inv.certs, func(i, j int) bool { return inv.certs[i].NotAfter < inv.certs[j].NotAfter })
+	if inv.certs == nil {
+		return []Certificate{}
+	}
+	return inv.certs
+}
+
+func describeCertificate(cert *x509.Certificate) Certificate {
+	sum := sha256.Sum256(cert.Raw)
+	c := Certificate{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: hex.EncodeToString(cert.SerialNumber.Bytes()),
+		KeyType:      describeKey(cert.PublicKey),
+		NotBefore:    cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:     cert.NotAfter.UTC().Format(time.RFC3339),
+		IsCA:         cert.IsCA,
+		SHA256:       hex.EncodeToString(sum[:]),
+	}
+	c.SANs = append(c.SANs, cert.DNSNames...)
+	c.SANs = append(c.SANs, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		c.SANs = append(c.SANs, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		c.SANs = append(c.SANs, uri.String())
+	}
+	return c
+}
+
+func describeKey(pub any) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return "unknown"
+	}
+}
+
+// parseCertificateFile returns the certificates in a PEM bundle or DER
+// file, the public keys of any private keys stored alongside them, and
+// whether the file also holds a passphrase-protected key, whose public
+// half cannot be read.
+func parseCertificateFile(content []byte) ([]*x509.Certificate, []crypto.PublicKey, bool) {
+	var certs []*x509.Certificate
+	var keys []crypto.PublicKey
+	encrypted := false
+
+	if !bytes.Contains(content, []byte("-----BEGIN")) {
+		if cert, err := x509.ParseCertificate(content); err == nil {
+			certs = append(certs, cert)
+		}
+		return certs, keys, encrypted
+	}
+
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE", "TRUSTED CERTIFICATE":
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				certs = append(certs, cert)
+			}
+		case "ENCRYPTED PRIVATE KEY":
+			encrypted = true
+		case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+			// Legacy OpenSSL encryption: Proc-Type: 4,ENCRYPTED
+			if strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+				encrypted = true
+			} else if pub := privateKeyPublic(block.Bytes); pub != nil {
+				keys = append(keys, pub)
+			}
+		}
+	}
+	return certs, keys, encrypted
+}
+
+func privateKeyPublic(der []byte) crypto.PublicKey {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer.Public()
+		}
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key.Public()
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key.Public()
+	}
+	return nil
+}
+
+// addCertificatePaths walks files and directories for PEM/DER certificates
+// and private keys. A certificate has a private key when a key with the
+// same public key was found anywhere in the same walk, which covers the
+// usual certs/ and private/ split, or when it is the first certificate in
+// a file that also holds an encrypted key.
+func (inv *certInventory) addCertificatePaths(paths []string, user string) {
+	type found struct {
+		cert   *x509.Certificate
+		path   string
+		hasKey bool
+	}
+	var certs []found
+	var keys []crypto.PublicKey
+
+	for _, root := range paths {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := os.Stat(path) // Follows the hash symlinks in /etc/ssl/certs
+			if err != nil || !info.Mode().IsRegular() || info.Size() > maxCertFileSize {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".pem", ".crt", ".cer", ".der", ".key", ".0", "":
+			default:
+				// Hash links are named <hash>.<n>
+				if !isDigits(strings.TrimPrefix(filepath.Ext(path), ".")) {
+					return nil
+				}
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			// Report the link target rather than the hash name
+			if real, err := filepath.EvalSymlinks(path); err == nil {
+				path = real
+			}
+			c, k, encrypted := parseCertificateFile(content)
+			for i, cert := range c {
+				// The leaf comes first, ahead of its chain
+				certs = append(certs, found{cert, path, encrypted && i == 0})
+			}
+			keys = append(keys, k...)
+			return nil
+		})
+	}
+
+	for _, f := range certs {
+		inv.add(f.cert, f.path, user, f.hasKey || matchesAnyKey(f.cert.PublicKey, keys))
+	}
+}
+
+func isDigits(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func matchesAnyKey(pub crypto.PublicKey, keys []crypto.PublicKey) bool {
+	eq, ok := pub.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return false
+	}
+	for _, k := range keys {
+		if eq.Equal(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// PKCS#11 attribute columns and object classes used by NSS's sqlite
+// databases. Columns are named "a" + the attribute type in hex.
+const (
+	nssColClass        = "a0"   // CKA_CLASS
+	nssColValue        = "a11"  // CKA_VALUE
+	nssColID           = "a102" // CKA_ID
+	nssClassCert       = 1      // CKO_CERTIFICATE
+	nssClassPrivateKey = 3      // CKO_PRIVATE_KEY
+)
+
+// addNSSDatabase reads certificates from an NSS cert9.db (Chrome on Linux,
+// Firefox everywhere). A certificate has a private key when key4.db in the
+// same directory holds a key with the same CKA_ID.
+func (inv *certInventory) addNSSDatabase(dir, user string) {
+	keyIDs := map[string]bool{}
+	readNSSObjects(filepath.Join(dir, "key4.db"), "nssPrivate", func(class uint32, values map[string]any) {
+		if class == nssClassPrivateKey {
+			if id, ok := values[nssColID].([]byte); ok {
+				keyIDs[string(id)] = true
+			}
+		}
+	})
+
+	store := "nss:" + dir
+	readNSSObjects(filepath.Join(dir, "cert9.db"), "nssPublic", func(class uint32, values map[string]any) {
+		if class != nssClassCert {
+			return
+		}
+		der, ok := values[nssColValue].([]byte)
+		if !ok {
+			return
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return
+		}
+		id, _ := values[nssColID].([]byte)
+		inv.add(cert, store, user, len(id) > 0 && keyIDs[string(id)])
+	})
+}
+
+func readNSSObjects(path, table string, fn func(class uint32, values map[string]any)) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	columns, err := db.tableColumns(table)
+	if err != nil {
+		return
+	}
+	db.tableRows(table, func(_ int64, row []any) error {
+		values := map[string]any{}
+		for i, v := range row {
+			if i < len(columns) {
+				values[columns[i]] = v
+			}
+		}
+		// CK_ULONG attributes are stored as 4-byte big-endian blobs
+		if class, ok := values[nssColClass].([]byte); ok && len(class) == 4 {
+			fn(binary.BigEndian.Uint32(class), values)
+		}
+		return nil
+	})
+}
+
+// addUserNSSDatabases covers each user's shared NSS database and Firefox
+// profiles, including the Snap and Flatpak ones.
+func (inv *certInventory) addUserNSSDatabases() {
+	for _, home := range userHomeDirs() {
+		user := filepath.Base(home)
+		dirs := []string{filepath.Join(home, ".pki", "nssdb")}
+		for _, p := range firefoxProfiles(home, "cert9.db") {
+			dirs = append(dirs, filepath.Dir(p))
+		}
+		for _, dir := range dirs {
+			inv.addNSSDatabase(dir, user)
+		}
+	}
+}
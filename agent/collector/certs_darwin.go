@@ -0,0 +1,51 @@
+//go:build darwin
+
+package collector
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+)
+
+// getMacOSCertificates reads the system keychains and each user's login
+// keychain through the security tool, then extra paths and Firefox's NSS
+// databases.
+func getMacOSCertificates(extraPaths []string) []Certificate {
+	inv := newCertInventory()
+	for _, kc := range []string{"/Library/Keychains/System.keychain", "/System/Library/Keychains/SystemRootCertificates.keychain"} {
+		addKeychain(inv, kc, "")
+	}
+	for _, home := range userHomeDirs() {
+		addKeychain(inv, filepath.Join(home, "Library", "Keychains", "login.keychain-db"), filepath.Base(home))
+	}
+	inv.addCertificatePaths(extraPaths, "")
+	inv.addUserNSSDatabases()
+	return inv.sorted()
+}
+
+// addKeychain exports a keychain's certificates as PEM. Certificates with a
+// private key show up as identities, listed by SHA-1:
+//
+//  1. 2B3C4D...E9F0 "Acme Wi-Fi"
+func addKeychain(inv *certInventory, keychain, user string) {
+	pemData := runCommand("security", "find-certificate", "-a", "-p", keychain)
+	if pemData == "" {
+		return
+	}
+
+	identities := map[string]bool{}
+	for _, line := range strings.Split(runCommand("security", "find-identity", keychain), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && strings.HasSuffix(fields[0], ")") && len(fields[1]) == 40 {
+			identities[strings.ToUpper(fields[1])] = true
+		}
+	}
+
+	certs, _, _ := parseCertificateFile([]byte(pemData))
+	for _, cert := range certs {
+		sum := sha1.Sum(cert.Raw)
+		inv.add(cert, keychain, user, identities[strings.ToUpper(hex.EncodeToString(sum[:]))])
+	}
+}
@@ -0,0 +1,13 @@
+//go:build linux
+
+package collector
+
+// getLinuxCertificates reads the system trust stores and private key
+// directories, any extra paths, and every user's NSS databases.
+func getLinuxCertificates(extraPaths []string) []Certificate {
+	inv := newCertInventory()
+	paths := []string{"/etc/ssl/certs", "/etc/ssl/private", "/etc/pki"}
+	inv.addCertificatePaths(append(paths, extraPaths...), "")
+	inv.addUserNSSDatabases()
+	return inv.sorted()
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddCertificatePathsEncryptedKey(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCert := func(name string, ca bool) []byte {
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(time.Now().UnixNano()),
+			Subject:               pkix.Name{CommonName: name},
+			NotBefore:             time.Now(),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  ca,
+			BasicConstraintsValid: true,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+
+	// Leaf, its chain and a PKCS#8 key that cannot be read without the
+	// passphrase
+	var bundle []byte
+	bundle = append(bundle, newCert("leaf", false)...)
+	bundle = append(bundle, newCert("intermediate", true)...)
+	bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte{0x30, 0x00}})...)
+	path := filepath.Join(t.TempDir(), "server.pem")
+	if err := os.WriteFile(path, bundle, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	inv := newCertInventory()
+	inv.addCertificatePaths([]string{path}, "")
+	got := map[string]bool{}
+	for _, c := range inv.sorted() {
+		got[c.Subject] = c.HasPrivateKey
+	}
+	want := map[string]bool{"CN=leaf": true, "CN=intermediate": false}
+	if len(got) != len(want) || got["CN=leaf"] != want["CN=leaf"] || got["CN=intermediate"] != want["CN=intermediate"] {
+		t.Errorf("HasPrivateKey = %v, want %v", got, want)
+	}
+}
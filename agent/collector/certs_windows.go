@@ -0,0 +1,137 @@
+//go:build windows
+
+package collector
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Machine stores plus the agent account's personal store. Users' stores
+// are read from their registry hives by addWindowsUserStores.
+const windowsCertificatesScript = `Get-ChildItem 'Cert:\LocalMachine\My', 'Cert:\LocalMachine\Root', 'Cert:\LocalMachine\CA', 'Cert:\LocalMachine\Remote Desktop', 'Cert:\CurrentUser\My' -ErrorAction SilentlyContinue |
+Where-Object { $_.RawData } |
+ForEach-Object { "$($_.PSParentPath -replace '^.*::','')|$($_.HasPrivateKey)|$([Convert]::ToBase64String($_.RawData))" }`
+
+// getWindowsCertificates reads the certificate stores through PowerShell's
+// Cert: drive, then the logged-on users' stores, extra paths and Firefox's
+// NSS databases.
+func getWindowsCertificates(extraPaths []string) []Certificate {
+	inv := newCertInventory()
+
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsCertificatesScript)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err == nil {
+		// LocalMachine\My|True|MIIF...
+		for _, line := range strings.Split(out.String(), "\n") {
+			parts := strings.Split(strings.TrimSpace(line), "|")
+			if len(parts) != 3 {
+				continue
+			}
+			der, err := base64.StdEncoding.DecodeString(parts[2])
+			if err != nil {
+				continue
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				continue
+			}
+			inv.add(cert, parts[0], "", strings.EqualFold(parts[1], "True"))
+		}
+	}
+
+	inv.addWindowsUserStores()
+	inv.addCertificatePaths(extraPaths, "")
+	inv.addUserNSSDatabases()
+	return inv.sorted()
+}
+
+// Per-user stores read from the registry
+var windowsUserCertStores = []string{"My", "Root", "CA", "TrustedPeople"}
+
+// Property IDs in a serialized certificate store element
+const (
+	certKeyProvInfoPropID = 2  // CERT_KEY_PROV_INFO_PROP_ID: linked private key
+	certCertPropID        = 32 // CERT_CERT_PROP_ID: DER certificate
+)
+
+// addWindowsUserStores reads the stores of every user whose hive is loaded,
+// i.e. who is logged on. The agent runs as SYSTEM, whose Cert:\CurrentUser
+// is its own, so each user's HKU\<SID>\Software\Microsoft\SystemCertificates
+// is read directly.
+func (inv *certInventory) addWindowsUserStores() {
+	for _, sid := range loadedUserSIDs() {
+		user := sid
+		// ProfileImagePath: C:\Users\alice
+		if path, err := getRegistryValue(`HKLM\SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileList\`+sid, "ProfileImagePath"); err == nil {
+			user = filepath.Base(path)
+		}
+		for _, store := range windowsUserCertStores {
+			key := `HKU\` + sid + `\Software\Microsoft\SystemCertificates\` + store + `\Certificates`
+			cmd := exec.Command("reg", "query", key, "/s", "/v", "Blob")
+			var out bytes.Buffer
+			cmd.Stdout = &out
+			if err := cmd.Run(); err != nil {
+				continue
+			}
+			for _, blob := range parseRegBlobs(out.String()) {
+				der, hasKey := parseSerializedCertificate(blob)
+				cert, err := x509.ParseCertificate(der)
+				if err != nil {
+					continue
+				}
+				inv.add(cert, `CurrentUser\`+store, user, hasKey)
+			}
+		}
+	}
+}
+
+// parseRegBlobs parses `reg query /s /v Blob` output:
+//
+//	HKEY_USERS\S-1-5-21-...\SystemCertificates\My\Certificates\3B1EFD3A66EA28B16697394703A72CA340A05BD5
+//	    Blob    REG_BINARY    0300000001000000140000003B1EFD3A...
+func parseRegBlobs(output string) [][]byte {
+	var blobs [][]byte
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "Blob" || fields[1] != "REG_BINARY" {
+			continue
+		}
+		if blob, err := hex.DecodeString(fields[2]); err == nil {
+			blobs = append(blobs, blob)
+		}
+	}
+	return blobs
+}
+
+// parseSerializedCertificate returns the certificate in a serialized store
+// element, a run of properties each laid out as ID, encoding type and
+// length (little-endian uint32s) followed by the value, and whether a
+// private key is linked to it.
+func parseSerializedCertificate(blob []byte) ([]byte, bool) {
+	var der []byte
+	hasKey := false
+	for len(blob) >= 12 {
+		id := binary.LittleEndian.Uint32(blob)
+		size := binary.LittleEndian.Uint32(blob[8:])
+		blob = blob[12:]
+		if uint64(size) > uint64(len(blob)) {
+			break
+		}
+		switch id {
+		case certCertPropID:
+			der = blob[:size]
+		case certKeyProvInfoPropID:
+			hasKey = true
+		}
+		blob = blob[size:]
+	}
+	return der, hasKey
+}
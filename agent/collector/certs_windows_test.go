@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSerializedCertificate(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "alice"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prop := func(id uint32, value []byte) []byte {
+		b := binary.LittleEndian.AppendUint32(nil, id)
+		b = binary.LittleEndian.AppendUint32(b, 1)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(value)))
+		return append(b, value...)
+	}
+	withKey := append(append(prop(3, make([]byte, 20)), prop(certKeyProvInfoPropID, make([]byte, 40))...), prop(certCertPropID, der)...)
+	withoutKey := append(prop(3, make([]byte, 20)), prop(certCertPropID, der)...)
+	reg := `
+HKEY_USERS\S-1-5-21-1004336348-1177238915-682003330-1001\Software\Microsoft\SystemCertificates\My\Certificates\AAAA
+    Blob    REG_BINARY    ` + strings.ToUpper(hex.EncodeToString(withKey)) + `
+
+HKEY_USERS\S-1-5-21-1004336348-1177238915-682003330-1001\Software\Microsoft\SystemCertificates\My\Certificates\BBBB
+    Blob    REG_BINARY    ` + strings.ToUpper(hex.EncodeToString(withoutKey)) + `
+
+End of search: 2 match(es) found.
+`
+	blobs := parseRegBlobs(reg)
+	if len(blobs) != 2 {
+		t.Fatalf("got %d blobs, want 2", len(blobs))
+	}
+	for i, wantKey := range []bool{true, false} {
+		got, hasKey := parseSerializedCertificate(blobs[i])
+		if string(got) != string(der) || hasKey != wantKey {
+			t.Errorf("blob %d: certificate match %v, hasKey %v, want true, %v", i, string(got) == string(der), hasKey, wantKey)
+		}
+	}
+
+	// A length running past the end stops the parse
+	if got, _ := parseSerializedCertificate(withoutKey[:len(withoutKey)-1]); got != nil {
+		t.Errorf("truncated blob returned a certificate")
+	}
+}
@@ -9,6 +9,8 @@ type SystemInfo struct {
 	Username        string `json:"username"`
 	SerialNumber    string `json:"serial_number"`
 	OS              string `json:"os"`
+	DistroID        string `json:"distro_id,omitempty"`      // os-release ID on Linux, e.g. "debian", "rhel", "alpine"
+	DistroVersion   string `json:"distro_version,omitempty"` // os-release VERSION_ID, e.g. "12", "9.3", "3.19.1"
 	Platform        string `json:"platform"`
 	IPAddress       string `json:"ip_address"`
 	MACAddress      string `json:"mac_address"`
@@ -20,6 +22,21 @@ type SystemInfo struct {
 	DiskTotalGB     int    `json:"disk_total_gb"`
 	DiskFreeGB      int    `json:"disk_free_gb"`
 	InstalledSoftware []Software `json:"installed_software"`
+	Security        *SecurityInfo `json:"security,omitempty"`
+	Updates         *UpdateStatus `json:"updates,omitempty"`
+	Containers      []Container   `json:"containers,omitempty"`
+	VirtualizationInfo
+	Peripherals     []Peripheral  `json:"peripherals,omitempty"`
+	Monitors        []Monitor     `json:"monitors,omitempty"`
+	PrimaryUser     string         `json:"primary_user,omitempty"` // Most frequent interactive user; Username is the agent's process user
+	LocalUsers      []UserAccount  `json:"local_users,omitempty"`
+	ActiveSessions  []LoginSession `json:"active_sessions,omitempty"`
+	Services        []Service       `json:"services,omitempty"`
+	ListeningPorts  []ListeningPort `json:"listening_ports,omitempty"`
+	BrowserExtensions []BrowserExtension `json:"browser_extensions,omitempty"`
+	Certificates    []Certificate   `json:"certificates,omitempty"`
+	SoftwareUsage   []SoftwareUsage `json:"software_usage,omitempty"` // Filled from the metering module, not by Collect
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"` // Filled from the vuln package, not by Collect
 }
 
 type Software struct {
@@ -27,12 +44,113 @@ type Software struct {
 	Version      string `json:"version,omitempty"`
 	Vendor       string `json:"vendor,omitempty"`
 	InstallDate  string `json:"install_date,omitempty"`
+	Source       string `json:"source,omitempty"` // Package manager, e.g. "dpkg", "rpm", "snap"
+	Architecture string `json:"architecture,omitempty"`
+	SizeBytes    int64  `json:"size_bytes,omitempty"`
+	Description  string `json:"description,omitempty"`
+	InstallPath  string `json:"install_path,omitempty"`
+	SourcePackage string `json:"source_package,omitempty"` // Distro source package, when it differs from Name (dpkg, rpm)
+}
+
+// Package manager sources reported in Software.Source
+const (
+	SourceDpkg    = "dpkg"
+	SourceRPM     = "rpm"
+	SourcePacman  = "pacman"
+	SourceApk     = "apk"
+	SourceSnap    = "snap"
+	SourceFlatpak = "flatpak"
+	SourceNix     = "nix"
+)
+
+// SoftwareUsage is one metered application's use on one day. Values are
+// running totals for the day, so a re-sent day replaces the earlier one.
+type SoftwareUsage struct {
+	App               string `json:"app"`
+	Date              string `json:"date"` // YYYY-MM-DD, local time
+	RunSeconds        int64  `json:"run_seconds"`
+	ForegroundSeconds int64  `json:"foreground_seconds"` // Only where the OS exposes the foreground window
+	Launches          int    `json:"launches"`
+	LastUsed          string `json:"last_used,omitempty"` // RFC 3339
+}
+
+// Vulnerability is a known advisory affecting an installed Software entry,
+// matched offline against the local vulnerability database.
+type Vulnerability struct {
+	Package       string   `json:"package"`
+	Version       string   `json:"version"` // Installed version
+	Source        string   `json:"source,omitempty"`
+	ID            string   `json:"id"` // Advisory ID, e.g. "DSA-5678-1", "GHSA-xxxx-xxxx-xxxx", "CVE-2024-1234"
+	CVEs          []string `json:"cves,omitempty"`
+	Summary       string   `json:"summary,omitempty"`
+	Severity      string   `json:"severity,omitempty"` // "CRITICAL", "HIGH", "MEDIUM", "LOW" or empty if unrated
+	Score         float64  `json:"score,omitempty"`    // CVSS v3 base score, when the advisory has a vector
+	FixedVersions []string `json:"fixed_versions,omitempty"` // Empty when no fix is available
+}
+
+// SecurityInfo describes the host's security posture so the backend can flag
+// non-compliant assets. Fields the agent could not determine are left empty.
+type SecurityInfo struct {
+	Firewall           FirewallStatus `json:"firewall"`
+	SecureBoot         string         `json:"secure_boot"` // "enabled", "disabled", "unsupported" or "unknown"
+	TPMPresent         bool           `json:"tpm_present"`
+	TPMVersion         string         `json:"tpm_version,omitempty"` // e.g. "2.0", "1.2"
+	ScreenLockEnabled  *bool          `json:"screen_lock_enabled,omitempty"`
+	IdleTimeoutSeconds int            `json:"idle_timeout_seconds,omitempty"`
+}
+
+type FirewallStatus struct {
+	Enabled  bool            `json:"enabled"`
+	Product  string          `json:"product,omitempty"`  // e.g. "nftables", "ufw", "alf", "windows_firewall"
+	Profiles map[string]bool `json:"profiles,omitempty"` // Per-profile state (Windows Domain/Private/Public)
+}
+
+// Secure Boot states reported in SecurityInfo.SecureBoot
+const (
+	SecureBootEnabled     = "enabled"
+	SecureBootDisabled    = "disabled"
+	SecureBootUnsupported = "unsupported"
+	SecureBootUnknown     = "unknown"
+)
+
+// UpdateStatus summarises the OS patch level: updates waiting to be
+// installed, when updates were last applied and whether a reboot is pending.
+type UpdateStatus struct {
+	Source                 string `json:"source,omitempty"` // e.g. "apt", "dnf", "softwareupdate", "windows_update"
+	PendingUpdates         int    `json:"pending_updates"`
+	PendingSecurityUpdates int    `json:"pending_security_updates"`
+	LastUpdated            string `json:"last_updated,omitempty"` // YYYY-MM-DD
+	RebootRequired         bool   `json:"reboot_required"`
+}
+
+// Peripheral is a PCI or USB device attached to the asset, such as a dock,
+// webcam or security key. IDs are lower-case hex without a 0x prefix.
+type Peripheral struct {
+	Bus       string `json:"bus"`     // "pci" or "usb"
+	Address   string `json:"address"` // PCI slot ("0000:00:1f.6") or USB port path ("1-4.2")
+	VendorID  string `json:"vendor_id"`
+	ProductID string `json:"product_id"`
+	Vendor    string `json:"vendor,omitempty"`
+	Product   string `json:"product,omitempty"`
+	ClassCode string `json:"class_code,omitempty"`
+	Class     string `json:"class,omitempty"`
+	Serial    string `json:"serial,omitempty"`
+	Driver    string `json:"driver,omitempty"`
 }
 
 type Collector interface {
 	Collect() (*SystemInfo, error)
 }
 
+// Options enables the optional, more expensive parts of a collection.
+type Options struct {
+	LanguagePackages bool   // Include pip/npm/gem/cargo/go packages in InstalledSoftware
+	DockerSocket     string // Docker Engine API socket; empty disables Docker inventory
+	ContainerdSocket string // containerd gRPC socket; empty disables containerd inventory
+	CloudMetadataURL string // Base URL of the cloud metadata service; empty disables cloud detection
+	CertPaths        []string // Extra certificate files or directories to inventory
+}
+
 func GetPlatform() string {
 	return runtime.GOOS
 }
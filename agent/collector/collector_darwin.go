@@ -14,10 +14,12 @@ import (
 	"syscall"
 )
 
-type DarwinCollector struct{}
+type DarwinCollector struct {
+	opts Options
+}
 
-func New() Collector {
-	return &DarwinCollector{}
+func New(opts Options) Collector {
+	return &DarwinCollector{opts: opts}
 }
 
 func (c *DarwinCollector) Collect() (*SystemInfo, error) {
@@ -83,6 +85,36 @@ func (c *DarwinCollector) Collect() (*SystemInfo, error) {
 
 	// 7. Installed Software
 	info.InstalledSoftware = getMacOSInstalledSoftware()
+	if c.opts.LanguagePackages {
+		info.InstalledSoftware = append(info.InstalledSoftware, GetLanguagePackages()...)
+	}
+
+	// 8. Security Posture
+	info.Security = getSecurityInfo()
+
+	// 9. Pending Updates
+	info.Updates = getUpdateStatus()
+
+	// 10. Containers
+	info.Containers = GetContainers(c.opts.DockerSocket, c.opts.ContainerdSocket)
+
+	// 11. Virtualization / Cloud
+	info.VirtualizationInfo = detectVirtualization(getVirtHints(), c.opts.CloudMetadataURL)
+
+	// 12. Monitors
+	info.Monitors = getMacOSMonitors()
+
+	// 13. Local Users / Primary User
+	info.LocalUsers, info.ActiveSessions, info.PrimaryUser = getMacOSUsers()
+
+	// 14. Services / Listening Ports
+	info.Services, info.ListeningPorts = getMacOSServices()
+
+	// 15. Browser Extensions
+	info.BrowserExtensions = GetBrowserExtensions()
+
+	// 16. Certificates
+	info.Certificates = getMacOSCertificates(c.opts.CertPaths)
 
 	return info, nil
 }
@@ -4,22 +4,20 @@ package collector
 
 import (
 	"bufio"
-	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/user"
 	"runtime"
-	"strconv"
 	"strings"
 	"syscall"
-	"time"
 )
 
-type LinuxCollector struct{}
+type LinuxCollector struct {
+	opts Options
+}
 
-func New() Collector {
-	return &LinuxCollector{}
+func New(opts Options) Collector {
+	return &LinuxCollector{opts: opts}
 }
 
 func (c *LinuxCollector) Collect() (*SystemInfo, error) {
@@ -56,6 +54,7 @@ func (c *LinuxCollector) Collect() (*SystemInfo, error) {
 
 	// 4. OS Version
 	info.OS = getLinuxOSName()
+	info.DistroID, info.DistroVersion = getLinuxDistro()
 
 	// 5. Network Info
 	ip, mac, err := GetNetworkInfo()
@@ -83,68 +82,41 @@ func (c *LinuxCollector) Collect() (*SystemInfo, error) {
 
 	// 7. Installed Software
 	info.InstalledSoftware = getLinuxInstalledSoftware()
+	if c.opts.LanguagePackages {
+		info.InstalledSoftware = append(info.InstalledSoftware, GetLanguagePackages()...)
+	}
 
-	return info, nil
-}
+	// 8. Security Posture
+	info.Security = getSecurityInfo()
 
-// getLinuxInstalledSoftware uses dpkg or rpm to list installed packages.
-func getLinuxInstalledSoftware() []Software {
-	softwareList := []Software{}
+	// 9. Pending Updates
+	info.Updates = getUpdateStatus()
 
-	// Try dpkg first (Debian/Ubuntu)
-	cmd := exec.Command("dpkg", "-l")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
-	if err == nil {
-		scanner := bufio.NewScanner(bytes.NewReader(out.Bytes()))
-		for scanner.Scan() {
-			line := scanner.Text()
-			// Example: "ii  apache2  2.4.41-4ubuntu3.1  amd64  Apache HTTP Server"
-			// We are looking for lines starting with "ii" or "hi" (installed)
-			if strings.HasPrefix(line, "ii ") || strings.HasPrefix(line, "hi ") {
-				fields := strings.Fields(line)
-				if len(fields) >= 3 {
-					name := fields[1]
-					version := fields[2]
-					softwareList = append(softwareList, Software{Name: name, Version: version})
-				}
-			}
-		}
-		return softwareList
-	}
+	// 10. Containers
+	info.Containers = GetContainers(c.opts.DockerSocket, c.opts.ContainerdSocket)
 
-	// Fallback to rpm (RHEL/CentOS/Fedora)
-	cmd = exec.Command("rpm", "-qa", "--queryformat", "%{NAME}|%{VERSION}|%{VENDOR}|%{INSTALLTIME}
-")
-	out.Reset()
-	cmd.Stdout = &out
-	err = cmd.Run()
-	if err == nil {
-		scanner := bufio.NewScanner(bytes.NewReader(out.Bytes()))
-		for scanner.Scan() {
-			line := scanner.Text()
-			parts := strings.Split(line, "|")
-			if len(parts) >= 4 {
-				name := parts[0]
-				version := parts[1]
-				vendor := parts[2]
-				installTime := parts[3] // Unix timestamp
-
-				// Convert Unix timestamp to YYYY-MM-DD
-				date := ""
-				if ts, err := strconv.ParseInt(installTime, 10, 64); err == nil {
-					date = time.Unix(ts, 0).Format("2006-01-02")
-				}
-				
-				softwareList = append(softwareList, Software{Name: name, Version: version, Vendor: vendor, InstallDate: date})
-			}
-		}
-		return softwareList
-	}
+	// 11. Virtualization / Cloud
+	info.VirtualizationInfo = detectVirtualization(getVirtHints(), c.opts.CloudMetadataURL)
 
-	fmt.Printf("Warning: failed to get Linux software inventory: %v\n", err)
-	return []Software{}
+	// 12. Peripherals
+	info.Peripherals = getLinuxPeripherals("/sys/bus")
+
+	// 13. Monitors
+	info.Monitors = getLinuxMonitors("/sys/class/drm")
+
+	// 14. Local Users / Primary User
+	info.LocalUsers, info.ActiveSessions, info.PrimaryUser = getLinuxUsers()
+
+	// 15. Services / Listening Ports
+	info.Services, info.ListeningPorts = getLinuxServices()
+
+	// 16. Browser Extensions
+	info.BrowserExtensions = GetBrowserExtensions()
+
+	// 17. Certificates
+	info.Certificates = getLinuxCertificates(c.opts.CertPaths)
+
+	return info, nil
 }
 
 func getFileContent(path string) string {
@@ -221,6 +193,24 @@ func getLinuxSerialNumber() (string, error) {
 	return strings.TrimSpace(string(content)), nil
 }
 
+// getLinuxDistro returns the machine-readable ID and VERSION_ID from
+// /etc/os-release, which package URLs and vulnerability feeds key on.
+func getLinuxDistro() (string, string) {
+	content, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return "", ""
+	}
+	var id, version string
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "ID=") {
+			id = parseOsReleaseField(line)
+		} else if strings.HasPrefix(line, "VERSION_ID=") {
+			version = parseOsReleaseField(line)
+		}
+	}
+	return id, version
+}
+
 func getLinuxOSName() string {
 	// Try /etc/os-release
 	f, err := os.Open("/etc/os-release")
@@ -14,10 +14,12 @@ import (
 	"strings"
 )
 
-type WindowsCollector struct{}
+type WindowsCollector struct {
+	opts Options
+}
 
-func New() Collector {
-	return &WindowsCollector{}
+func New(opts Options) Collector {
+	return &WindowsCollector{opts: opts}
 }
 
 func (c *WindowsCollector) Collect() (*SystemInfo, error) {
@@ -71,11 +73,9 @@ func (c *WindowsCollector) Collect() (*SystemInfo, error) {
 	info.CPUModel = getWmic("cpu", "name")
 	info.CPUCores = getWmicInt("cpu", "NumberOfCores")
 	
-	// RAM (Capacity is in bytes)
-	ramBytes := getWmicInt64("memorychip", "Capacity") 
-	// Note: wmic memorychip returns multiple rows for multiple sticks. 
-	// This simple helper only grabs the first one. For full total we need to sum.
-	// Let's do a slightly better job for RAM:
+	// RAM
+	// Note: wmic memorychip returns one Capacity row per stick, so we read
+	// TotalPhysicalMemory from computersystem instead of summing them.
 	info.RAMGB = getWindowsTotalRAM()
 
 	// Disk
@@ -83,6 +83,36 @@ func (c *WindowsCollector) Collect() (*SystemInfo, error) {
 
 	// 7. Installed Software
 	info.InstalledSoftware = getWindowsInstalledSoftware()
+	if c.opts.LanguagePackages {
+		info.InstalledSoftware = append(info.InstalledSoftware, GetLanguagePackages()...)
+	}
+
+	// 8. Security Posture
+	info.Security = getSecurityInfo()
+
+	// 9. Pending Updates
+	info.Updates = getUpdateStatus()
+
+	// 10. Containers
+	info.Containers = GetContainers(c.opts.DockerSocket, c.opts.ContainerdSocket)
+
+	// 11. Virtualization / Cloud
+	info.VirtualizationInfo = detectVirtualization(getVirtHints(), c.opts.CloudMetadataURL)
+
+	// 12. Monitors
+	info.Monitors = getWindowsMonitors()
+
+	// 13. Local Users / Primary User
+	info.LocalUsers, info.ActiveSessions, info.PrimaryUser = getWindowsUsers()
+
+	// 14. Services / Listening Ports
+	info.Services, info.ListeningPorts = getWindowsServices()
+
+	// 15. Browser Extensions
+	info.BrowserExtensions = GetBrowserExtensions()
+
+	// 16. Certificates
+	info.Certificates = getWindowsCertificates(c.opts.CertPaths)
 
 	return info, nil
 }
@@ -248,7 +278,7 @@ func getWindowsSerialNumber() (string, error) {
 
 	lines := strings.Split(out.String(), "\n")
 	for _, line := range lines {
-		rimmed := strings.TrimSpace(line)
+		trimmed := strings.TrimSpace(line)
 		if trimmed != "" && !strings.Contains(strings.ToLower(trimmed), "serialnumber") {
 			return trimmed, nil
 		}
@@ -268,7 +298,7 @@ func getWindowsOSName() (string, error) {
 
 	lines := strings.Split(out.String(), "\n")
 	for _, line := range lines {
-		rimmed := strings.TrimSpace(line)
+		trimmed := strings.TrimSpace(line)
 		if trimmed != "" && !strings.Contains(strings.ToLower(trimmed), "caption") {
 			return trimmed, nil
 		}
@@ -0,0 +1,393 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Container struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Runtime     string          `json:"runtime"`             // "docker" or "containerd"
+	Namespace   string          `json:"namespace,omitempty"` // containerd namespace, e.g. "k8s.io"
+	Image       string          `json:"image"`
+	ImageDigest string          `json:"image_digest,omitempty"`
+	State       string          `json:"state"` // e.g. "running", "exited", "created"
+	CreatedAt   string          `json:"created_at,omitempty"`
+	StartedAt   string          `json:"started_at,omitempty"`
+	Ports       []ContainerPort `json:"ports,omitempty"`
+}
+
+type ContainerPort struct {
+	IP          string `json:"ip,omitempty"`
+	PrivatePort int    `json:"private_port"`
+	PublicPort  int    `json:"public_port,omitempty"`
+	Protocol    string `json:"protocol"`
+}
+
+// GetContainers lists containers from whichever engines answer on the given
+// Unix sockets. A missing socket simply means that engine is not installed.
+func GetContainers(dockerSocket, containerdSocket string) []Container {
+	containers := []Container{}
+
+	if dockerSocket != "" && socketExists(dockerSocket) {
+		list, err := getDockerContainers(dockerSocket)
+		if err != nil {
+			fmt.Printf("Warning: failed to list Docker containers: %v\n", err)
+		}
+		containers = append(containers, list...)
+	}
+
+	if containerdSocket != "" && socketExists(containerdSocket) {
+		list, err := getContainerdContainers(containerdSocket)
+		if err != nil {
+			fmt.Printf("Warning: failed to list containerd containers: %v\n", err)
+		}
+		containers = append(containers, list...)
+	}
+	return containers
+}
+
+func socketExists(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode()&os.ModeSocket != 0
+}
+
+// unixSocketClient returns an HTTP client whose every request is dialled to
+// the given Unix socket, regardless of the host in the URL.
+func unixSocketClient(socket string, h2c bool) *http.Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	if h2c {
+		// gRPC needs HTTP/2 with prior knowledge over the plain socket
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return &http.Client{Transport: transport, Timeout: 10 * time.Second}
+}
+
+// getDockerContainers uses the Engine API: /containers/json for the list,
+// then /containers/{id}/json for start times and the image digest.
+func getDockerContainers(socket string) ([]Container, error) {
+	client := unixSocketClient(socket, false)
+
+	var list []struct {
+		ID      string   `json:"Id"`
+		Names   []string `json:"Names"`
+		Image   string   `json:"Image"`
+		ImageID string   `json:"ImageID"`
+		Created int64    `json:"Created"`
+		State   string   `json:"State"`
+		Ports   []struct {
+			IP          string `json:"IP"`
+			PrivatePort int    `json:"PrivatePort"`
+			PublicPort  int    `json:"PublicPort"`
+			Type        string `json:"Type"`
+		} `json:"Ports"`
+	}
+	if err := dockerGet(client, "/containers/json?all=1", &list); err != nil {
+		return nil, err
+	}
+
+	imageDigests := map[string]string{}
+	containers := []Container{}
+	for _, c := range list {
+		ctr := Container{
+			ID:        c.ID,
+			Runtime:   "docker",
+			Image:     c.Image,
+			State:     c.State,
+			CreatedAt: time.Unix(c.Created, 0).UTC().Format(time.RFC3339),
+		}
+		if len(c.Names) > 0 {
+			ctr.Name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		for _, p := range c.Ports {
+			ctr.Ports = append(ctr.Ports, ContainerPort{IP: p.IP, PrivatePort: p.PrivatePort, PublicPort: p.PublicPort, Protocol: p.Type})
+		}
+
+		var inspect struct {
+			State struct {
+				StartedAt string `json:"StartedAt"`
+			} `json:"State"`
+		}
+		if err := dockerGet(client, "/containers/"+c.ID+"/json", &inspect); err == nil {
+			// Never-started containers report the zero time
+			if !strings.HasPrefix(inspect.State.StartedAt, "0001-") {
+				ctr.StartedAt = inspect.State.StartedAt
+			}
+		}
+
+		digest, ok := imageDigests[c.ImageID]
+		if !ok {
+			var image struct {
+				RepoDigests []string `json:"RepoDigests"`
+			}
+			if err := dockerGet(client, "/images/"+c.ImageID+"/json", &image); err == nil && len(image.RepoDigests) > 0 {
+				// "nginx@sha256:..." -> "sha256:..."
+				digest = image.RepoDigests[0][strings.LastIndex(image.RepoDigests[0], "@")+1:]
+			} else {
+				// Locally built images have no registry digest; fall back to the image ID
+				digest = c.ImageID
+			}
+			imageDigests[c.ImageID] = digest
+		}
+		ctr.ImageDigest = digest
+
+		containers = append(containers, ctr)
+	}
+	return containers, nil
+}
+
+func dockerGet(client *http.Client, path string, v any) error {
+	resp, err := client.Get("http://docker" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker API %s returned status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// containerd task states (containerd.v1.types.Status)
+var containerdTaskStates = map[uint64]string{
+	0: "unknown",
+	1: "created",
+	2: "running",
+	3: "stopped",
+	4: "paused",
+	5: "pausing",
+}
+
+// getContainerdContainers walks every containerd namespace except "moby",
+// whose containers Docker already reported.
+func getContainerdContainers(socket string) ([]Container, error) {
+	client := unixSocketClient(socket, true)
+
+	resp, err := containerdCall(client, "", "containerd.services.namespaces.v1.Namespaces/List", nil)
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []string
+	for _, ns := range protoRepeated(resp, 1) {
+		if name := protoString(ns, 1); name != "" && name != "moby" {
+			namespaces = append(namespaces, name)
+		}
+	}
+	sort.Strings(namespaces)
+
+	containers := []Container{}
+	for _, ns := range namespaces {
+		// Image name -> target digest
+		digests := map[string]string{}
+		if resp, err := containerdCall(client, ns, "containerd.services.images.v1.Images/List", nil); err == nil {
+			for _, img := range protoRepeated(resp, 1) {
+				target := protoRepeated(img, 3)
+				if len(target) > 0 {
+					digests[protoString(img, 1)] = protoString(target[0], 2)
+				}
+			}
+		}
+
+		// Container ID -> task status
+		states := map[string]string{}
+		if resp, err := containerdCall(client, ns, "containerd.services.tasks.v1.Tasks/List", nil); err == nil {
+			for _, task := range protoRepeated(resp, 1) {
+				states[protoString(task, 1)] = containerdTaskStates[protoVarint(task, 4)]
+			}
+		}
+
+		resp, err := containerdCall(client, ns, "containerd.services.containers.v1.Containers/List", nil)
+		if err != nil {
+			return containers, err
+		}
+		for _, c := range protoRepeated(resp, 1) {
+			id := protoString(c, 1)
+			ctr := Container{
+				ID:        id,
+				Name:      id,
+				Runtime:   "containerd",
+				Namespace: ns,
+				Image:     protoString(c, 3),
+				State:     states[id],
+			}
+			ctr.ImageDigest = digests[ctr.Image]
+			if ctr.State == "" {
+				// No task means the container exists but was never started or has been cleaned up
+				ctr.State = "created"
+			}
+			if ts := protoRepeated(c, 8); len(ts) > 0 {
+				ctr.CreatedAt = time.Unix(int64(protoVarint(ts[0], 1)), int64(protoVarint(ts[0], 2))).UTC().Format(time.RFC3339)
+			}
+			// Kubernetes names containers by UID; prefer the human-readable label
+			if name := protoMapValue(c, 2, "io.kubernetes.container.name"); name != "" {
+				ctr.Name = name
+			}
+			containers = append(containers, ctr)
+		}
+	}
+	return containers, nil
+}
+
+// containerdCall performs a unary gRPC call. Requests are protobuf messages
+// prefixed with the 5-byte gRPC frame header; the namespace travels as
+// metadata.
+func containerdCall(client *http.Client, namespace, method string, request []byte) ([]byte, error) {
+	frame := make([]byte, 5+len(request))
+	frame[1] = byte(len(request) >> 24)
+	frame[2] = byte(len(request) >> 16)
+	frame[3] = byte(len(request) >> 8)
+	frame[4] = byte(len(request))
+	copy(frame[5:], request)
+
+	req, err := http.NewRequest("POST", "http://containerd/"+method, bytes.NewReader(frame))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	if namespace != "" {
+		req.Header.Set("containerd-namespace", namespace)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Trailers are only populated once the body has been read to EOF
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	if resp.StatusCode != http.StatusOK || (status != "" && status != "0") {
+		return nil, fmt.Errorf("%s failed: http %d, grpc-status %s %s", method, resp.StatusCode, status, resp.Trailer.Get("Grpc-Message"))
+	}
+	if len(body) < 5 {
+		return nil, nil
+	}
+	length := int(body[1])<<24 | int(body[2])<<16 | int(body[3])<<8 | int(body[4])
+	if 5+length > len(body) {
+		return nil, fmt.Errorf("%s: truncated gRPC response", method)
+	}
+	return body[5 : 5+length], nil
+}
+
+// The helpers below decode just enough of the protobuf wire format to pull
+// fields out of containerd responses without generated code.
+
+// protoFields iterates the top-level fields of a message, passing the field
+// number, wire type and either the varint value or the raw bytes.
+func protoFields(msg []byte, fn func(field int, wire int, varint uint64, data []byte)) {
+	for len(msg) > 0 {
+		key, n := protoUvarint(msg)
+		if n <= 0 {
+			return
+		}
+		msg = msg[n:]
+		field, wire := int(key>>3), int(key&7)
+
+		switch wire {
+		case 0: // varint
+			v, n := protoUvarint(msg)
+			if n <= 0 {
+				return
+			}
+			fn(field, wire, v, nil)
+			msg = msg[n:]
+		case 1: // 64-bit
+			if len(msg) < 8 {
+				return
+			}
+			fn(field, wire, 0, msg[:8])
+			msg = msg[8:]
+		case 2: // length-delimited
+			l, n := protoUvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < l {
+				return
+			}
+			fn(field, wire, 0, msg[n:n+int(l)])
+			msg = msg[n+int(l):]
+		case 5: // 32-bit
+			if len(msg) < 4 {
+				return
+			}
+			fn(field, wire, 0, msg[:4])
+			msg = msg[4:]
+		default:
+			return
+		}
+	}
+}
+
+func protoUvarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+func protoRepeated(msg []byte, field int) [][]byte {
+	var out [][]byte
+	protoFields(msg, func(f, wire int, _ uint64, data []byte) {
+		if f == field && wire == 2 {
+			out = append(out, data)
+		}
+	})
+	return out
+}
+
+func protoString(msg []byte, field int) string {
+	values := protoRepeated(msg, field)
+	if len(values) == 0 {
+		return ""
+	}
+	return string(values[len(values)-1])
+}
+
+func protoVarint(msg []byte, field int) uint64 {
+	var v uint64
+	protoFields(msg, func(f, wire int, varint uint64, _ []byte) {
+		if f == field && wire == 0 {
+			v = varint
+		}
+	})
+	return v
+}
+
+// protoMapValue looks up a key in a map<string,string> field, which is
+// encoded as repeated {1: key, 2: value} entries.
+func protoMapValue(msg []byte, field int, key string) string {
+	for _, entry := range protoRepeated(msg, field) {
+		if protoString(entry, 1) == key {
+			return protoString(entry, 2)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// serveUnix starts handler on a Unix socket in a temporary directory and
+// returns the socket path.
+func serveUnix(t *testing.T, handler http.Handler, h2c bool) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "engine.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = l
+	if h2c {
+		srv.Config.Protocols = new(http.Protocols)
+		srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return socket
+}
+
+func TestGetDockerContainers(t *testing.T) {
+	responses := map[string]string{
+		"/containers/json": `[
+			{"Id": "abc123", "Names": ["/web"], "Image": "nginx:1.25", "ImageID": "sha256:img1", "Created": 1700000000, "State": "running",
+			 "Ports": [{"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"}]},
+			{"Id": "def456", "Names": ["/build"], "Image": "local/app", "ImageID": "sha256:img2", "Created": 1700000100, "State": "created"}
+		]`,
+		"/containers/abc123/json":  `{"State": {"StartedAt": "2023-11-14T22:13:20Z"}}`,
+		"/containers/def456/json":  `{"State": {"StartedAt": "0001-01-01T00:00:00Z"}}`,
+		"/images/sha256:img1/json": `{"RepoDigests": ["nginx@sha256:feed"]}`,
+		"/images/sha256:img2/json": `{"RepoDigests": []}`,
+	}
+	socket := serveUnix(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, body)
+	}), false)
+
+	got, err := getDockerContainers(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Container{
+		{
+			ID: "abc123", Name: "web", Runtime: "docker", Image: "nginx:1.25", ImageDigest: "sha256:feed", State: "running",
+			CreatedAt: "2023-11-14T22:13:20Z", StartedAt: "2023-11-14T22:13:20Z",
+			Ports: []ContainerPort{{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080, Protocol: "tcp"}},
+		},
+		{
+			ID: "def456", Name: "build", Runtime: "docker", Image: "local/app", ImageDigest: "sha256:img2", State: "created",
+			CreatedAt: "2023-11-14T22:15:00Z",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getDockerContainers:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+// protoMsg encodes fields for the containerd stub: string and []byte values
+// as length-delimited fields, uint64 values as varints.
+func protoMsg(fields ...any) []byte {
+	var msg []byte
+	for i := 0; i+1 < len(fields); i += 2 {
+		field := uint64(fields[i].(int))
+		switch v := fields[i+1].(type) {
+		case uint64:
+			msg = binary.AppendUvarint(msg, field<<3)
+			msg = binary.AppendUvarint(msg, v)
+		case string:
+			msg = binary.AppendUvarint(msg, field<<3|2)
+			msg = binary.AppendUvarint(msg, uint64(len(v)))
+			msg = append(msg, v...)
+		case []byte:
+			msg = binary.AppendUvarint(msg, field<<3|2)
+			msg = binary.AppendUvarint(msg, uint64(len(v)))
+			msg = append(msg, v...)
+		}
+	}
+	return msg
+}
+
+func TestGetContainerdContainers(t *testing.T) {
+	responses := map[string]map[string][]byte{
+		"": {
+			"/containerd.services.namespaces.v1.Namespaces/List": protoMsg(1, protoMsg(1, "k8s.io"), 1, protoMsg(1, "moby")),
+		},
+		"k8s.io": {
+			"/containerd.services.images.v1.Images/List": protoMsg(1, protoMsg(1, "docker.io/library/redis:7", 3, protoMsg(1, "application/vnd.oci.image.index.v1+json", 2, "sha256:beef"))),
+			"/containerd.services.tasks.v1.Tasks/List":   protoMsg(1, protoMsg(1, "c1", 4, uint64(2))),
+			"/containerd.services.containers.v1.Containers/List": protoMsg(
+				1, protoMsg(1, "c1", 2, protoMsg(1, "io.kubernetes.container.name", 2, "redis"), 3, "docker.io/library/redis:7", 8, protoMsg(1, uint64(1700000000))),
+				1, protoMsg(1, "c2", 3, "docker.io/library/busybox:latest"),
+			),
+		},
+	}
+	socket := serveUnix(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/grpc" {
+			t.Errorf("%s: content type %q", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		msg, ok := responses[r.Header.Get("containerd-namespace")][r.URL.Path]
+		if !ok {
+			w.Header().Set("Grpc-Status", "12") // UNIMPLEMENTED
+			return
+		}
+		frame := make([]byte, 5, 5+len(msg))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+		w.Write(append(frame, msg...))
+		w.Header().Set("Grpc-Status", "0")
+	}), true)
+
+	got, err := getContainerdContainers(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Container{
+		{
+			ID: "c1", Name: "redis", Runtime: "containerd", Namespace: "k8s.io", Image: "docker.io/library/redis:7",
+			ImageDigest: "sha256:beef", State: "running", CreatedAt: "2023-11-14T22:13:20Z",
+		},
+		{ID: "c2", Name: "c2", Runtime: "containerd", Namespace: "k8s.io", Image: "docker.io/library/busybox:latest", State: "created"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getContainerdContainers:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestGetContainersMissingSockets(t *testing.T) {
+	dir := t.TempDir()
+	got := GetContainers(filepath.Join(dir, "docker.sock"), filepath.Join(dir, "containerd.sock"))
+	if len(got) != 0 {
+		t.Errorf("GetContainers without engines = %+v, want none", got)
+	}
+}
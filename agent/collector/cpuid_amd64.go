@@ -0,0 +1,23 @@
+//go:build amd64
+
+package collector
+
+import "encoding/binary"
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+// cpuidHypervisor reports the CPUID "hypervisor present" bit (leaf 1, ECX
+// bit 31) and, when set, the 12-byte vendor signature from leaf 0x40000000,
+// e.g. "KVMKVMKVM", "VMwareVMware" or "Microsoft Hv".
+func cpuidHypervisor() (bool, string) {
+	_, _, ecx, _ := cpuid(1, 0)
+	if ecx&(1<<31) == 0 {
+		return false, ""
+	}
+	_, ebx, ecx, edx := cpuid(0x40000000, 0)
+	sig := make([]byte, 12)
+	binary.LittleEndian.PutUint32(sig[0:], ebx)
+	binary.LittleEndian.PutUint32(sig[4:], ecx)
+	binary.LittleEndian.PutUint32(sig[8:], edx)
+	return true, string(sig)
+}
@@ -0,0 +1,9 @@
+//go:build !amd64
+
+package collector
+
+// cpuidHypervisor is only implemented on amd64; other architectures rely on
+// DMI strings and /sys/hypervisor.
+func cpuidHypervisor() (bool, string) {
+	return false, ""
+}
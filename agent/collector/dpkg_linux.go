@@ -0,0 +1,97 @@
+//go:build linux
+
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// getDpkgStatusPackages reads dpkg's status database directly. It is a
+// sequence of RFC 822-style stanzas separated by blank lines:
+//
+//	Package: apache2
+//	Status: install ok installed
+//	Installed-Size: 546
+//	Maintainer: Debian Apache Maintainers <debian-apache@lists.debian.org>
+//	Architecture: amd64
+//	Version: 2.4.57-2
+//	Description: Apache HTTP Server
+//	 The Apache HTTP Server Project's goal is ...
+//
+// dpkg keeps no install timestamp, so the modification time of the
+// package's file list under info/ is used instead.
+func getDpkgStatusPackages(adminDir string) ([]Software, error) {
+	f, err := os.Open(filepath.Join(adminDir, "status"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	softwareList := []Software{}
+	stanza := map[string]string{}
+	flush := func() {
+		if sw, ok := dpkgStanzaToSoftware(stanza, adminDir); ok {
+			softwareList = append(softwareList, sw)
+		}
+		stanza = map[string]string{}
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		// Continuation lines (long descriptions, conffiles) are not needed
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		stanza[parts[0]] = strings.TrimSpace(parts[1])
+	}
+	flush()
+	return softwareList, scanner.Err()
+}
+
+func dpkgStanzaToSoftware(stanza map[string]string, adminDir string) (Software, bool) {
+	name := stanza["Package"]
+	// Status is "<want> <flag> <state>"; only fully installed packages count
+	status := strings.Fields(stanza["Status"])
+	if name == "" || len(status) != 3 || status[2] != "installed" {
+		return Software{}, false
+	}
+
+	sw := Software{
+		Name:         name,
+		Version:      stanza["Version"],
+		Vendor:       stanza["Maintainer"],
+		Architecture: stanza["Architecture"],
+		Description:  stanza["Description"],
+		Source:       SourceDpkg,
+	}
+	// "Source: glibc" or "Source: glibc (2.36-9)" when the versions differ
+	if src := strings.Fields(stanza["Source"]); len(src) > 0 && src[0] != name {
+		sw.SourcePackage = src[0]
+	}
+	if kb, err := strconv.ParseInt(stanza["Installed-Size"], 10, 64); err == nil {
+		sw.SizeBytes = kb * 1024
+	}
+
+	// Multi-arch packages use "<name>:<arch>.list"
+	for _, list := range []string{name + ".list", name + ":" + sw.Architecture + ".list"} {
+		if fi, err := os.Stat(filepath.Join(adminDir, "info", list)); err == nil {
+			sw.InstallDate = fi.ModTime().Format("2006-01-02")
+			break
+		}
+	}
+	return sw, true
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Monitor is a display identified from its EDID.
+type Monitor struct {
+	Connector       string `json:"connector,omitempty"` // e.g. "HDMI-A-1", "DP-2"
+	ManufacturerID  string `json:"manufacturer_id"`     // 3-letter PNP ID, e.g. "DEL"
+	Manufacturer    string `json:"manufacturer,omitempty"`
+	Model           string `json:"model,omitempty"`
+	ProductCode     string `json:"product_code"`
+	SerialNumber    string `json:"serial_number,omitempty"`
+	ManufactureWeek int    `json:"manufacture_week,omitempty"`
+	ManufactureYear int    `json:"manufacture_year,omitempty"`
+	NativeWidth     int    `json:"native_width,omitempty"`
+	NativeHeight    int    `json:"native_height,omitempty"`
+	WidthCM         int    `json:"width_cm,omitempty"`
+	HeightCM        int    `json:"height_cm,omitempty"`
+	Internal        bool   `json:"internal"` // Built-in laptop panel
+}
+
+// Common PNP manufacturer IDs found on monitors
+var pnpManufacturers = map[string]string{
+	"ACR": "Acer",
+	"AOC": "AOC",
+	"APP": "Apple",
+	"AUO": "AU Optronics",
+	"AUS": "ASUS",
+	"BNQ": "BenQ",
+	"BOE": "BOE",
+	"CMN": "Chimei Innolux",
+	"DEL": "Dell",
+	"ENC": "EIZO",
+	"GSM": "LG Electronics",
+	"HPN": "HP",
+	"HWP": "HP",
+	"IVM": "Iiyama",
+	"LEN": "Lenovo",
+	"LGD": "LG Display",
+	"MSI": "MSI",
+	"NEC": "NEC",
+	"PHL": "Philips",
+	"SAM": "Samsung",
+	"SHP": "Sharp",
+	"SNY": "Sony",
+	"VSC": "ViewSonic",
+}
+
+var edidHeader = []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}
+
+// parseEDID decodes the 128-byte EDID base block. Extension blocks are
+// ignored; everything needed for inventory lives in the base block.
+func parseEDID(edid []byte) (Monitor, error) {
+	if len(edid) < 128 || !bytes.Equal(edid[:8], edidHeader) {
+		return Monitor{}, fmt.Errorf("not an EDID block")
+	}
+	var sum byte
+	for _, b := range edid[:128] {
+		sum += b
+	}
+	if sum != 0 {
+		return Monitor{}, fmt.Errorf("EDID checksum mismatch")
+	}
+
+	// Manufacturer: three 5-bit letters, big-endian, 'A' = 1
+	mfg := binary.BigEndian.Uint16(edid[8:10])
+	id := string([]byte{
+		byte(mfg>>10&0x1f) + '@',
+		byte(mfg>>5&0x1f) + '@',
+		byte(mfg&0x1f) + '@',
+	})
+
+	m := Monitor{
+		ManufacturerID: id,
+		Manufacturer:   pnpManufacturers[id],
+		ProductCode:    fmt.Sprintf("%04x", binary.LittleEndian.Uint16(edid[10:12])),
+		WidthCM:        int(edid[21]),
+		HeightCM:       int(edid[22]),
+	}
+	if serial := binary.LittleEndian.Uint32(edid[12:16]); serial != 0 {
+		m.SerialNumber = strconv.FormatUint(uint64(serial), 10)
+	}
+	// Week 0xff means the year is a model year rather than a manufacture date
+	if week := int(edid[16]); week >= 1 && week <= 54 {
+		m.ManufactureWeek = week
+	}
+	m.ManufactureYear = int(edid[17]) + 1990
+
+	// Four 18-byte descriptors. The first is normally the preferred
+	// (native) detailed timing; the others may be display descriptors.
+	for i := 0; i < 4; i++ {
+		d := edid[54+18*i : 72+18*i]
+		if d[0] != 0 || d[1] != 0 {
+			if m.NativeWidth == 0 {
+				m.NativeWidth = int(d[2]) | int(d[4]&0xf0)<<4
+				m.NativeHeight = int(d[5]) | int(d[7]&0xf0)<<4
+			}
+			continue
+		}
+		text := edidDescriptorText(d[5:18])
+		switch d[3] {
+		case 0xfc: // Display product name
+			m.Model = text
+		case 0xff: // Display serial number (preferred over the numeric one)
+			if text != "" {
+				m.SerialNumber = text
+			}
+		}
+	}
+	return m, nil
+}
+
+// edidDescriptorText trims a 13-byte descriptor string, which ends with a
+// line feed and is padded with spaces.
+func edidDescriptorText(b []byte) string {
+	if i := bytes.IndexByte(b, 0x0a); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// parseEDIDHex decodes EDID bytes printed as hex, as ioreg and reg query do.
+func parseEDIDHex(s string) (Monitor, error) {
+	s = strings.Trim(strings.TrimSpace(s), "<>")
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return Monitor{}, err
+	}
+	return parseEDID(raw)
+}
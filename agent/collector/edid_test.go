@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readEDIDFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	edid, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return edid
+}
+
+func TestParseEDID(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    Monitor
+	}{
+		{
+			// External monitor with name and serial descriptors
+			"edid-dell-u2419h.bin",
+			Monitor{
+				ManufacturerID: "DEL", Manufacturer: "Dell", Model: "DELL U2419H", ProductCode: "4135",
+				SerialNumber: "7MT0196N3NSL", ManufactureWeek: 12, ManufactureYear: 2019,
+				NativeWidth: 2560, NativeHeight: 1440, WidthCM: 52, HeightCM: 32,
+			},
+		},
+		{
+			// Laptop panel: no serial, no week, only unspecified text descriptors
+			"edid-lgd-panel.bin",
+			Monitor{
+				ManufacturerID: "LGD", Manufacturer: "LG Display", ProductCode: "06a5", ManufactureYear: 2021,
+				NativeWidth: 1920, NativeHeight: 1200, WidthCM: 34, HeightCM: 19,
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := parseEDID(readEDIDFixture(t, tt.fixture))
+		if err != nil {
+			t.Errorf("%s: %v", tt.fixture, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.fixture, got, tt.want)
+		}
+	}
+}
+
+func TestParseEDIDInvalid(t *testing.T) {
+	edid := readEDIDFixture(t, "edid-dell-u2419h.bin")
+
+	corrupt := append([]byte(nil), edid...)
+	corrupt[20] ^= 0xff
+	if _, err := parseEDID(corrupt); err == nil {
+		t.Error("parseEDID accepted a block with a bad checksum")
+	}
+	if _, err := parseEDID(edid[:127]); err == nil {
+		t.Error("parseEDID accepted a truncated block")
+	}
+	if _, err := parseEDID(make([]byte, 128)); err == nil {
+		t.Error("parseEDID accepted a block without the header")
+	}
+}
+
+func TestParseEDIDHex(t *testing.T) {
+	edid := readEDIDFixture(t, "edid-lgd-panel.bin")
+	// ioreg prints <00ffffff...>
+	m, err := parseEDIDHex("<" + hex.EncodeToString(edid) + ">")
+	if err != nil || m.ManufacturerID != "LGD" {
+		t.Errorf("parseEDIDHex = %+v, %v", m, err)
+	}
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// userHomeDirs returns the home directories of local users. The agent
+// usually runs as a system service, so per-user data (language packages,
+// browser profiles, certificate stores) has to be found by walking every
+// home rather than relying on $HOME.
+func userHomeDirs() []string {
+	var patterns []string
+	switch runtime.GOOS {
+	case "darwin":
+		patterns = []string{"/Users/*"}
+	case "windows":
+		patterns = []string{filepath.Join(os.Getenv("SystemDrive")+`\`, "Users", "*")}
+	default:
+		patterns = []string{"/home/*", "/root"}
+	}
+
+	seen := map[string]bool{}
+	var homes []string
+	add := func(dir string) {
+		if dir == "" || seen[dir] {
+			return
+		}
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			seen[dir] = true
+			homes = append(homes, dir)
+		}
+	}
+
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, dir := range matches {
+			switch filepath.Base(dir) {
+			case "Shared", "Public", "Default", "Default User", "All Users", "Guest":
+				continue
+			}
+			add(dir)
+		}
+	}
+	// Also cover the account the agent runs as, which may live elsewhere
+	if home, err := os.UserHomeDir(); err == nil {
+		add(home)
+	}
+	return homes
+}
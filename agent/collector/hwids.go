@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The embedded databases are refreshed from upstream with `go generate`.
+//
+//go:generate curl -fsSL -o hwids/pci.ids https://pci-ids.ucw.cz/v2.2/pci.ids
+//go:generate curl -fsSL -o hwids/usb.ids https://www.linux-usb.org/usb.ids
+
+//go:embed hwids/pci.ids
+var embeddedPCIIDs []byte
+
+//go:embed hwids/usb.ids
+var embeddedUSBIDs []byte
+
+// idDatabase holds a parsed pci.ids or usb.ids file. Keys are lower-case
+// hex: "vvvv" for vendors, "vvvv:dddd" for devices, "cc" and "cc:ss" for
+// classes.
+type idDatabase struct {
+	vendors map[string]string
+	devices map[string]string
+	classes map[string]string
+}
+
+var (
+	pciIDsOnce, usbIDsOnce sync.Once
+	pciIDs, usbIDs         *idDatabase
+)
+
+// loadIDDatabase prefers the OS's hwdata copy of an ID file, which the
+// distribution keeps up to date, over the copy embedded in the binary.
+func loadIDDatabase(name string, embedded []byte) *idDatabase {
+	for _, path := range []string{"/usr/share/hwdata/" + name, "/usr/share/misc/" + name} {
+		if content, err := os.ReadFile(path); err == nil {
+			if db := parseIDDatabase(content); len(db.vendors) > 0 {
+				return db
+			}
+		}
+	}
+	return parseIDDatabase(embedded)
+}
+
+func getPCIIDs() *idDatabase {
+	pciIDsOnce.Do(func() { pciIDs = loadIDDatabase("pci.ids", embeddedPCIIDs) })
+	return pciIDs
+}
+
+func getUSBIDs() *idDatabase {
+	usbIDsOnce.Do(func() { usbIDs = loadIDDatabase("usb.ids", embeddedUSBIDs) })
+	return usbIDs
+}
+
+// parseIDDatabase reads the shared pci.ids/usb.ids format: vendor lines
+// start in column 0 with a 4-digit hex ID, devices are indented by one tab,
+// subsystems by two. Class lists start with "C xx". usb.ids carries further
+// sections (AT, HID, ...) that are skipped.
+func parseIDDatabase(content []byte) *idDatabase {
+	db := &idDatabase{
+		vendors: map[string]string{},
+		devices: map[string]string{},
+		classes: map[string]string{},
+	}
+
+	const (
+		sectionNone = iota
+		sectionVendor
+		sectionClass
+	)
+	section := sectionNone
+	parent := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] != '\t' {
+			if id, name, ok := splitIDLine(line); ok && len(id) == 4 && isHex(id) {
+				section, parent = sectionVendor, strings.ToLower(id)
+				db.vendors[parent] = name
+			} else if strings.HasPrefix(line, "C ") {
+				id, name, _ := splitIDLine(line[2:])
+				section, parent = sectionClass, strings.ToLower(id)
+				db.classes[parent] = name
+			} else {
+				section = sectionNone
+			}
+			continue
+		}
+
+		// Only one level of nesting is needed (devices, subclasses)
+		if strings.HasPrefix(line, "\t\t") {
+			continue
+		}
+		id, name, ok := splitIDLine(line[1:])
+		if !ok {
+			continue
+		}
+		switch section {
+		case sectionVendor:
+			db.devices[parent+":"+strings.ToLower(id)] = name
+		case sectionClass:
+			db.classes[parent+":"+strings.ToLower(id)] = name
+		}
+	}
+	return db
+}
+
+// splitIDLine splits "8086  Intel Corporation" into ID and name.
+func splitIDLine(line string) (string, string, bool) {
+	id, name, ok := strings.Cut(line, "  ")
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(id), strings.TrimSpace(name), true
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the vendor and device names for an ID pair.
+func (db *idDatabase) lookup(vendorID, deviceID string) (string, string) {
+	vendorID, deviceID = strings.ToLower(vendorID), strings.ToLower(deviceID)
+	return db.vendors[vendorID], db.devices[vendorID+":"+deviceID]
+}
+
+// className prefers the subclass name and falls back to the base class.
+func (db *idDatabase) className(class, subclass string) string {
+	class, subclass = strings.ToLower(class), strings.ToLower(subclass)
+	if name, ok := db.classes[class+":"+subclass]; ok && subclass != "" {
+		return name
+	}
+	return db.classes[class]
+}
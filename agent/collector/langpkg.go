@@ -0,0 +1,421 @@
+package collector
+
+import (
+	"bufio"
+	"debug/buildinfo"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Language ecosystems reported in Software.Source
+const (
+	SourcePyPI  = "pypi"
+	SourceNpm   = "npm"
+	SourceGem   = "gem"
+	SourceCargo = "cargo"
+	SourceGo    = "go"
+)
+
+// GetLanguagePackages enumerates globally and per-user installed language
+// packages that the OS package managers do not know about: Python
+// distributions, global npm modules, RubyGems, `cargo install` crates and Go
+// binaries. Each entry carries its ecosystem in Source and where it was found
+// in InstallPath. This walks every user's home, so it is opt-in.
+func GetLanguagePackages() []Software {
+	homes := userHomeDirs()
+	softwareList := []Software{}
+	softwareList = append(softwareList, getPythonPackages(pythonSitePackageDirs(homes))...)
+	softwareList = append(softwareList, getNpmGlobalPackages(npmGlobalModuleDirs(homes))...)
+	softwareList = append(softwareList, getRubyGems(gemSpecificationDirs(homes))...)
+	softwareList = append(softwareList, getCargoInstalls(homes)...)
+	softwareList = append(softwareList, getGoBinaries(goBinDirs(homes))...)
+	return softwareList
+}
+
+// globAll expands every pattern and returns the existing directories,
+// de-duplicated after resolving symlinks.
+func globAll(patterns []string) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, dir := range matches {
+			real, err := filepath.EvalSymlinks(dir)
+			if err != nil || seen[real] {
+				continue
+			}
+			if fi, err := os.Stat(real); err == nil && fi.IsDir() {
+				seen[real] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+func pythonSitePackageDirs(homes []string) []string {
+	var patterns []string
+	switch runtime.GOOS {
+	case "windows":
+		patterns = []string{
+			`C:\Python3*\Lib\site-packages`,
+			`C:\Program Files\Python3*\Lib\site-packages`,
+		}
+		for _, home := range homes {
+			patterns = append(patterns,
+				filepath.Join(home, `AppData\Local\Programs\Python\Python3*\Lib\site-packages`),
+				filepath.Join(home, `AppData\Roaming\Python\Python3*\site-packages`))
+		}
+	case "darwin":
+		patterns = []string{
+			"/Library/Frameworks/Python.framework/Versions/*/lib/python3*/site-packages",
+			"/opt/homebrew/lib/python3*/site-packages",
+			"/usr/local/lib/python3*/site-packages",
+		}
+		for _, home := range homes {
+			patterns = append(patterns, filepath.Join(home, "Library/Python/*/lib/python/site-packages"))
+		}
+	default:
+		// /usr/lib/python3/dist-packages is owned by dpkg and already
+		// inventoried; /usr/lib*/python3*/site-packages is shared by rpm and
+		// pacman with `sudo pip`, see getPythonPackages
+		patterns = []string{
+			"/usr/local/lib/python3*/site-packages",
+			"/usr/local/lib/python3*/dist-packages",
+			"/usr/lib/python3*/site-packages",
+			"/usr/lib64/python3*/site-packages",
+		}
+		for _, home := range homes {
+			patterns = append(patterns, filepath.Join(home, ".local/lib/python3*/site-packages"))
+		}
+	}
+	return globAll(patterns)
+}
+
+// getPythonPackages reads the core metadata of every *.dist-info (wheel/pip)
+// and *.egg-info (setuptools) entry in the given site-packages directories.
+// In the distribution's own site-packages only what pip or uv installed is
+// reported; the rest belongs to OS packages, which are inventoried already.
+func getPythonPackages(siteDirs []string) []Software {
+	softwareList := []Software{}
+	for _, dir := range siteDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		distroDir := runtime.GOOS == "linux" && strings.HasPrefix(dir, "/usr/lib")
+		for _, entry := range entries {
+			if distroDir && !pythonInstalledByPip(filepath.Join(dir, entry.Name())) {
+				continue
+			}
+			var metadataPath string
+			switch {
+			case strings.HasSuffix(entry.Name(), ".dist-info"):
+				metadataPath = filepath.Join(dir, entry.Name(), "METADATA")
+			case strings.HasSuffix(entry.Name(), ".egg-info") && entry.IsDir():
+				metadataPath = filepath.Join(dir, entry.Name(), "PKG-INFO")
+			case strings.HasSuffix(entry.Name(), ".egg-info"):
+				metadataPath = filepath.Join(dir, entry.Name())
+			default:
+				continue
+			}
+
+			meta := readPythonMetadata(metadataPath)
+			if meta["Name"] == "" {
+				continue
+			}
+			vendor := meta["Author"]
+			if vendor == "" {
+				vendor = meta["Author-email"]
+			}
+			softwareList = append(softwareList, Software{
+				Name:        meta["Name"],
+				Version:     meta["Version"],
+				Vendor:      vendor,
+				Description: meta["Summary"],
+				Source:      SourcePyPI,
+				InstallPath: dir,
+			})
+		}
+	}
+	return softwareList
+}
+
+// pythonInstalledByPip reads the INSTALLER file of a .dist-info directory.
+// Distribution packages record their own tool there ("rpm") or leave it
+// out.
+func pythonInstalledByPip(distInfo string) bool {
+	content, _ := os.ReadFile(filepath.Join(distInfo, "INSTALLER"))
+	switch strings.TrimSpace(string(content)) {
+	case "pip", "uv":
+		return true
+	}
+	return false
+}
+
+// readPythonMetadata parses the RFC 822 header block of a METADATA or
+// PKG-INFO file, stopping at the blank line before the long description.
+func readPythonMetadata(path string) map[string]string {
+	meta := map[string]string{}
+	f, err := os.Open(path)
+	if err != nil {
+		return meta
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.HasPrefix(line, " ") {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		if _, seen := meta[key]; !seen {
+			meta[key] = strings.TrimSpace(parts[1])
+		}
+	}
+	return meta
+}
+
+func npmGlobalModuleDirs(homes []string) []string {
+	var patterns []string
+	switch runtime.GOOS {
+	case "windows":
+		patterns = []string{`C:\Program Files\nodejs\node_modules`}
+		for _, home := range homes {
+			patterns = append(patterns, filepath.Join(home, `AppData\Roaming\npm\node_modules`))
+		}
+	default:
+		patterns = []string{
+			"/usr/lib/node_modules",
+			"/usr/local/lib/node_modules",
+			"/opt/homebrew/lib/node_modules",
+		}
+		for _, home := range homes {
+			patterns = append(patterns,
+				filepath.Join(home, ".npm-global/lib/node_modules"),
+				filepath.Join(home, ".nvm/versions/node/*/lib/node_modules"),
+				filepath.Join(home, ".volta/tools/image/packages/*/lib/node_modules"))
+		}
+	}
+	return globAll(patterns)
+}
+
+// getNpmGlobalPackages reads package.json from each top-level module,
+// descending one level into @scope directories.
+func getNpmGlobalPackages(moduleDirs []string) []Software {
+	softwareList := []Software{}
+	for _, dir := range moduleDirs {
+		var pkgDirs []string
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), "@") {
+				scoped, _ := os.ReadDir(filepath.Join(dir, entry.Name()))
+				for _, s := range scoped {
+					pkgDirs = append(pkgDirs, filepath.Join(dir, entry.Name(), s.Name()))
+				}
+			} else if !strings.HasPrefix(entry.Name(), ".") {
+				pkgDirs = append(pkgDirs, filepath.Join(dir, entry.Name()))
+			}
+		}
+
+		for _, pkgDir := range pkgDirs {
+			content, err := os.ReadFile(filepath.Join(pkgDir, "package.json"))
+			if err != nil {
+				continue
+			}
+			var pkg struct {
+				Name        string          `json:"name"`
+				Version     string          `json:"version"`
+				Description string          `json:"description"`
+				Author      json.RawMessage `json:"author"`
+			}
+			if err := json.Unmarshal(content, &pkg); err != nil || pkg.Name == "" {
+				continue
+			}
+			softwareList = append(softwareList, Software{
+				Name:        pkg.Name,
+				Version:     pkg.Version,
+				Vendor:      npmAuthorName(pkg.Author),
+				Description: pkg.Description,
+				Source:      SourceNpm,
+				InstallPath: pkgDir,
+			})
+		}
+	}
+	return softwareList
+}
+
+// npmAuthorName accepts both the "Name <email> (url)" string form and the
+// {"name": ...} object form of package.json's author field.
+func npmAuthorName(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if i := strings.IndexAny(s, "<("); i > 0 {
+			s = s[:i]
+		}
+		return strings.TrimSpace(s)
+	}
+	var obj struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		return obj.Name
+	}
+	return ""
+}
+
+func gemSpecificationDirs(homes []string) []string {
+	var patterns []string
+	switch runtime.GOOS {
+	case "windows":
+		patterns = []string{`C:\Ruby*\lib\ruby\gems\*\specifications`}
+	case "darwin":
+		patterns = []string{
+			"/Library/Ruby/Gems/*/specifications",
+			"/opt/homebrew/lib/ruby/gems/*/specifications",
+			"/usr/local/lib/ruby/gems/*/specifications",
+		}
+	default:
+		patterns = []string{
+			"/var/lib/gems/*/specifications",
+			"/usr/local/lib/ruby/gems/*/specifications",
+			"/usr/local/share/gems/specifications",
+		}
+	}
+	for _, home := range homes {
+		patterns = append(patterns,
+			filepath.Join(home, ".gem/ruby/*/specifications"),
+			filepath.Join(home, ".local/share/gem/ruby/*/specifications"),
+			filepath.Join(home, ".rbenv/versions/*/lib/ruby/gems/*/specifications"),
+			filepath.Join(home, ".rvm/gems/*/specifications"))
+	}
+	return globAll(patterns)
+}
+
+// getRubyGems derives name and version from the installed gemspec file
+// names, e.g. "nokogiri-1.15.4-x86_64-linux.gemspec". Reading the Ruby
+// source of each spec is not worth it for inventory purposes.
+func getRubyGems(specDirs []string) []Software {
+	softwareList := []Software{}
+	for _, dir := range specDirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.gemspec"))
+		for _, path := range matches {
+			name, version := splitNameVersion(strings.TrimSuffix(filepath.Base(path), ".gemspec"))
+			if name == "" {
+				continue
+			}
+			softwareList = append(softwareList, Software{
+				Name:        name,
+				Version:     version,
+				Source:      SourceGem,
+				InstallPath: filepath.Dir(dir),
+			})
+		}
+	}
+	return softwareList
+}
+
+// splitNameVersion splits "name-with-dashes-1.2.3-platform" at the first
+// dash-separated segment starting with a digit. Any platform suffix after
+// the version is dropped.
+func splitNameVersion(s string) (string, string) {
+	parts := strings.Split(s, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" && parts[i][0] >= '0' && parts[i][0] <= '9' {
+			return strings.Join(parts[:i], "-"), parts[i]
+		}
+	}
+	return s, ""
+}
+
+// getCargoInstalls reads the install tracking file cargo keeps in each
+// user's CARGO_HOME. Keys look like
+// "ripgrep 14.1.0 (registry+https://github.com/rust-lang/crates.io-index)".
+func getCargoInstalls(homes []string) []Software {
+	softwareList := []Software{}
+	for _, home := range homes {
+		cargoHome := filepath.Join(home, ".cargo")
+		content, err := os.ReadFile(filepath.Join(cargoHome, ".crates2.json"))
+		if err != nil {
+			continue
+		}
+		var tracking struct {
+			Installs map[string]json.RawMessage `json:"installs"`
+		}
+		if err := json.Unmarshal(content, &tracking); err != nil {
+			continue
+		}
+		for key := range tracking.Installs {
+			fields := strings.Fields(key)
+			if len(fields) < 2 {
+				continue
+			}
+			softwareList = append(softwareList, Software{
+				Name:        fields[0],
+				Version:     fields[1],
+				Source:      SourceCargo,
+				InstallPath: filepath.Join(cargoHome, "bin"),
+			})
+		}
+	}
+	return softwareList
+}
+
+func goBinDirs(homes []string) []string {
+	var patterns []string
+	for _, env := range []string{"GOBIN", "GOPATH"} {
+		if value := os.Getenv(env); value != "" {
+			for _, dir := range filepath.SplitList(value) {
+				if env == "GOPATH" {
+					dir = filepath.Join(dir, "bin")
+				}
+				patterns = append(patterns, dir)
+			}
+		}
+	}
+	for _, home := range homes {
+		patterns = append(patterns, filepath.Join(home, "go", "bin"))
+	}
+	return globAll(patterns)
+}
+
+// getGoBinaries reads the module build info embedded in every Go binary
+// installed with `go install`.
+func getGoBinaries(binDirs []string) []Software {
+	softwareList := []Software{}
+	for _, dir := range binDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			info, err := buildinfo.ReadFile(path)
+			if err != nil || info.Main.Path == "" {
+				continue
+			}
+			softwareList = append(softwareList, Software{
+				Name:        info.Main.Path,
+				Version:     info.Main.Version,
+				Description: "built with " + info.GoVersion,
+				Source:      SourceGo,
+				InstallPath: path,
+			})
+		}
+	}
+	return softwareList
+}
@@ -0,0 +1,51 @@
+//go:build darwin
+
+package collector
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// getMacOSMonitors pulls EDIDs out of the IORegistry. Intel Macs publish
+// them on AppleDisplay as "IODisplayEDID" = <00ffffffffffff00...>; Apple
+// Silicon publishes "EDID" on the AppleCLCD2 / DCPAVServiceProxy nodes.
+func getMacOSMonitors() []Monitor {
+	monitors := []Monitor{}
+	seen := map[string]bool{}
+	for _, class := range []string{"AppleDisplay", "AppleCLCD2", "DCPAVServiceProxy"} {
+		out, err := exec.Command("ioreg", "-lw0", "-r", "-c", class).Output()
+		if err != nil {
+			continue
+		}
+		for _, m := range parseIORegEDIDs(string(out)) {
+			key := m.ManufacturerID + m.ProductCode + m.SerialNumber
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			monitors = append(monitors, m)
+		}
+	}
+	return monitors
+}
+
+// parseIORegEDIDs extracts every `"IODisplayEDID" = <hex>` or
+// `"EDID" = <hex>` property from ioreg output.
+func parseIORegEDIDs(output string) []Monitor {
+	var monitors []Monitor
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(strings.TrimLeft(line, " |+-o")), " = ")
+		if !ok || (key != `"IODisplayEDID"` && key != `"EDID"`) {
+			continue
+		}
+		m, err := parseEDIDHex(value)
+		if err != nil {
+			continue
+		}
+		// Apple's built-in panels report the APP manufacturer with no serial
+		m.Internal = m.ManufacturerID == "APP" && m.SerialNumber == ""
+		monitors = append(monitors, m)
+	}
+	return monitors
+}
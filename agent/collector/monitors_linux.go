@@ -0,0 +1,44 @@
+//go:build linux
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// getLinuxMonitors reads the EDID of every connected DRM connector, e.g.
+// /sys/class/drm/card0-DP-1/edid.
+func getLinuxMonitors(drmDir string) []Monitor {
+	connectors, _ := filepath.Glob(filepath.Join(drmDir, "card*-*"))
+
+	monitors := []Monitor{}
+	for _, dir := range connectors {
+		if getFileContent(filepath.Join(dir, "status")) != "connected" {
+			continue
+		}
+		edid, err := os.ReadFile(filepath.Join(dir, "edid"))
+		if err != nil || len(edid) == 0 {
+			continue
+		}
+		m, err := parseEDID(edid)
+		if err != nil {
+			continue
+		}
+		// "card0-HDMI-A-1" -> "HDMI-A-1"
+		m.Connector = filepath.Base(dir)[strings.Index(filepath.Base(dir), "-")+1:]
+		m.Internal = isInternalConnector(m.Connector)
+		monitors = append(monitors, m)
+	}
+	return monitors
+}
+
+func isInternalConnector(connector string) bool {
+	for _, prefix := range []string{"eDP", "LVDS", "DSI"} {
+		if strings.HasPrefix(connector, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetLinuxMonitors(t *testing.T) {
+	drm := t.TempDir()
+	connectors := []struct{ name, status, fixture string }{
+		{"card0-eDP-1", "connected", "edid-lgd-panel.bin"},
+		{"card0-DP-2", "connected", "edid-dell-u2419h.bin"},
+		{"card0-HDMI-A-1", "disconnected", ""},
+	}
+	for _, c := range connectors {
+		dir := filepath.Join(drm, c.name)
+		os.Mkdir(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "status"), []byte(c.status+"\n"), 0644)
+		if c.fixture != "" {
+			os.WriteFile(filepath.Join(dir, "edid"), readEDIDFixture(t, c.fixture), 0644)
+		}
+	}
+
+	monitors := getLinuxMonitors(drm)
+	if len(monitors) != 2 {
+		t.Fatalf("got %d monitors, want 2: %+v", len(monitors), monitors)
+	}
+	for _, m := range monitors {
+		switch m.Connector {
+		case "eDP-1":
+			if !m.Internal || m.ManufacturerID != "LGD" {
+				t.Errorf("eDP-1 = %+v, want the internal LGD panel", m)
+			}
+		case "DP-2":
+			if m.Internal || m.Model != "DELL U2419H" {
+				t.Errorf("DP-2 = %+v, want the external Dell", m)
+			}
+		default:
+			t.Errorf("unexpected connector %q", m.Connector)
+		}
+	}
+}
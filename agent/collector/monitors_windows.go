@@ -0,0 +1,120 @@
+//go:build windows
+
+package collector
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Output technologies (D3DKMDT_VIDEO_OUTPUT_TECHNOLOGY) of built-in
+// panels: LVDS, embedded DisplayPort, embedded UDI and "internal"
+const (
+	outputLVDS        = 6
+	outputDPEmbedded  = 11
+	outputUDIEmbedded = 13
+	outputInternal    = 0x80000000
+)
+
+const monitorConnectionsScript = `Get-CimInstance -Namespace root\wmi -ClassName WmiMonitorConnectionParams -ErrorAction SilentlyContinue |
+ForEach-Object { "$($_.InstanceName)|$($_.VideoOutputTechnology)" }`
+
+// getWindowsMonitors reads the EDIDs Windows caches per display under
+// HKLM\SYSTEM\CurrentControlSet\Enum\DISPLAY. The cache also keeps
+// monitors that were attached in the past, so only instances with a live
+// device node (Control subkey) are reported.
+func getWindowsMonitors() []Monitor {
+	cmd := exec.Command("reg", "query", `HKLM\SYSTEM\CurrentControlSet\Enum\DISPLAY`, "/s", "/v", "EDID")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return []Monitor{}
+	}
+	return parseRegEDIDs(out.String(), func(instanceKey string) bool {
+		return exec.Command("reg", "query", instanceKey+`\Control`).Run() == nil
+	}, getWindowsMonitorOutputs())
+}
+
+// getWindowsMonitorOutputs maps each connected display's device instance
+// path, lower-cased, to how it is attached, from WmiMonitorConnectionParams:
+//
+//	DISPLAY\LGD06A5\4&2b1f5a3b&0&UID8388688_0|2147483648
+func getWindowsMonitorOutputs() map[string]uint32 {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", monitorConnectionsScript)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil
+	}
+	return parseMonitorOutputs(out.String())
+}
+
+func parseMonitorOutputs(output string) map[string]uint32 {
+	outputs := map[string]uint32{}
+	for _, line := range strings.Split(output, "\n") {
+		instance, tech, ok := strings.Cut(strings.TrimSpace(line), "|")
+		if !ok {
+			continue
+		}
+		// WMI appends the monitor index to the instance path
+		if i := strings.LastIndex(instance, "_"); i > strings.LastIndex(instance, `\`) {
+			instance = instance[:i]
+		}
+		// Reported as signed by some WMI providers (-2147483648)
+		v, err := strconv.ParseInt(tech, 10, 64)
+		if err != nil {
+			continue
+		}
+		outputs[strings.ToLower(instance)] = uint32(v)
+	}
+	return outputs
+}
+
+func isInternalOutput(tech uint32) bool {
+	switch tech {
+	case outputLVDS, outputDPEmbedded, outputUDIEmbedded, outputInternal:
+		return true
+	}
+	return false
+}
+
+// parseRegEDIDs parses `reg query /s /v EDID` output:
+//
+//	HKEY_LOCAL_MACHINE\...\DISPLAY\DEL4135\5&2b5c7d1&0&UID4352\Device Parameters
+//	    EDID    REG_BINARY    00FFFFFFFFFFFF0010AC35414C...
+//
+// outputs marks built-in panels, as the connector name does on Linux.
+func parseRegEDIDs(output string, connected func(instanceKey string) bool, outputs map[string]uint32) []Monitor {
+	monitors := []Monitor{}
+	key := ""
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "HKEY_") {
+			key = trimmed
+			continue
+		}
+		fields := strings.Fields(trimmed)
+		if len(fields) != 3 || fields[0] != "EDID" || fields[1] != "REG_BINARY" {
+			continue
+		}
+		instanceKey := strings.TrimSuffix(key, `\Device Parameters`)
+		if connected != nil && !connected(instanceKey) {
+			continue
+		}
+		m, err := parseEDIDHex(fields[2])
+		if err != nil {
+			continue
+		}
+		// ...\Enum\DISPLAY\DEL4135\5&2b5c7d1&0&UID4352 -> DISPLAY\DEL4135\5&2b5c7d1&0&UID4352
+		lower := strings.ToLower(instanceKey)
+		if i := strings.Index(lower, `\enum\`); i >= 0 {
+			if tech, ok := outputs[lower[i+len(`\enum\`):]]; ok {
+				m.Internal = isInternalOutput(tech)
+			}
+		}
+		monitors = append(monitors, m)
+	}
+	return monitors
+}
@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestParseRegEDIDs(t *testing.T) {
+	panel := strings.ToUpper(hex.EncodeToString(readEDIDFixture(t, "edid-lgd-panel.bin")))
+	dell := strings.ToUpper(hex.EncodeToString(readEDIDFixture(t, "edid-dell-u2419h.bin")))
+	reg := `
+HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Enum\DISPLAY\LGD06A5\4&2b1f5a3b&0&UID8388688\Device Parameters
+    EDID    REG_BINARY    ` + panel + `
+
+HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Enum\DISPLAY\DEL4135\5&2b5c7d1&0&UID4352\Device Parameters
+    EDID    REG_BINARY    ` + dell + `
+
+HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Enum\DISPLAY\DEL4135\5&1a2b3c4&0&UID4353\Device Parameters
+    EDID    REG_BINARY    ` + dell + `
+
+End of search: 3 match(es) found.
+`
+	wmi := "DISPLAY\\LGD06A5\\4&2b1f5a3b&0&UID8388688_0|-2147483648\r\nDISPLAY\\DEL4135\\5&2b5c7d1&0&UID4352_0|10\r\n"
+	connected := func(key string) bool { return !strings.HasSuffix(key, "UID4353") }
+
+	monitors := parseRegEDIDs(reg, connected, parseMonitorOutputs(wmi))
+	if len(monitors) != 2 {
+		t.Fatalf("got %d monitors, want 2: %+v", len(monitors), monitors)
+	}
+	if m := monitors[0]; m.ManufacturerID != "LGD" || !m.Internal {
+		t.Errorf("panel = %+v, want internal", m)
+	}
+	if m := monitors[1]; m.ManufacturerID != "DEL" || m.Internal {
+		t.Errorf("DisplayPort monitor = %+v, want external", m)
+	}
+}
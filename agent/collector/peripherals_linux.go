@@ -0,0 +1,132 @@
+//go:build linux
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// getLinuxPeripherals lists PCI and USB devices from sysfs and resolves
+// their names through the PCI/USB ID databases.
+func getLinuxPeripherals(sysBus string) []Peripheral {
+	peripherals := []Peripheral{}
+	peripherals = append(peripherals, getPCIDevices(filepath.Join(sysBus, "pci", "devices"), getPCIIDs())...)
+	peripherals = append(peripherals, getUSBDevices(filepath.Join(sysBus, "usb", "devices"), getUSBIDs())...)
+	return peripherals
+}
+
+// getPCIDevices reads /sys/bus/pci/devices/<slot>/{vendor,device,class}.
+// IDs are exposed as "0x8086"; class is "0xCCSSPP".
+func getPCIDevices(dir string, ids *idDatabase) []Peripheral {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	devices := []Peripheral{}
+	for _, entry := range entries {
+		devDir := filepath.Join(dir, entry.Name())
+		vendorID := strings.TrimPrefix(getFileContent(filepath.Join(devDir, "vendor")), "0x")
+		productID := strings.TrimPrefix(getFileContent(filepath.Join(devDir, "device")), "0x")
+		if vendorID == "" {
+			continue
+		}
+
+		p := Peripheral{
+			Bus:       "pci",
+			Address:   entry.Name(),
+			VendorID:  vendorID,
+			ProductID: productID,
+			Driver:    linkBase(filepath.Join(devDir, "driver")),
+		}
+		p.Vendor, p.Product = ids.lookup(vendorID, productID)
+		if class := strings.TrimPrefix(getFileContent(filepath.Join(devDir, "class")), "0x"); len(class) >= 4 {
+			p.ClassCode = class[:4]
+			p.Class = ids.className(class[:2], class[2:4])
+		}
+		devices = append(devices, p)
+	}
+	return devices
+}
+
+// getUSBDevices reads /sys/bus/usb/devices/<bus>-<port>. Interface entries
+// ("1-1:1.0") are folded into their device: composite devices declare their
+// class per interface, and interface drivers (uvcvideo, usbhid) are more
+// telling than the generic "usb" device driver.
+func getUSBDevices(dir string, ids *idDatabase) []Peripheral {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	devices := []Peripheral{}
+	for _, entry := range entries {
+		name := entry.Name()
+		// Skip interfaces and the host controllers' root hubs ("usb1")
+		if strings.Contains(name, ":") || strings.HasPrefix(name, "usb") {
+			continue
+		}
+		devDir := filepath.Join(dir, name)
+		vendorID := getFileContent(filepath.Join(devDir, "idVendor"))
+		productID := getFileContent(filepath.Join(devDir, "idProduct"))
+		if vendorID == "" {
+			continue
+		}
+
+		p := Peripheral{
+			Bus:       "usb",
+			Address:   name,
+			VendorID:  vendorID,
+			ProductID: productID,
+			Serial:    getFileContent(filepath.Join(devDir, "serial")),
+		}
+		p.Vendor, p.Product = ids.lookup(vendorID, productID)
+		// Prefer the database names; fall back to the device's own strings
+		if p.Vendor == "" {
+			p.Vendor = getFileContent(filepath.Join(devDir, "manufacturer"))
+		}
+		if p.Product == "" {
+			p.Product = getFileContent(filepath.Join(devDir, "product"))
+		}
+
+		class := getFileContent(filepath.Join(devDir, "bDeviceClass"))
+		interfaces, _ := filepath.Glob(filepath.Join(dir, name+":*"))
+		drivers := map[string]bool{}
+		for _, iface := range interfaces {
+			if class == "00" || class == "ef" {
+				class = getFileContent(filepath.Join(iface, "bInterfaceClass"))
+			}
+			if driver := linkBase(filepath.Join(iface, "driver")); driver != "" {
+				drivers[driver] = true
+			}
+		}
+		p.ClassCode = class
+		p.Class = ids.className(class, "")
+		p.Driver = joinSortedKeys(drivers)
+
+		devices = append(devices, p)
+	}
+	return devices
+}
+
+// linkBase returns the final element of a symlink target, e.g. the driver
+// name from /sys/.../driver -> ../../bus/pci/drivers/e1000e.
+func linkBase(path string) string {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+func joinSortedKeys(m map[string]bool) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,387 @@
+//go:build linux
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Directories that may hold the rpm database. Fedora 36+ and openSUSE moved
+// it under /usr/lib/sysimage and left /var/lib/rpm as a symlink.
+var rpmDBDirs = []string{"/var/lib/rpm", "/usr/lib/sysimage/rpm"}
+
+// readRpmDatabase reads installed package headers straight from the rpm
+// database, whichever backend the distribution uses: sqlite (Fedora 33+,
+// RHEL 9), ndb (openSUSE/SLES 15) or Berkeley DB hash (RHEL 7/8, CentOS).
+func readRpmDatabase() ([]Software, error) {
+	for _, dir := range rpmDBDirs {
+		var blobs [][]byte
+		var err error
+		switch {
+		case fileExists(filepath.Join(dir, "rpmdb.sqlite")):
+			blobs, err = readRpmSQLite(filepath.Join(dir, "rpmdb.sqlite"))
+		case fileExists(filepath.Join(dir, "Packages.db")):
+			blobs, err = readRpmNDB(filepath.Join(dir, "Packages.db"))
+		case fileExists(filepath.Join(dir, "Packages")):
+			blobs, err = readRpmBDB(filepath.Join(dir, "Packages"))
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		softwareList := []Software{}
+		for _, blob := range blobs {
+			sw, err := parseRpmHeader(blob)
+			if err != nil || sw.Name == "" || sw.Name == "gpg-pubkey" {
+				continue
+			}
+			softwareList = append(softwareList, sw)
+		}
+		return softwareList, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// readRpmSQLite returns the header blobs of the Packages(hnum, blob) table.
+func readRpmSQLite(path string) ([][]byte, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var blobs [][]byte
+	err = db.tableRows("Packages", func(_ int64, values []any) error {
+		if len(values) >= 2 {
+			if blob, ok := values[1].([]byte); ok {
+				blobs = append(blobs, blob)
+			}
+		}
+		return nil
+	})
+	return blobs, err
+}
+
+// ndb layout constants from rpm's lib/backend/ndb/rpmpkg.c
+const (
+	ndbHeaderMagic = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	ndbSlotMagic   = 'S' | 'l'<<8 | 'o'<<16 | 't'<<24
+	ndbBlobMagic   = 'B' | 'l'<<8 | 'b'<<16 | 'S'<<24
+	ndbPageSize    = 4096
+	ndbSlotSize    = 16
+	ndbBlockSize   = 16
+	ndbBlobHdrSize = 16
+)
+
+// Largest header blob accepted; real headers are well under 1 MiB, so a
+// bigger length is corruption and must not drive an allocation
+const rpmMaxHeaderSize = 64 << 20
+
+// readRpmNDB reads the slot table at the start of Packages.db, then the blob
+// each used slot points at. All integers are little-endian.
+func readRpmNDB(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Header: magic, version, generation, slot page count, then padding
+	// up to the size of two slots.
+	header := make([]byte, 2*ndbSlotSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(header) != ndbHeaderMagic {
+		return nil, fmt.Errorf("%s: bad ndb magic", path)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	slotPages := binary.LittleEndian.Uint32(header[12:])
+	if slotPages == 0 || int64(slotPages)*ndbPageSize > fi.Size() {
+		return nil, fmt.Errorf("%s: implausible slot page count %d", path, slotPages)
+	}
+
+	slots := make([]byte, int(slotPages)*ndbPageSize-len(header))
+	if _, err := io.ReadFull(f, slots); err != nil {
+		return nil, err
+	}
+
+	var blobs [][]byte
+	for off := 0; off+ndbSlotSize <= len(slots); off += ndbSlotSize {
+		slot := slots[off : off+ndbSlotSize]
+		if binary.LittleEndian.Uint32(slot) != ndbSlotMagic {
+			continue
+		}
+		pkgIdx := binary.LittleEndian.Uint32(slot[4:])
+		blkOff := int64(binary.LittleEndian.Uint32(slot[8:]))
+		if pkgIdx == 0 {
+			continue
+		}
+
+		blobHdr := make([]byte, ndbBlobHdrSize)
+		if _, err := f.ReadAt(blobHdr, blkOff*ndbBlockSize); err != nil {
+			continue
+		}
+		if binary.LittleEndian.Uint32(blobHdr) != ndbBlobMagic || binary.LittleEndian.Uint32(blobHdr[4:]) != pkgIdx {
+			continue
+		}
+		length := binary.LittleEndian.Uint32(blobHdr[12:])
+		if length > rpmMaxHeaderSize {
+			continue
+		}
+		blob := make([]byte, length)
+		if _, err := f.ReadAt(blob, blkOff*ndbBlockSize+ndbBlobHdrSize); err != nil {
+			continue
+		}
+		blobs = append(blobs, blob)
+	}
+	return blobs, nil
+}
+
+// Berkeley DB hash access method constants (db/dbinc/db_page.h)
+const (
+	bdbHashMagic     = 0x061561
+	bdbPageHeaderLen = 26
+	bdbPageHash      = 13 // P_HASH
+	bdbPageHashUns   = 2  // P_HASH_UNSORTED
+	bdbPageOverflow  = 7  // P_OVERFLOW
+	bdbItemKeyData   = 1  // H_KEYDATA
+	bdbItemOffPage   = 3  // H_OFFPAGE
+)
+
+// readRpmBDB scans every page of a Berkeley DB hash file for values. Keys
+// are 4-byte package numbers; values are header blobs, almost always stored
+// on overflow pages. The file uses the writer's byte order, detected from
+// the magic number.
+func readRpmBDB(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 512 {
+		return nil, fmt.Errorf("%s: file too short", path)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(data[12:]) != bdbHashMagic {
+		order = binary.BigEndian
+		if order.Uint32(data[12:]) != bdbHashMagic {
+			return nil, fmt.Errorf("%s: not a Berkeley DB hash database", path)
+		}
+	}
+	pageSize := int(order.Uint32(data[20:]))
+	if pageSize < 512 || pageSize > 65536 {
+		return nil, fmt.Errorf("%s: bad page size %d", path, pageSize)
+	}
+	lastPage := int(order.Uint32(data[32:]))
+
+	page := func(n int) []byte {
+		start := n * pageSize
+		if n < 0 || start+pageSize > len(data) {
+			return nil
+		}
+		return data[start : start+pageSize]
+	}
+
+	var blobs [][]byte
+	for n := 1; n <= lastPage; n++ {
+		p := page(n)
+		if p == nil {
+			break
+		}
+		if p[25] != bdbPageHash && p[25] != bdbPageHashUns {
+			continue
+		}
+
+		entries := int(order.Uint16(p[20:]))
+		if bdbPageHeaderLen+2*entries > pageSize {
+			return nil, fmt.Errorf("%s: page %d claims %d entries", path, n, entries)
+		}
+		// Index entries alternate key, value; the value is at odd indexes.
+		for i := 1; i < entries; i += 2 {
+			itemOff := int(order.Uint16(p[bdbPageHeaderLen+2*i:]))
+			if itemOff >= pageSize {
+				continue
+			}
+			switch p[itemOff] {
+			case bdbItemOffPage:
+				// type(1) pad(3) pgno(4) tlen(4)
+				if itemOff+12 > pageSize {
+					return nil, fmt.Errorf("%s: page %d overflow item runs off the page", path, n)
+				}
+				pgno := int(order.Uint32(p[itemOff+4:]))
+				length := int(order.Uint32(p[itemOff+8:]))
+				if blob := readBDBOverflow(page, order, pgno, length); blob != nil {
+					blobs = append(blobs, blob)
+				}
+			case bdbItemKeyData:
+				// Inline items run up to the previous item's offset
+				end := pageSize
+				if i > 0 {
+					end = int(order.Uint16(p[bdbPageHeaderLen+2*(i-1):]))
+				}
+				if end > itemOff+1 && end <= pageSize {
+					blobs = append(blobs, append([]byte(nil), p[itemOff+1:end]...))
+				}
+			}
+		}
+	}
+	return blobs, nil
+}
+
+// readBDBOverflow follows a chain of overflow pages. Every page must add
+// data, so a chain that loops back on itself ends at length.
+func readBDBOverflow(page func(int) []byte, order binary.ByteOrder, pgno, length int) []byte {
+	if length > rpmMaxHeaderSize {
+		return nil
+	}
+	blob := make([]byte, 0, length)
+	for pgno != 0 && len(blob) < length {
+		p := page(pgno)
+		if p == nil || p[25] != bdbPageOverflow {
+			return nil
+		}
+		// hf_offset holds the number of bytes used on an overflow page
+		used := int(order.Uint16(p[22:]))
+		if used == 0 || bdbPageHeaderLen+used > len(p) {
+			return nil
+		}
+		blob = append(blob, p[bdbPageHeaderLen:bdbPageHeaderLen+used]...)
+		pgno = int(order.Uint32(p[16:]))
+	}
+	if len(blob) != length {
+		return nil
+	}
+	return blob
+}
+
+// rpm header tags and types (rpmtag.h)
+const (
+	rpmTagName        = 1000
+	rpmTagVersion     = 1001
+	rpmTagRelease     = 1002
+	rpmTagEpoch       = 1003
+	rpmTagSummary     = 1004
+	rpmTagInstallTime = 1008
+	rpmTagSize        = 1009
+	rpmTagVendor      = 1011
+	rpmTagArch        = 1022
+	rpmTagSourceRPM   = 1044
+
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+)
+
+// parseRpmHeader decodes an immutable header blob as stored in the database:
+// big-endian index count and data length, 16-byte index entries
+// (tag, type, offset, count), then the data store.
+func parseRpmHeader(blob []byte) (Software, error) {
+	if len(blob) < 8 {
+		return Software{}, fmt.Errorf("header too short")
+	}
+	indexCount := int(binary.BigEndian.Uint32(blob))
+	dataLen := int(binary.BigEndian.Uint32(blob[4:]))
+	if indexCount <= 0 || indexCount > len(blob)/16 || dataLen > len(blob) {
+		return Software{}, fmt.Errorf("header index overruns blob")
+	}
+	dataStart := 8 + indexCount*16
+	if dataStart+dataLen > len(blob) {
+		return Software{}, fmt.Errorf("header index overruns blob")
+	}
+	store := blob[dataStart : dataStart+dataLen]
+
+	var version, release, epoch string
+	sw := Software{Source: SourceRPM}
+	for i := 0; i < indexCount; i++ {
+		entry := blob[8+i*16:]
+		tag := binary.BigEndian.Uint32(entry)
+		typ := binary.BigEndian.Uint32(entry[4:])
+		off := int(binary.BigEndian.Uint32(entry[8:]))
+		if off < 0 || off >= len(store) {
+			continue
+		}
+
+		var str string
+		var num int64
+		switch typ {
+		case rpmTypeString, rpmTypeI18NString, rpmTypeStringArray:
+			end := bytes.IndexByte(store[off:], 0)
+			if end < 0 {
+				continue
+			}
+			str = string(store[off : off+end])
+		case rpmTypeInt32:
+			if off+4 > len(store) {
+				continue
+			}
+			num = int64(binary.BigEndian.Uint32(store[off:]))
+		default:
+			continue
+		}
+
+		switch tag {
+		case rpmTagName:
+			sw.Name = str
+		case rpmTagVersion:
+			version = str
+		case rpmTagRelease:
+			release = str
+		case rpmTagEpoch:
+			epoch = strconv.FormatInt(num, 10)
+		case rpmTagSummary:
+			sw.Description = str
+		case rpmTagInstallTime:
+			sw.InstallDate = unixToDate(strconv.FormatInt(num, 10))
+		case rpmTagSize:
+			sw.SizeBytes = num
+		case rpmTagVendor:
+			sw.Vendor = str
+		case rpmTagArch:
+			sw.Architecture = str
+		case rpmTagSourceRPM:
+			sw.SourcePackage = sourceRPMName(str)
+		}
+	}
+
+	// Full EVR so later version comparisons see the distro release
+	sw.Version = version
+	if release != "" {
+		sw.Version += "-" + release
+	}
+	if epoch != "" && epoch != "0" {
+		sw.Version = epoch + ":" + sw.Version
+	}
+	if sw.SourcePackage == sw.Name {
+		sw.SourcePackage = ""
+	}
+	return sw, nil
+}
+
+// sourceRPMName returns the package name of a SOURCERPM value:
+// "bash-5.1.8-6.el9.src.rpm" is "bash".
+func sourceRPMName(srpm string) string {
+	parts := strings.Split(strings.TrimSuffix(srpm, ".src.rpm"), "-")
+	if len(parts) < 3 {
+		return ""
+	}
+	return strings.Join(parts[:len(parts)-2], "-")
+}
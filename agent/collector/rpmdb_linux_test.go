@@ -0,0 +1,216 @@
+package collector
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// rpmHeader builds an immutable header blob holding string tags.
+func rpmHeader(tags map[uint32]string) []byte {
+	var index, store []byte
+	for _, tag := range []uint32{rpmTagName, rpmTagVersion, rpmTagRelease, rpmTagArch} {
+		value, ok := tags[tag]
+		if !ok {
+			continue
+		}
+		entry := make([]byte, 16)
+		binary.BigEndian.PutUint32(entry, tag)
+		binary.BigEndian.PutUint32(entry[4:], rpmTypeString)
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(store)))
+		binary.BigEndian.PutUint32(entry[12:], 1)
+		index = append(index, entry...)
+		store = append(store, value+"\x00"...)
+	}
+	blob := make([]byte, 8)
+	binary.BigEndian.PutUint32(blob, uint32(len(index)/16))
+	binary.BigEndian.PutUint32(blob[4:], uint32(len(store)))
+	return append(append(blob, index...), store...)
+}
+
+var testRpmBlob = rpmHeader(map[uint32]string{
+	rpmTagName: "bash", rpmTagVersion: "5.2.26", rpmTagRelease: "3.fc40", rpmTagArch: "x86_64",
+})
+
+// ndbFile lays out a Packages.db with one slot page and one blob.
+func ndbFile(blob []byte) []byte {
+	le := binary.LittleEndian
+	data := make([]byte, ndbPageSize)
+	le.PutUint32(data, ndbHeaderMagic)
+	le.PutUint32(data[12:], 1)
+
+	slot := data[2*ndbSlotSize:]
+	le.PutUint32(slot, ndbSlotMagic)
+	le.PutUint32(slot[4:], 1)
+	le.PutUint32(slot[8:], ndbPageSize/ndbBlockSize)
+	le.PutUint32(slot[12:], uint32((ndbBlobHdrSize+len(blob)+ndbBlockSize-1)/ndbBlockSize))
+
+	hdr := make([]byte, ndbBlobHdrSize)
+	le.PutUint32(hdr, ndbBlobMagic)
+	le.PutUint32(hdr[4:], 1)
+	le.PutUint32(hdr[12:], uint32(len(blob)))
+	return append(append(data, hdr...), blob...)
+}
+
+// bdbFile lays out a little-endian Berkeley DB hash file: the meta page, a
+// hash page with one key and an off-page value, and the overflow page.
+func bdbFile(blob []byte) []byte {
+	const pageSize = 512
+	le := binary.LittleEndian
+	data := make([]byte, 3*pageSize)
+	le.PutUint32(data[12:], bdbHashMagic)
+	le.PutUint32(data[20:], pageSize)
+	le.PutUint32(data[32:], 2)
+
+	p := data[pageSize : 2*pageSize]
+	p[25] = bdbPageHash
+	le.PutUint16(p[20:], 2)
+	keyOff, valOff := pageSize-5, pageSize-17
+	le.PutUint16(p[bdbPageHeaderLen:], uint16(keyOff))
+	le.PutUint16(p[bdbPageHeaderLen+2:], uint16(valOff))
+	p[keyOff] = bdbItemKeyData
+	le.PutUint32(p[keyOff+1:], 1)
+	p[valOff] = bdbItemOffPage
+	le.PutUint32(p[valOff+4:], 2)
+	le.PutUint32(p[valOff+8:], uint32(len(blob)))
+
+	o := data[2*pageSize:]
+	o[25] = bdbPageOverflow
+	le.PutUint16(o[22:], uint16(len(blob)))
+	copy(o[bdbPageHeaderLen:], blob)
+	return data
+}
+
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readRpmFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRpmDatabaseBackends(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(string) ([][]byte, error)
+		data []byte
+		want []string
+		bash string
+	}{
+		{"Packages.db", readRpmNDB, ndbFile(testRpmBlob), []string{"bash"}, "5.2.26-3.fc40"},
+		{"Packages", readRpmBDB, bdbFile(testRpmBlob), []string{"bash"}, "5.2.26-3.fc40"},
+		{"rpmdb.sqlite", readRpmSQLite, readRpmFixture(t, "rpmdb.sqlite"), []string{"bash", "gpg-pubkey"}, "1:5.2.26-3.fc40"},
+	}
+	for _, tt := range tests {
+		blobs, err := tt.read(writeTemp(t, tt.name, tt.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(blobs) != len(tt.want) {
+			t.Fatalf("%s: got %d blobs, want %d", tt.name, len(blobs), len(tt.want))
+		}
+		for i, blob := range blobs {
+			sw, err := parseRpmHeader(blob)
+			if err != nil || sw.Name != tt.want[i] {
+				t.Errorf("%s: blob %d = %+v, %v; want %s", tt.name, i, sw, err, tt.want[i])
+			}
+		}
+		if sw, _ := parseRpmHeader(blobs[0]); sw.Version != tt.bash || sw.Architecture != "x86_64" {
+			t.Errorf("%s: bash = %+v, want version %s", tt.name, sw, tt.bash)
+		}
+	}
+}
+
+// Corrupt databases must fail or come back short, never panic or allocate
+// whatever a garbage length asks for.
+func TestRpmDatabaseCorrupt(t *testing.T) {
+	backends := []struct {
+		name string
+		read func(string) ([][]byte, error)
+		data []byte
+	}{
+		{"Packages.db", readRpmNDB, ndbFile(testRpmBlob)},
+		{"Packages", readRpmBDB, bdbFile(testRpmBlob)},
+		{"rpmdb.sqlite", readRpmSQLite, readRpmFixture(t, "rpmdb.sqlite")},
+	}
+	for _, b := range backends {
+		path := filepath.Join(t.TempDir(), b.name)
+		read := func(data []byte) {
+			os.WriteFile(path, data, 0644)
+			blobs, _ := b.read(path)
+			for _, blob := range blobs {
+				parseRpmHeader(blob)
+			}
+		}
+		for _, n := range []int{0, 16, 100, len(b.data) / 2, len(b.data) - 1} {
+			read(b.data[:n])
+		}
+		for i := range b.data {
+			for _, v := range []byte{0x00, 0xff, b.data[i] ^ 0x80} {
+				data := append([]byte(nil), b.data...)
+				data[i] = v
+				read(data)
+			}
+		}
+	}
+
+	// Lengths and counts that would index past the page or allocate
+	// gigabytes
+	le := binary.LittleEndian
+	ndb := ndbFile(testRpmBlob)
+	le.PutUint32(ndb[ndbPageSize+12:], 0xffffffff)
+	if blobs, err := readRpmNDB(writeTemp(t, "Packages.db", ndb)); err != nil || len(blobs) != 0 {
+		t.Errorf("ndb with a 4 GiB blob = %d blobs, %v; want the slot skipped", len(blobs), err)
+	}
+	ndb = ndbFile(testRpmBlob)
+	le.PutUint32(ndb[12:], 1<<20)
+	if _, err := readRpmNDB(writeTemp(t, "Packages.db", ndb)); err == nil {
+		t.Error("ndb with more slot pages than the file holds: want an error")
+	}
+
+	bdb := bdbFile(testRpmBlob)
+	le.PutUint16(bdb[512+20:], 0xffff)
+	if _, err := readRpmBDB(writeTemp(t, "Packages", bdb)); err == nil {
+		t.Error("bdb page with 65535 entries: want an error")
+	}
+	bdb = bdbFile(testRpmBlob)
+	le.PutUint16(bdb[512+bdbPageHeaderLen+2:], 510)
+	bdb[512+510] = bdbItemOffPage
+	if _, err := readRpmBDB(writeTemp(t, "Packages", bdb)); err == nil {
+		t.Error("bdb overflow item at the page end: want an error")
+	}
+	bdb = bdbFile(testRpmBlob)
+	le.PutUint32(bdb[1024+16:], 2)
+	le.PutUint32(bdb[512+512-17+8:], 1<<30)
+	if blobs, err := readRpmBDB(writeTemp(t, "Packages", bdb)); err != nil || len(blobs) != 0 {
+		t.Errorf("bdb with a looping 1 GiB overflow chain = %d blobs, %v; want it skipped", len(blobs), err)
+	}
+}
+
+func TestParseRpmHeaderCorrupt(t *testing.T) {
+	for _, blob := range [][]byte{
+		nil,
+		{0, 0, 0, 1},
+		{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0},
+		{0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff},
+		{0x80, 0, 0, 0, 0x80, 0, 0, 0},
+	} {
+		if _, err := parseRpmHeader(blob); err == nil {
+			t.Errorf("parseRpmHeader(% x): want an error", blob)
+		}
+	}
+	for n := range testRpmBlob {
+		parseRpmHeader(testRpmBlob[:n])
+	}
+}
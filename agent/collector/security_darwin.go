@@ -0,0 +1,112 @@
+//go:build darwin
+
+package collector
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// getSecurityInfo collects the Application Layer Firewall and screen lock
+// state. Macs have no TPM; the Secure Enclave is not reported as one.
+func getSecurityInfo() *SecurityInfo {
+	sec := &SecurityInfo{
+		Firewall:   getMacOSFirewallStatus(),
+		SecureBoot: getMacOSSecureBootState(),
+	}
+	sec.ScreenLockEnabled, sec.IdleTimeoutSeconds = getMacOSScreenLock()
+	return sec
+}
+
+func getMacOSFirewallStatus() FirewallStatus {
+	// Output: "Firewall is enabled. (State = 1)"
+	out, err := exec.Command("/usr/libexec/ApplicationFirewall/socketfilterfw", "--getglobalstate").Output()
+	if err != nil {
+		return FirewallStatus{Product: "alf"}
+	}
+	output := string(out)
+	enabled := strings.Contains(output, "enabled") || strings.Contains(output, "State = 1") || strings.Contains(output, "State = 2")
+	return FirewallStatus{Enabled: enabled, Product: "alf"}
+}
+
+// getMacOSSecureBootState reads the boot policy from the T2/Apple Silicon
+// firmware. Intel Macs without a T2 chip report "unsupported".
+func getMacOSSecureBootState() string {
+	out, err := exec.Command("system_profiler", "SPiBridgeDataType").Output()
+	if err != nil {
+		return SecureBootUnknown
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "Secure Boot:") {
+			continue
+		}
+		value := strings.TrimSpace(strings.TrimPrefix(line, "Secure Boot:"))
+		switch {
+		case strings.HasPrefix(value, "Full"), strings.HasPrefix(value, "Medium"):
+			return SecureBootEnabled
+		case strings.HasPrefix(value, "No Security"), strings.HasPrefix(value, "Permissive"):
+			return SecureBootDisabled
+		}
+		return SecureBootUnknown
+	}
+	return SecureBootUnsupported
+}
+
+// getMacOSScreenLock reads the screen lock settings of the user at the
+// console. Both are per-user preferences, so sysadminctl and defaults run as
+// that user in their launchd session; run as root they describe root. The
+// login window and machines nobody is logged on to report nothing.
+func getMacOSScreenLock() (*bool, int) {
+	u, ok := getMacOSConsoleUser()
+	if !ok {
+		return nil, 0
+	}
+
+	var enabled *bool
+	// sysadminctl writes its status to stderr: "screenLock delay is 5 seconds" / "screenLock is off"
+	cmd := consoleUserCommand(u, "sysadminctl", "-screenLock", "status")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err == nil {
+		v := !strings.Contains(stderr.String(), "screenLock is off")
+		enabled = &v
+	}
+
+	idle := 0
+	if out, err := consoleUserCommand(u, "defaults", "-currentHost", "read", "com.apple.screensaver", "idleTime").Output(); err == nil {
+		idle, _ = strconv.Atoi(strings.TrimSpace(string(out)))
+	}
+	return enabled, idle
+}
+
+// getMacOSConsoleUser returns the owner of /dev/console, which is root
+// while the login window is showing.
+func getMacOSConsoleUser() (*user.User, bool) {
+	out, err := exec.Command("stat", "-f%Su", "/dev/console").Output()
+	if err != nil {
+		return nil, false
+	}
+	name := strings.TrimSpace(string(out))
+	if name == "" || name == "root" || name == "loginwindow" {
+		return nil, false
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, false
+	}
+	return u, true
+}
+
+// consoleUserCommand prepares a command to run as u inside their launchd
+// bootstrap session, where their preferences domain is visible.
+func consoleUserCommand(u *user.User, name string, args ...string) *exec.Cmd {
+	if strconv.Itoa(os.Getuid()) == u.Uid {
+		return exec.Command(name, args...)
+	}
+	return exec.Command("launchctl", append([]string{"asuser", u.Uid, "sudo", "-u", u.Username, name}, args...)...)
+}
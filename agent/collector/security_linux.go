@@ -0,0 +1,223 @@
+//go:build linux
+
+package collector
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// EFI global variable GUID under which SecureBoot is stored
+const efiSecureBootVar = "/sys/firmware/efi/efivars/SecureBoot-8be4df61-93ca-11d2-aa0d-00e098032b8c"
+
+// getSecurityInfo collects firewall, Secure Boot, TPM and screen lock state.
+func getSecurityInfo() *SecurityInfo {
+	sec := &SecurityInfo{
+		Firewall:   getLinuxFirewallStatus(),
+		SecureBoot: getLinuxSecureBootState(),
+	}
+	sec.TPMPresent, sec.TPMVersion = getLinuxTPMInfo("/sys/class/tpm")
+	sec.ScreenLockEnabled, sec.IdleTimeoutSeconds = getLinuxScreenLock()
+	return sec
+}
+
+// getLinuxFirewallStatus checks the common firewall front-ends first (they
+// describe intent), then falls back to inspecting the kernel rulesets.
+func getLinuxFirewallStatus() FirewallStatus {
+	// firewalld
+	if out, err := exec.Command("firewall-cmd", "--state").Output(); err == nil {
+		if strings.TrimSpace(string(out)) == "running" {
+			return FirewallStatus{Enabled: true, Product: "firewalld"}
+		}
+	}
+
+	// ufw (the config file is readable without root, the CLI is not)
+	if out, err := exec.Command("ufw", "status").Output(); err == nil {
+		if strings.Contains(string(out), "Status: active") {
+			return FirewallStatus{Enabled: true, Product: "ufw"}
+		}
+	} else if strings.Contains(getFileContent("/etc/ufw/ufw.conf"), "ENABLED=yes") {
+		return FirewallStatus{Enabled: true, Product: "ufw"}
+	}
+
+	// nftables
+	if out, err := exec.Command("nft", "list", "ruleset").Output(); err == nil {
+		if nftHasInputFilter(string(out)) {
+			return FirewallStatus{Enabled: true, Product: "nftables"}
+		}
+	}
+
+	// iptables (legacy or nft backend)
+	if out, err := exec.Command("iptables", "-S", "INPUT").Output(); err == nil {
+		if iptablesInputFiltered(string(out)) {
+			return FirewallStatus{Enabled: true, Product: "iptables"}
+		}
+	}
+
+	return FirewallStatus{Enabled: false}
+}
+
+// nftHasInputFilter reports whether an nftables ruleset contains an input
+// hook chain that either drops by default or carries at least one rule.
+func nftHasInputFilter(ruleset string) bool {
+	inInputChain := false
+	for _, line := range strings.Split(ruleset, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "chain "), line == "}":
+			inInputChain = false
+		case strings.Contains(line, "hook input"):
+			inInputChain = true
+			if strings.Contains(line, "policy drop") {
+				return true
+			}
+		case inInputChain && line != "":
+			return true
+		}
+	}
+	return false
+}
+
+// iptablesInputFiltered reports whether `iptables -S INPUT` output shows a
+// non-ACCEPT policy or any appended rules.
+func iptablesInputFiltered(output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "-P" && fields[2] != "ACCEPT" {
+			return true
+		}
+		if len(fields) >= 2 && fields[0] == "-A" {
+			return true
+		}
+	}
+	return false
+}
+
+func getLinuxSecureBootState() string {
+	if _, err := os.Stat("/sys/firmware/efi"); err != nil {
+		// Booted via legacy BIOS
+		return SecureBootUnsupported
+	}
+	data, err := os.ReadFile(efiSecureBootVar)
+	if err != nil {
+		return SecureBootUnknown
+	}
+	return parseSecureBootVar(data)
+}
+
+// parseSecureBootVar decodes an efivarfs SecureBoot variable: 4 bytes of
+// attributes followed by a single byte value.
+func parseSecureBootVar(data []byte) string {
+	if len(data) < 5 {
+		return SecureBootUnknown
+	}
+	if data[4] == 1 {
+		return SecureBootEnabled
+	}
+	return SecureBootDisabled
+}
+
+// getLinuxTPMInfo inspects the first TPM device exposed under sysfs.
+func getLinuxTPMInfo(tpmClassDir string) (bool, string) {
+	dev := filepath.Join(tpmClassDir, "tpm0")
+	if _, err := os.Stat(dev); err != nil {
+		return false, ""
+	}
+
+	// Kernel 5.6+ exposes the major version directly
+	switch getFileContent(filepath.Join(dev, "tpm_version_major")) {
+	case "2":
+		return true, "2.0"
+	case "1":
+		return true, "1.2"
+	}
+
+	// Older kernels only publish "caps" for TPM 1.2 devices
+	if _, err := os.Stat(filepath.Join(dev, "caps")); err == nil {
+		return true, "1.2"
+	}
+	if _, err := os.Stat("/dev/tpmrm0"); err == nil {
+		return true, "2.0"
+	}
+	return true, ""
+}
+
+// getLinuxScreenLock reads the GNOME screensaver settings of the user at
+// the console. gsettings runs as that user against their session bus; the
+// agent's own settings as root say nothing about the desktop. Other
+// desktops, headless servers and machines nobody is logged on to report
+// nothing.
+func getLinuxScreenLock() (*bool, int) {
+	uid, ok := getLinuxConsoleUID()
+	if !ok {
+		return nil, 0
+	}
+
+	var enabled *bool
+	if out, err := consoleUserCommand(uid, "gsettings", "get", "org.gnome.desktop.screensaver", "lock-enabled").Output(); err == nil {
+		v := strings.TrimSpace(string(out)) == "true"
+		enabled = &v
+	}
+
+	idle := 0
+	if out, err := consoleUserCommand(uid, "gsettings", "get", "org.gnome.desktop.session", "idle-delay").Output(); err == nil {
+		// Output: "uint32 300"
+		fields := strings.Fields(string(out))
+		if len(fields) > 0 {
+			idle, _ = strconv.Atoi(fields[len(fields)-1])
+		}
+	}
+	return enabled, idle
+}
+
+// getLinuxConsoleUID returns the owner of the active session on seat0 as
+// reported by logind, or without logind the only non-root user with a
+// session bus.
+func getLinuxConsoleUID() (int, bool) {
+	if out, err := exec.Command("loginctl", "show-seat", "seat0", "--property=ActiveSession", "--value").Output(); err == nil {
+		if session := strings.TrimSpace(string(out)); session != "" {
+			if out, err := exec.Command("loginctl", "show-session", session, "--property=User", "--value").Output(); err == nil {
+				if uid, err := strconv.Atoi(strings.TrimSpace(string(out))); err == nil {
+					return uid, true
+				}
+			}
+		}
+	}
+
+	buses, _ := filepath.Glob("/run/user/*/bus")
+	var uids []int
+	for _, bus := range buses {
+		if uid, err := strconv.Atoi(filepath.Base(filepath.Dir(bus))); err == nil && uid != 0 {
+			uids = append(uids, uid)
+		}
+	}
+	if len(uids) == 1 {
+		return uids[0], true
+	}
+	return 0, false
+}
+
+// consoleUserCommand prepares a command to run as uid inside their desktop
+// session: with their credentials, home directory and session bus.
+func consoleUserCommand(uid int, name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	runtimeDir := "/run/user/" + strconv.Itoa(uid)
+	cmd.Env = append(os.Environ(),
+		"XDG_RUNTIME_DIR="+runtimeDir,
+		"DBUS_SESSION_BUS_ADDRESS=unix:path="+runtimeDir+"/bus",
+	)
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		cmd.Env = append(cmd.Env, "HOME="+u.HomeDir, "USER="+u.Username)
+		if gid, err := strconv.Atoi(u.Gid); err == nil && os.Getuid() != uid {
+			cmd.SysProcAttr = &syscall.SysProcAttr{
+				Credential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)},
+			}
+		}
+	}
+	return cmd
+}
@@ -0,0 +1,165 @@
+//go:build windows
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// getSecurityInfo collects Windows Firewall profiles, Secure Boot, TPM and
+// screen saver lock state.
+func getSecurityInfo() *SecurityInfo {
+	sec := &SecurityInfo{
+		Firewall:   getWindowsFirewallStatus(),
+		SecureBoot: getWindowsSecureBootState(),
+	}
+	sec.TPMPresent, sec.TPMVersion = getWindowsTPMInfo()
+	sec.ScreenLockEnabled, sec.IdleTimeoutSeconds = getWindowsScreenLock()
+	return sec
+}
+
+// getWindowsFirewallStatus parses `netsh advfirewall show allprofiles state`.
+// The firewall counts as enabled only if every profile is on.
+func getWindowsFirewallStatus() FirewallStatus {
+	status := FirewallStatus{Product: "windows_firewall", Profiles: map[string]bool{}}
+
+	cmd := exec.Command("netsh", "advfirewall", "show", "allprofiles", "state")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return status
+	}
+
+	// Output:
+	// Domain Profile Settings:
+	// ----------------------------------------------------------------------
+	// State                                 ON
+	profile := ""
+	for _, line := range strings.Split(out.String(), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasSuffix(trimmed, "Profile Settings:") {
+			profile = strings.ToLower(strings.Fields(trimmed)[0])
+			continue
+		}
+		fields := strings.Fields(trimmed)
+		if profile != "" && len(fields) == 2 && fields[0] == "State" {
+			status.Profiles[profile] = strings.EqualFold(fields[1], "ON")
+		}
+	}
+
+	status.Enabled = len(status.Profiles) > 0
+	for _, on := range status.Profiles {
+		status.Enabled = status.Enabled && on
+	}
+	return status
+}
+
+func getWindowsSecureBootState() string {
+	value, err := getRegistryValue(`HKLM\SYSTEM\CurrentControlSet\Control\SecureBoot\State`, "UEFISecureBootEnabled")
+	if err != nil {
+		// The key is absent on legacy BIOS installs
+		return SecureBootUnsupported
+	}
+	switch value {
+	case "0x1":
+		return SecureBootEnabled
+	case "0x0":
+		return SecureBootDisabled
+	}
+	return SecureBootUnknown
+}
+
+// getWindowsTPMInfo queries Win32_Tpm (requires administrator rights).
+// SpecVersion looks like "2.0, 0, 1.38".
+func getWindowsTPMInfo() (bool, string) {
+	cmd := exec.Command("wmic", `/namespace:\\root\cimv2\security\microsofttpm`, "path", "Win32_Tpm", "get", "SpecVersion")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return false, ""
+	}
+
+	for _, line := range strings.Split(out.String(), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.Contains(strings.ToLower(trimmed), "specversion") {
+			continue
+		}
+		version := strings.TrimSpace(strings.Split(trimmed, ",")[0])
+		return true, version
+	}
+	return false, ""
+}
+
+// getWindowsScreenLock reads the screen saver policy of the console user
+// from their hive under HKEY_USERS; HKCU would be the settings of the agent
+// account (SYSTEM). Group Policy values take precedence over the user's own
+// settings.
+func getWindowsScreenLock() (*bool, int) {
+	sid := getWindowsConsoleUserSID()
+	if sid == "" {
+		return nil, 0
+	}
+	keys := []string{
+		`HKU\` + sid + `\Software\Policies\Microsoft\Windows\Control Panel\Desktop`,
+		`HKU\` + sid + `\Control Panel\Desktop`,
+	}
+
+	var enabled *bool
+	idle := 0
+	for _, key := range keys {
+		if enabled == nil {
+			if value, err := getRegistryValue(key, "ScreenSaverIsSecure"); err == nil {
+				v := value == "1"
+				enabled = &v
+			}
+		}
+		if idle == 0 {
+			if value, err := getRegistryValue(key, "ScreenSaveTimeOut"); err == nil {
+				idle, _ = strconv.Atoi(value)
+			}
+		}
+	}
+	return enabled, idle
+}
+
+// getWindowsConsoleUserSID returns the SID of the user who last logged on
+// at the console if their hive is loaded, or else of the only loaded user
+// hive.
+func getWindowsConsoleUserSID() string {
+	sids := loadedUserSIDs()
+	if last, err := getRegistryValue(`HKLM\SOFTWARE\Microsoft\Windows\CurrentVersion\Authentication\LogonUI`, "LastLoggedOnUserSID"); err == nil {
+		for _, sid := range sids {
+			if strings.EqualFold(sid, last) {
+				return sid
+			}
+		}
+	}
+	if len(sids) == 1 {
+		return sids[0]
+	}
+	return ""
+}
+
+// getRegistryValue returns the data of a single registry value via `reg query`.
+func getRegistryValue(key, name string) (string, error) {
+	cmd := exec.Command("reg", "query", key, "/v", name)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	// Output:
+	//     UEFISecureBootEnabled    REG_DWORD    0x1
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && strings.EqualFold(fields[0], name) {
+			return strings.Join(fields[2:], " "), nil
+		}
+	}
+	return "", fmt.Errorf("registry value %s not found", name)
+}
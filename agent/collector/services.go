@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Service is a system service (systemd unit, launchd job, Windows service).
+type Service struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	State       string `json:"state"`               // e.g. "active", "inactive", "failed", "running", "stopped"
+	SubState    string `json:"sub_state,omitempty"` // systemd only, e.g. "running", "exited", "dead"
+	Enabled     string `json:"enabled,omitempty"`   // "enabled", "disabled", "static", "masked", "auto", "manual"
+	PID         int    `json:"pid,omitempty"`
+}
+
+// ListeningPort is a socket accepting connections (TCP) or datagrams (UDP).
+type ListeningPort struct {
+	Protocol string `json:"protocol"` // "tcp", "tcp6", "udp", "udp6"
+	Address  string `json:"address"`  // Bound address, "0.0.0.0" or "::" for all interfaces
+	Port     int    `json:"port"`
+	PID      int    `json:"pid,omitempty"`
+	Process  string `json:"process,omitempty"`
+	Service  string `json:"service,omitempty"` // Owning service, when the process belongs to one
+}
+
+// splitListenAddress splits the "addr:port" forms printed by lsof and
+// netstat: "127.0.0.1:631", "[::1]:631", "*:22". A wildcard address is
+// normalized to the unspecified address of the given family.
+func splitListenAddress(s string, ipv6 bool) (string, int, bool) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	addr := strings.Trim(s[:i], "[]")
+	if addr == "*" {
+		addr = "0.0.0.0"
+		if ipv6 {
+			addr = "::"
+		}
+	}
+	return addr, port, true
+}
+
+// sortListeningPorts orders listeners by protocol, port and address so
+// that check-ins diff cleanly.
+func sortListeningPorts(ports []ListeningPort) {
+	sort.Slice(ports, func(i, j int) bool {
+		a, b := ports[i], ports[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.Address < b.Address
+	})
+}
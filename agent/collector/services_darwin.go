@@ -0,0 +1,118 @@
+//go:build darwin
+
+package collector
+
+import (
+	"strconv"
+	"strings"
+)
+
+// getMacOSServices returns the system launchd jobs and the listening
+// sockets reported by lsof.
+func getMacOSServices() ([]Service, []ListeningPort) {
+	services := parseLaunchctlList(runCommand("launchctl", "list"), parseLaunchctlDisabled(runCommand("launchctl", "print-disabled", "system")))
+
+	byPID := map[int]string{}
+	for _, s := range services {
+		if s.PID > 0 {
+			byPID[s.PID] = s.Name
+		}
+	}
+	ports := parseLsofListeners(runCommand("lsof", "-nP", "-F", "pcPtn", "-iTCP", "-sTCP:LISTEN", "-iUDP"))
+	for i := range ports {
+		ports[i].Service = byPID[ports[i].PID]
+	}
+	return services, ports
+}
+
+// parseLaunchctlList parses `launchctl list`:
+//
+//	PID	Status	Label
+//	312	0	com.apple.sshd
+//	-	0	com.apple.backupd
+func parseLaunchctlList(output string, disabled map[string]bool) []Service {
+	services := []Service{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] == "PID" {
+			continue
+		}
+		s := Service{Name: fields[2], State: "stopped", Enabled: "enabled"}
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			s.State, s.PID = "running", pid
+		} else if fields[1] != "0" {
+			// Last exit status of a job that is not running
+			s.State = "failed"
+		}
+		if disabled[s.Name] {
+			s.Enabled = "disabled"
+		}
+		services = append(services, s)
+	}
+	return services
+}
+
+// parseLaunchctlDisabled parses `launchctl print-disabled system`:
+//
+//	"com.apple.ftpd" => disabled
+//	"com.openssh.sshd" => enabled
+func parseLaunchctlDisabled(output string) map[string]bool {
+	disabled := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		label, state, ok := strings.Cut(strings.TrimSpace(line), "=>")
+		if !ok {
+			continue
+		}
+		state = strings.TrimSpace(state)
+		// Older releases print true/false instead of disabled/enabled
+		disabled[strings.Trim(strings.TrimSpace(label), `"`)] = state == "disabled" || state == "true"
+	}
+	return disabled
+}
+
+// parseLsofListeners parses `lsof -F pcPtn` field output: a "p" line starts
+// each process, "c" is its command, and each socket contributes "t"
+// (IPv4/IPv6), "P" (TCP/UDP) and "n" (address) lines.
+func parseLsofListeners(output string) []ListeningPort {
+	ports := []ListeningPort{}
+	seen := map[ListeningPort]bool{}
+
+	var pid int
+	var command, family, proto string
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+		value := line[1:]
+		switch line[0] {
+		case 'p':
+			pid, _ = strconv.Atoi(value)
+			command = ""
+		case 'c':
+			command = value
+		case 't':
+			family = value
+		case 'P':
+			proto = strings.ToLower(value)
+		case 'n':
+			// UDP sockets with a peer ("a->b") are clients, not listeners
+			if strings.Contains(value, "->") {
+				continue
+			}
+			addr, port, ok := splitListenAddress(value, family == "IPv6")
+			if !ok {
+				continue
+			}
+			p := ListeningPort{Protocol: proto, Address: addr, Port: port, PID: pid, Process: command}
+			if family == "IPv6" {
+				p.Protocol += "6"
+			}
+			if !seen[p] {
+				seen[p] = true
+				ports = append(ports, p)
+			}
+		}
+	}
+	sortListeningPorts(ports)
+	return ports
+}
@@ -0,0 +1,345 @@
+//go:build linux
+
+package collector
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unit search path, highest precedence first
+var systemdUnitDirs = []string{
+	"/etc/systemd/system",
+	"/run/systemd/system",
+	"/usr/local/lib/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// getLinuxServices returns the systemd service units and the listening
+// sockets with their owning processes.
+func getLinuxServices() ([]Service, []ListeningPort) {
+	services := getSystemdUnits(systemdUnitDirs)
+	applySystemdState(services, "/sys/fs/cgroup")
+	ports := getLinuxListeningPorts("/proc")
+	applySocketOwners(services, ports)
+	return services, ports
+}
+
+type systemdUnitFile struct {
+	description string
+	installable bool
+}
+
+// getSystemdUnits parses the unit files for descriptions and enablement.
+// A unit is enabled when it is linked into a .wants/.requires directory,
+// by the admin under /etc or by the vendor under /usr/lib, masked when
+// linked to /dev/null, and static when it has no [Install] section.
+// applySystemdState replaces this with systemctl's verdict when it can.
+func getSystemdUnits(dirs []string) []Service {
+	files := map[string]systemdUnitFile{}
+	masked := map[string]bool{}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if _, seen := files[name]; seen || masked[name] || !strings.HasSuffix(name, ".service") {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if target, err := os.Readlink(path); err == nil {
+				if target == "/dev/null" {
+					masked[name] = true
+					continue
+				}
+				// Aliases (dbus-org.*.service) point at another unit
+				if filepath.Base(target) != name {
+					continue
+				}
+			}
+			if f, err := parseUnitFile(path); err == nil {
+				files[name] = f
+			}
+		}
+	}
+
+	enabled := map[string]bool{}
+	for _, dir := range dirs {
+		for _, pattern := range []string{"*.wants/*.service", "*.requires/*.service"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, m := range matches {
+				enabled[filepath.Base(m)] = true
+			}
+		}
+	}
+
+	services := []Service{}
+	add := func(name string, f systemdUnitFile) {
+		s := Service{Name: name, Description: f.description, Enabled: "disabled"}
+		if enabled[name] {
+			s.Enabled = "enabled"
+		} else if !f.installable {
+			s.Enabled = "static"
+		}
+		services = append(services, s)
+	}
+	for name, f := range files {
+		// Templates (getty@.service) are reported through their instances
+		if !strings.HasSuffix(name, "@.service") {
+			add(name, f)
+		}
+	}
+	for name := range enabled {
+		at := strings.Index(name, "@")
+		if _, seen := files[name]; seen || at < 0 {
+			continue
+		}
+		if tmpl, ok := files[name[:at]+"@.service"]; ok {
+			instance := strings.TrimSuffix(name[at+1:], ".service")
+			tmpl.description = strings.NewReplacer("%i", instance, "%I", instance).Replace(tmpl.description)
+			add(name, tmpl)
+		}
+	}
+	for name := range masked {
+		services = append(services, Service{Name: name, State: "inactive", Enabled: "masked"})
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services
+}
+
+// parseUnitFile reads Description= from [Unit] and notes whether [Install]
+// declares anything enabling would act on.
+func parseUnitFile(path string) (systemdUnitFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return systemdUnitFile{}, err
+	}
+	defer f.Close()
+
+	var unit systemdUnitFile
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case section == "[Unit]" && key == "Description":
+			unit.description = value
+		case section == "[Install]" && value != "":
+			switch key {
+			case "WantedBy", "RequiredBy", "UpheldBy", "Alias", "Also":
+				unit.installable = true
+			}
+		}
+	}
+	return unit, scanner.Err()
+}
+
+// applySystemdState fills in the runtime state and enablement from
+// systemctl. Without a running systemd (containers, chroots) a unit counts
+// as active when its cgroup still holds processes.
+func applySystemdState(services []Service, cgroupRoot string) {
+	index := map[string]int{}
+	for i, s := range services {
+		index[s.Name] = i
+	}
+
+	// ssh.service enabled enabled
+	if out, err := exec.Command("systemctl", "list-unit-files", "--type=service", "--no-legend", "--no-pager").Output(); err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			if i, ok := index[fields[0]]; ok {
+				services[i].Enabled = fields[1]
+			}
+		}
+	}
+
+	out, err := exec.Command("systemctl", "list-units", "--type=service", "--all", "--no-legend", "--plain", "--no-pager").Output()
+	if err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			// ssh.service loaded active running OpenBSD Secure Shell server
+			fields := strings.Fields(line)
+			if len(fields) < 4 || fields[1] == "not-found" {
+				continue
+			}
+			if i, ok := index[fields[0]]; ok {
+				services[i].State = fields[2]
+				services[i].SubState = fields[3]
+			}
+		}
+	}
+
+	for i := range services {
+		if services[i].State != "" {
+			continue
+		}
+		services[i].State = "inactive"
+		for _, dir := range []string{"", "unified", "systemd"} {
+			procs := getFileContent(filepath.Join(cgroupRoot, dir, "system.slice", services[i].Name, "cgroup.procs"))
+			if procs != "" {
+				services[i].State = "active"
+				services[i].SubState = "running"
+				services[i].PID, _ = strconv.Atoi(strings.Fields(procs)[0])
+				break
+			}
+		}
+	}
+}
+
+// Socket states in /proc/net/tcp: 0A is LISTEN. Unconnected UDP sockets
+// show as 07 (CLOSE).
+const (
+	tcpListen = "0A"
+	udpClose  = "07"
+)
+
+var serviceCgroupRe = regexp.MustCompile(`(?m)/([^/]+\.service)(?:/|$)`)
+
+// getLinuxListeningPorts parses /proc/net/{tcp,tcp6,udp,udp6} and maps
+// each socket inode to the process holding it through /proc/<pid>/fd.
+func getLinuxListeningPorts(procRoot string) []ListeningPort {
+	type socket struct {
+		ListeningPort
+		inode string
+	}
+	var sockets []socket
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		content, err := os.ReadFile(filepath.Join(procRoot, "net", proto))
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(content), "\n")
+		for _, line := range lines[1:] {
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			fields := strings.Fields(line)
+			if len(fields) < 10 {
+				continue
+			}
+			state := fields[3]
+			if (strings.HasPrefix(proto, "tcp") && state != tcpListen) ||
+				(strings.HasPrefix(proto, "udp") && (state != udpClose || !strings.HasSuffix(fields[2], ":0000"))) {
+				continue
+			}
+			addr, port, ok := parseProcNetAddress(fields[1])
+			if !ok {
+				continue
+			}
+			sockets = append(sockets, socket{
+				ListeningPort: ListeningPort{Protocol: proto, Address: addr, Port: port},
+				inode:         fields[9],
+			})
+		}
+	}
+
+	owners := mapSocketOwners(procRoot)
+	ports := []ListeningPort{}
+	for _, s := range sockets {
+		if pid, ok := owners[s.inode]; ok {
+			s.PID = pid
+			s.Process = getFileContent(filepath.Join(procRoot, strconv.Itoa(pid), "comm"))
+			cgroup, _ := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "cgroup"))
+			if m := serviceCgroupRe.FindSubmatch(cgroup); m != nil {
+				s.Service = string(m[1])
+			}
+		}
+		ports = append(ports, s.ListeningPort)
+	}
+	sortListeningPorts(ports)
+	return ports
+}
+
+// applySocketOwners gives running services without a known PID the one
+// of the process holding their listening sockets.
+func applySocketOwners(services []Service, ports []ListeningPort) {
+	pids := map[string]int{}
+	for _, p := range ports {
+		if p.Service != "" && p.PID != 0 && (pids[p.Service] == 0 || p.PID < pids[p.Service]) {
+			pids[p.Service] = p.PID
+		}
+	}
+	for i := range services {
+		if services[i].PID == 0 && services[i].State == "active" {
+			services[i].PID = pids[services[i].Name]
+		}
+	}
+}
+
+// mapSocketOwners maps socket inodes to the lowest PID holding them, so a
+// pre-forked server's socket is attributed to its parent.
+func mapSocketOwners(procRoot string) map[string]int {
+	owners := map[string]int{}
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return owners
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join(procRoot, entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+			if existing, ok := owners[inode]; !ok || pid < existing {
+				owners[inode] = pid
+			}
+		}
+	}
+	return owners
+}
+
+// parseProcNetAddress decodes "0100007F:0277" (IPv4) or the 32-digit IPv6
+// form. The address is stored as 32-bit words in the host's byte order;
+// the port is big-endian hex.
+func parseProcNetAddress(s string) (string, int, bool) {
+	hexAddr, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", 0, false
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", 0, false
+	}
+	raw, err := hex.DecodeString(hexAddr)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return "", 0, false
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.NativeEndian.Uint32(raw[i:]))
+	}
+	return ip.String(), int(port), true
+}
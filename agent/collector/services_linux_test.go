@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles creates each file under root, making parent directories.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// symlinks creates each link under root pointing at its target.
+func symlinks(t *testing.T, root string, links map[string]string) {
+	t.Helper()
+	for name, target := range links {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetSystemdUnits(t *testing.T) {
+	root := t.TempDir()
+	etc, lib := filepath.Join(root, "etc"), filepath.Join(root, "lib")
+	writeFiles(t, lib, map[string]string{
+		"ssh.service":              "[Unit]\nDescription=OpenBSD Secure Shell server\n\n[Service]\nExecStart=/usr/sbin/sshd -D\n\n[Install]\nWantedBy=multi-user.target\nAlias=sshd.service\n",
+		"cron.service":             "[Unit]\nDescription=Regular background program processing daemon\n\n[Install]\nWantedBy=multi-user.target\n",
+		"cups.service":             "[Unit]\nDescription=CUPS Scheduler\n\n[Install]\nWantedBy=printer.target\n",
+		"avahi-daemon.service":     "[Unit]\nDescription=Avahi mDNS/DNS-SD Stack\n\n[Install]\nWantedBy=multi-user.target\nAlias=dbus-org.freedesktop.Avahi.service\n",
+		"dbus.service":             "[Unit]\nDescription=D-Bus System Message Bus\n",
+		"systemd-journald.service": "[Unit]\nDescription=Journal Service\n\n[Install]\nWantedBy=\n",
+		"getty@.service":           "[Unit]\nDescription=Getty on %I\n\n[Install]\nWantedBy=getty.target\n",
+		"serial-getty@.service":    "[Unit]\nDescription=Serial Getty on %I\n\n[Install]\nWantedBy=getty.target\n",
+		"multi-user.target":        "[Unit]\nDescription=Multi-User System\n",
+	})
+	// The admin's copy under /etc overrides the vendor's
+	writeFiles(t, etc, map[string]string{
+		"cron.service": "[Unit]\nDescription=Local cron\n\n[Install]\nWantedBy=multi-user.target\n",
+	})
+	symlinks(t, etc, map[string]string{
+		"multi-user.target.wants/ssh.service":   filepath.Join(lib, "ssh.service"),
+		"getty.target.wants/getty@tty1.service": filepath.Join(lib, "getty@.service"),
+		"cups.service":                          "/dev/null",
+		"dbus-org.freedesktop.Avahi.service":    filepath.Join(lib, "avahi-daemon.service"),
+		"sshd.service":                          filepath.Join(lib, "ssh.service"),
+	})
+	symlinks(t, lib, map[string]string{
+		"sysinit.target.wants/systemd-journald.service": "../systemd-journald.service",
+	})
+
+	want := []Service{
+		{Name: "avahi-daemon.service", Description: "Avahi mDNS/DNS-SD Stack", Enabled: "disabled"},
+		{Name: "cron.service", Description: "Local cron", Enabled: "disabled"},
+		{Name: "cups.service", State: "inactive", Enabled: "masked"},
+		{Name: "dbus.service", Description: "D-Bus System Message Bus", Enabled: "static"},
+		{Name: "getty@tty1.service", Description: "Getty on tty1", Enabled: "enabled"},
+		{Name: "ssh.service", Description: "OpenBSD Secure Shell server", Enabled: "enabled"},
+		{Name: "systemd-journald.service", Description: "Journal Service", Enabled: "enabled"},
+	}
+	if got := getSystemdUnits([]string{etc, lib}); !reflect.DeepEqual(got, want) {
+		t.Errorf("getSystemdUnits:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+// The /proc/net fixtures below hold addresses as a little-endian host
+// writes them.
+func skipBigEndian(t *testing.T) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("fixtures are little-endian")
+	}
+}
+
+func TestParseProcNetAddress(t *testing.T) {
+	skipBigEndian(t)
+	tests := []struct {
+		in   string
+		addr string
+		port int
+		ok   bool
+	}{
+		{"0100007F:0277", "127.0.0.1", 631, true},
+		{"00000000:0016", "0.0.0.0", 22, true},
+		{"0101A8C0:0035", "192.168.1.1", 53, true},
+		{"00000000000000000000000000000000:0050", "::", 80, true},
+		{"00000000000000000000000001000000:1F90", "::1", 8080, true},
+		{"B80D0120000000000000000001000000:01BB", "2001:db8::1", 443, true},
+		{"0000000000000000FFFF00000100007F:0277", "127.0.0.1", 631, true},
+		{"0100007F", "", 0, false},
+		{"0100007F:XYZ", "", 0, false},
+		{"0100007F:10000", "", 0, false},
+		{"01007F:0277", "", 0, false},
+		{"ZZ00007F:0277", "", 0, false},
+	}
+	for _, tt := range tests {
+		addr, port, ok := parseProcNetAddress(tt.in)
+		if addr != tt.addr || port != tt.port || ok != tt.ok {
+			t.Errorf("parseProcNetAddress(%q) = %q, %d, %v; want %q, %d, %v", tt.in, addr, port, ok, tt.addr, tt.port, tt.ok)
+		}
+	}
+}
+
+func TestGetLinuxListeningPorts(t *testing.T) {
+	skipBigEndian(t)
+	proc := t.TempDir()
+	const header = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	writeFiles(t, proc, map[string]string{
+		"net/tcp": header +
+			"   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0\n" +
+			"   1: 0100007F:0277 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0\n" +
+			"   2: 0101A8C0:0016 0201A8C0:D431 01 00000000:00000000 02:00090A2B 00000000     0        0 1009 4 0000000000000000 20 4 29 10 -1\n",
+		"net/tcp6": header +
+			"   0: 00000000000000000000000000000000:0050 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000    33        0 1003 1 0000000000000000 100 0 0 10 0\n",
+		"net/udp": header +
+			"  10: 00000000:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 1005 2 0000000000000000 0\n" +
+			"  11: 0101A8C0:A1B2 08080808:0035 01 00000000:00000000 00:00000000 00000000  1000        0 1006 2 0000000000000000 0\n" +
+			"  12: short line\n",
+
+		"100/comm":   "sshd\n",
+		"100/cgroup": "0::/system.slice/ssh.service\n",
+		"101/comm":   "sshd\n",
+		"101/cgroup": "0::/system.slice/ssh.service\n",
+		"300/comm":   "nginx\n",
+		"300/cgroup": "0::/system.slice/nginx.service/worker\n",
+		"400/comm":   "dnsmasq\n",
+		"400/cgroup": "0::/user.slice/user-1000.slice/session-2.scope\n",
+	})
+	symlinks(t, proc, map[string]string{
+		"101/fd/3": "socket:[1001]", // A forked child shares the socket
+		"100/fd/3": "socket:[1001]",
+		"100/fd/4": "/dev/null",
+		"300/fd/6": "socket:[1003]",
+		"400/fd/5": "socket:[1005]",
+	})
+
+	want := []ListeningPort{
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 22, PID: 100, Process: "sshd", Service: "ssh.service"},
+		{Protocol: "tcp", Address: "127.0.0.1", Port: 631},
+		{Protocol: "tcp6", Address: "::", Port: 80, PID: 300, Process: "nginx", Service: "nginx.service"},
+		{Protocol: "udp", Address: "0.0.0.0", Port: 53, PID: 400, Process: "dnsmasq"},
+	}
+	ports := getLinuxListeningPorts(proc)
+	if !reflect.DeepEqual(ports, want) {
+		t.Errorf("getLinuxListeningPorts:\n got %+v\nwant %+v", ports, want)
+	}
+
+	services := []Service{
+		{Name: "ssh.service", State: "active"},
+		{Name: "nginx.service", State: "inactive"},
+	}
+	applySocketOwners(services, ports)
+	if services[0].PID != 100 || services[1].PID != 0 {
+		t.Errorf("applySocketOwners: %+v", services)
+	}
+}
@@ -0,0 +1,132 @@
+//go:build windows
+
+package collector
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// getWindowsServices returns the Win32 services and the listening sockets
+// from netstat, attributed to processes and (for svchost and other
+// service hosts) to the services running in them.
+func getWindowsServices() ([]Service, []ListeningPort) {
+	services := getWindowsServiceList()
+
+	byPID := map[int][]string{}
+	for _, s := range services {
+		if s.PID > 0 {
+			byPID[s.PID] = append(byPID[s.PID], s.Name)
+		}
+	}
+
+	ports := []ListeningPort{}
+	cmd := exec.Command("netstat", "-ano")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err == nil {
+		ports = parseNetstatListeners(out.String())
+	}
+	processes := getWindowsProcessNames()
+	for i := range ports {
+		ports[i].Process = processes[ports[i].PID]
+		ports[i].Service = strings.Join(byPID[ports[i].PID], ",")
+	}
+	return services, ports
+}
+
+// getWindowsServiceList uses wmic to list services with their state,
+// start mode and hosting process.
+func getWindowsServiceList() []Service {
+	services := []Service{}
+	cmd := exec.Command("wmic", "service", "get", "DisplayName,Name,ProcessId,StartMode,State", "/format:csv")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return services
+	}
+
+	// Node,DisplayName,Name,ProcessId,StartMode,State
+	reader := csv.NewReader(strings.NewReader(strings.ReplaceAll(out.String(), "\r", "")))
+	reader.FieldsPerRecord = -1
+	records, _ := reader.ReadAll()
+	for _, r := range records {
+		if len(r) != 6 || r[0] == "Node" {
+			continue
+		}
+		s := Service{
+			Name:        r[2],
+			Description: r[1],
+			State:       strings.ToLower(r[5]), // "running", "stopped", ...
+			Enabled:     strings.ToLower(r[4]), // "auto", "manual", "disabled"
+		}
+		s.PID, _ = strconv.Atoi(r[3])
+		services = append(services, s)
+	}
+	return services
+}
+
+// parseNetstatListeners parses `netstat -ano`:
+//
+//	TCP    0.0.0.0:135            0.0.0.0:0              LISTENING       1044
+//	TCP    [::]:445               [::]:0                 LISTENING       4
+//	UDP    0.0.0.0:123            *:*                                    1520
+func parseNetstatListeners(output string) []ListeningPort {
+	ports := []ListeningPort{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		var proto string
+		switch {
+		case fields[0] == "TCP" && len(fields) == 5 && fields[3] == "LISTENING":
+			proto = "tcp"
+		case fields[0] == "UDP" && len(fields) == 4 && fields[2] == "*:*":
+			proto = "udp"
+		default:
+			continue
+		}
+		ipv6 := strings.HasPrefix(fields[1], "[")
+		addr, port, ok := splitListenAddress(fields[1], ipv6)
+		if !ok {
+			continue
+		}
+		// Strip the zone index from link-local addresses ("fe80::1%7")
+		if i := strings.Index(addr, "%"); i >= 0 {
+			addr = addr[:i]
+		}
+		if ipv6 {
+			proto += "6"
+		}
+		pid, _ := strconv.Atoi(fields[len(fields)-1])
+		ports = append(ports, ListeningPort{Protocol: proto, Address: addr, Port: port, PID: pid})
+	}
+	sortListeningPorts(ports)
+	return ports
+}
+
+// getWindowsProcessNames maps PIDs to image names using tasklist.
+func getWindowsProcessNames() map[int]string {
+	names := map[int]string{}
+	cmd := exec.Command("tasklist", "/fo", "csv", "/nh")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return names
+	}
+
+	// "svchost.exe","1044","Services","0","12,345 K"
+	records, _ := csv.NewReader(strings.NewReader(strings.ReplaceAll(out.String(), "\r", ""))).ReadAll()
+	for _, r := range records {
+		if len(r) >= 2 {
+			if pid, err := strconv.Atoi(r[1]); err == nil {
+				names[pid] = r[0]
+			}
+		}
+	}
+	return names
+}
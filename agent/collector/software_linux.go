@@ -0,0 +1,415 @@
+//go:build linux
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// linuxPackageSources lists every package manager we inventory. A host can
+// carry several at once (e.g. dpkg + snap + flatpak, or rpm on a Debian
+// build box), so all of them are queried and the results concatenated.
+var linuxPackageSources = []struct {
+	name string
+	list func() ([]Software, error)
+}{
+	{SourceDpkg, getDpkgPackages},
+	{SourceRPM, getRpmPackages},
+	{SourcePacman, func() ([]Software, error) { return getPacmanPackages("/var/lib/pacman/local") }},
+	{SourceApk, func() ([]Software, error) { return getApkPackages("/lib/apk/db/installed") }},
+	{SourceSnap, func() ([]Software, error) { return getSnapPackages("/snap") }},
+	{SourceFlatpak, getFlatpakPackages},
+	{SourceNix, getNixPackages},
+}
+
+// getLinuxInstalledSoftware lists installed packages from every package
+// manager present on the host, tagging each entry with its source.
+func getLinuxInstalledSoftware() []Software {
+	softwareList := []Software{}
+	for _, source := range linuxPackageSources {
+		packages, err := source.list()
+		if err != nil {
+			if !os.IsNotExist(err) && !isCommandNotFound(err) {
+				fmt.Printf("Warning: failed to read %s packages: %v\n", source.name, err)
+			}
+			continue
+		}
+		softwareList = append(softwareList, packages...)
+	}
+
+	if len(softwareList) == 0 {
+		fmt.Printf("Warning: no Linux package manager found for software inventory\n")
+	}
+	return softwareList
+}
+
+func isCommandNotFound(err error) bool {
+	var execErr *exec.Error
+	return errors.As(err, &execErr)
+}
+
+func getDpkgPackages() ([]Software, error) {
+	return getDpkgStatusPackages("/var/lib/dpkg")
+}
+
+// getRpmPackages reads the rpm database directly and only falls back to the
+// rpm CLI when the database format is not one we understand.
+func getRpmPackages() ([]Software, error) {
+	softwareList, err := readRpmDatabase()
+	if err == nil || os.IsNotExist(err) {
+		return softwareList, err
+	}
+	fmt.Printf("Warning: failed to read rpm database, falling back to rpm CLI: %v\n", err)
+
+	cmd := exec.Command("rpm", "-qa", "--queryformat", "%{NAME}|%{EPOCHNUM}:%{VERSION}-%{RELEASE}|%{VENDOR}|%{INSTALLTIME}|%{ARCH}|%{SIZE}|%{SOURCERPM}|%{SUMMARY}\n")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	softwareList = []Software{}
+	scanner := bufio.NewScanner(bytes.NewReader(out.Bytes()))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "|", 8)
+		if len(parts) < 8 || parts[0] == "gpg-pubkey" {
+			continue
+		}
+		sw := Software{
+			Name:         parts[0],
+			Version:      strings.TrimPrefix(parts[1], "0:"),
+			Vendor:       strings.TrimSuffix(parts[2], "(none)"),
+			InstallDate:  unixToDate(parts[3]),
+			Architecture: strings.TrimSuffix(parts[4], "(none)"),
+			Description:  parts[7],
+			Source:       SourceRPM,
+		}
+		sw.SizeBytes, _ = strconv.ParseInt(parts[5], 10, 64)
+		if src := sourceRPMName(parts[6]); src != sw.Name {
+			sw.SourcePackage = src
+		}
+		softwareList = append(softwareList, sw)
+	}
+	return softwareList, nil
+}
+
+// getPacmanPackages reads the local pacman database, where each package has
+// a "<name>-<version>/desc" file of %SECTION% headers followed by values:
+//
+//	%NAME%
+//	bash
+//
+//	%VERSION%
+//	5.2.026-2
+func getPacmanPackages(dbDir string) ([]Software, error) {
+	entries, err := os.ReadDir(dbDir)
+	if err != nil {
+		return nil, err
+	}
+
+	softwareList := []Software{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		desc, err := parsePacmanDesc(filepath.Join(dbDir, entry.Name(), "desc"))
+		if err != nil || desc["NAME"] == "" {
+			continue
+		}
+		softwareList = append(softwareList, Software{
+			Name:        desc["NAME"],
+			Version:     desc["VERSION"],
+			Vendor:      desc["PACKAGER"],
+			InstallDate: unixToDate(desc["INSTALLDATE"]),
+			Source:      SourcePacman,
+		})
+	}
+	return softwareList, nil
+}
+
+// parsePacmanDesc returns the first value line of every section.
+func parsePacmanDesc(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fields := map[string]string{}
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			section = ""
+		case strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%") && len(line) > 2:
+			section = strings.Trim(line, "%")
+		case section != "":
+			if _, seen := fields[section]; !seen {
+				fields[section] = line
+			}
+		}
+	}
+	return fields, scanner.Err()
+}
+
+// getApkPackages reads Alpine's installed database: blank-line separated
+// records of single-letter keys, e.g. "P:busybox", "V:1.36.1-r15",
+// "m:Sören Tempel <soeren+alpine@soeren-tempel.net>". apk does not record
+// when a package was installed, only its build time ("t:"), so InstallDate
+// stays empty.
+func getApkPackages(dbPath string) ([]Software, error) {
+	f, err := os.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	softwareList := []Software{}
+	current := Software{Source: SourceApk}
+	flush := func() {
+		if current.Name != "" {
+			softwareList = append(softwareList, current)
+		}
+		current = Software{Source: SourceApk}
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		value := line[2:]
+		switch line[0] {
+		case 'P':
+			current.Name = value
+		case 'V':
+			current.Version = value
+		case 'm':
+			current.Vendor = value
+		}
+	}
+	flush()
+	return softwareList, scanner.Err()
+}
+
+// getSnapPackages reads meta/snap.yaml from each mounted snap's current
+// revision.
+func getSnapPackages(snapDir string) ([]Software, error) {
+	entries, err := os.ReadDir(snapDir)
+	if err != nil {
+		return nil, err
+	}
+
+	softwareList := []Software{}
+	for _, entry := range entries {
+		if entry.Name() == "bin" {
+			continue
+		}
+		current := filepath.Join(snapDir, entry.Name(), "current")
+		meta := readSimpleYAML(filepath.Join(current, "meta", "snap.yaml"))
+		if meta["name"] == "" {
+			continue
+		}
+		sw := Software{Name: meta["name"], Version: meta["version"], Source: SourceSnap}
+		if fi, err := os.Stat(current); err == nil {
+			sw.InstallDate = fi.ModTime().Format("2006-01-02")
+		}
+		softwareList = append(softwareList, sw)
+	}
+	return softwareList, nil
+}
+
+// readSimpleYAML extracts top-level "key: value" scalars from a YAML file.
+// Good enough for snap metadata without pulling in a YAML library.
+func readSimpleYAML(path string) map[string]string {
+	values := map[string]string{}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return values
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		value = strings.Trim(value, "\"'")
+		values[strings.TrimSpace(parts[0])] = value
+	}
+	return values
+}
+
+var metainfoReleaseRe = regexp.MustCompile(`<release[^>]*\sversion="([^"]+)"`)
+
+// getFlatpakPackages walks the system installation and every user's
+// ~/.local/share/flatpak. Versions come from the AppStream metainfo shipped
+// inside each deployed app, whose first <release> is the newest.
+func getFlatpakPackages() ([]Software, error) {
+	installations := []string{"/var/lib/flatpak"}
+	homes, _ := filepath.Glob("/home/*")
+	for _, home := range append(homes, "/root") {
+		installations = append(installations, filepath.Join(home, ".local/share/flatpak"))
+	}
+
+	softwareList := []Software{}
+	found := false
+	for _, installation := range installations {
+		appDir := filepath.Join(installation, "app")
+		apps, err := os.ReadDir(appDir)
+		if err != nil {
+			continue
+		}
+		found = true
+		for _, app := range apps {
+			id := app.Name()
+			active := filepath.Join(appDir, id, "current", "active")
+			if _, err := os.Stat(active); err != nil {
+				continue
+			}
+			sw := Software{Name: id, Source: SourceFlatpak}
+			for _, name := range []string{"metainfo/" + id + ".metainfo.xml", "appdata/" + id + ".appdata.xml"} {
+				content, err := os.ReadFile(filepath.Join(active, "files", "share", name))
+				if err != nil {
+					continue
+				}
+				if m := metainfoReleaseRe.FindSubmatch(content); m != nil {
+					sw.Version = string(m[1])
+					break
+				}
+			}
+			if fi, err := os.Stat(filepath.Join(active, "deploy")); err == nil {
+				sw.InstallDate = fi.ModTime().Format("2006-01-02")
+			}
+			softwareList = append(softwareList, sw)
+		}
+	}
+	if !found {
+		return nil, os.ErrNotExist
+	}
+	return softwareList, nil
+}
+
+// getNixPackages lists the store paths referenced by the NixOS system
+// profile, the default profile and every per-user profile.
+func getNixPackages() ([]Software, error) {
+	if _, err := os.Stat("/nix/store"); err != nil {
+		return nil, err
+	}
+
+	profiles := []string{"/run/current-system/sw", "/nix/var/nix/profiles/default"}
+	userProfiles, _ := filepath.Glob("/nix/var/nix/profiles/per-user/*/profile")
+	profiles = append(profiles, userProfiles...)
+	homeProfiles, _ := filepath.Glob("/home/*/.nix-profile")
+	profiles = append(profiles, homeProfiles...)
+
+	seen := map[string]bool{}
+	softwareList := []Software{}
+	for _, profile := range profiles {
+		storePaths, err := nixProfileStorePaths(profile)
+		if err != nil {
+			continue
+		}
+		for _, storePath := range storePaths {
+			if seen[storePath] {
+				continue
+			}
+			seen[storePath] = true
+			name, version := parseNixStorePath(storePath)
+			if name == "" {
+				continue
+			}
+			softwareList = append(softwareList, Software{Name: name, Version: version, Source: SourceNix})
+		}
+	}
+	return softwareList, nil
+}
+
+// nixProfileStorePaths reads a `nix profile` manifest.json when present and
+// falls back to asking the store for the profile's references (nix-env and
+// NixOS system profiles).
+func nixProfileStorePaths(profile string) ([]string, error) {
+	if content, err := os.ReadFile(filepath.Join(profile, "manifest.json")); err == nil {
+		var manifest struct {
+			Elements json.RawMessage `json:"elements"`
+		}
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, err
+		}
+		// Version 2 manifests use a list, version 3 a map keyed by name
+		type element struct {
+			StorePaths []string `json:"storePaths"`
+		}
+		var list []element
+		if err := json.Unmarshal(manifest.Elements, &list); err != nil {
+			var byName map[string]element
+			if err := json.Unmarshal(manifest.Elements, &byName); err != nil {
+				return nil, err
+			}
+			for _, e := range byName {
+				list = append(list, e)
+			}
+		}
+		paths := []string{}
+		for _, e := range list {
+			paths = append(paths, e.StorePaths...)
+		}
+		return paths, nil
+	}
+
+	if _, err := os.Stat(profile); err != nil {
+		return nil, err
+	}
+	out, err := exec.Command("nix-store", "--query", "--references", profile).Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+var nixVersionStartRe = regexp.MustCompile(`-[0-9]`)
+
+// parseNixStorePath splits "/nix/store/<hash>-openssl-3.0.13" into
+// ("openssl", "3.0.13"). The version starts at the first dash followed by a
+// digit, matching Nix's own parseDrvName.
+func parseNixStorePath(storePath string) (string, string) {
+	base := filepath.Base(storePath)
+	dash := strings.Index(base, "-")
+	if dash < 0 {
+		return "", ""
+	}
+	nameVersion := base[dash+1:]
+	if loc := nixVersionStartRe.FindStringIndex(nameVersion); loc != nil {
+		return nameVersion[:loc[0]], nameVersion[loc[0]+1:]
+	}
+	return nameVersion, ""
+}
+
+// unixToDate converts a Unix timestamp string to YYYY-MM-DD.
+func unixToDate(value string) string {
+	ts, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || ts <= 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).Format("2006-01-02")
+}
@@ -0,0 +1,375 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// sqliteDB is a minimal read-only SQLite reader. It walks table b-trees so
+// the agent can read package and certificate databases (rpmdb.sqlite, NSS
+// cert9.db) without cgo or a sqlite3 binary. Only committed data in the main
+// database file is visible; pages still sitting in a -wal file are not.
+//
+// The files come from other software and can be corrupt or truncated, so
+// every offset and page number read from them is checked before use.
+type sqliteDB struct {
+	f        *os.File
+	pageSize int
+	usable   int
+	pages    int
+}
+
+func openSQLite(path string) (*sqliteDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 100)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read sqlite header: %w", err)
+	}
+	if !bytes.HasPrefix(header, []byte("SQLite format 3\x00")) {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a sqlite database", path)
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	// A power of two from 512 to 65536, with at least 480 usable bytes
+	usable := pageSize - int(header[20])
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 || usable < 480 {
+		f.Close()
+		return nil, fmt.Errorf("%s: invalid sqlite page size %d", path, pageSize)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &sqliteDB{
+		f:        f,
+		pageSize: pageSize,
+		usable:   usable,
+		pages:    int(fi.Size() / int64(pageSize)),
+	}, nil
+}
+
+func (db *sqliteDB) Close() error {
+	return db.f.Close()
+}
+
+// tableRows calls fn for every row of the named table. Values are nil,
+// int64, float64, string or []byte following SQLite's storage classes.
+// An INTEGER PRIMARY KEY column reads as nil; its value is the rowid.
+func (db *sqliteDB) tableRows(table string, fn func(rowid int64, values []any) error) error {
+	root := 0
+	err := db.walkTable(1, func(_ int64, values []any) error {
+		// sqlite_schema(type, name, tbl_name, rootpage, sql)
+		if len(values) >= 4 && values[0] == "table" {
+			if name, ok := values[1].(string); ok && strings.EqualFold(name, table) {
+				if page, ok := values[3].(int64); ok {
+					root = int(page)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if root == 0 {
+		return fmt.Errorf("table %s not found", table)
+	}
+	return db.walkTable(root, fn)
+}
+
+// tableColumns returns the column names of a table, parsed from its
+// CREATE TABLE statement, so rows from tableRows can be read by name.
+func (db *sqliteDB) tableColumns(table string) ([]string, error) {
+	var sql string
+	err := db.walkTable(1, func(_ int64, values []any) error {
+		if len(values) >= 5 && values[0] == "table" {
+			if name, ok := values[1].(string); ok && strings.EqualFold(name, table) {
+				sql, _ = values[4].(string)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	start, end := strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+
+	var columns []string
+	depth, from := 0, start+1
+	for i := start + 1; i <= end; i++ {
+		switch sql[i] {
+		case '(':
+			depth++
+		case ')':
+			if i < end {
+				depth--
+				continue
+			}
+			fallthrough
+		case ',':
+			if depth > 0 {
+				continue
+			}
+			fields := strings.Fields(sql[from:i])
+			from = i + 1
+			if len(fields) == 0 {
+				continue
+			}
+			// Table constraints are not columns
+			switch strings.ToUpper(fields[0]) {
+			case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "CONSTRAINT":
+				continue
+			}
+			columns = append(columns, strings.Trim(fields[0], "\"`[]"))
+		}
+	}
+	return columns, nil
+}
+
+func (db *sqliteDB) readPage(n int) ([]byte, error) {
+	if n < 1 || n > db.pages {
+		return nil, fmt.Errorf("page %d out of range (%d pages)", n, db.pages)
+	}
+	page := make([]byte, db.pageSize)
+	if _, err := db.f.ReadAt(page, int64(n-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("failed to read page %d: %w", n, err)
+	}
+	return page, nil
+}
+
+// walkTable calls fn for every row of the table b-tree rooted at pageNo.
+func (db *sqliteDB) walkTable(pageNo int, fn func(int64, []any) error) error {
+	return db.walkTree(pageNo, map[int]bool{}, fn)
+}
+
+func (db *sqliteDB) walkTree(pageNo int, visited map[int]bool, fn func(int64, []any) error) error {
+	// A corrupt child pointer can point back up the tree
+	if visited[pageNo] {
+		return fmt.Errorf("b-tree page %d is referenced twice", pageNo)
+	}
+	visited[pageNo] = true
+	page, err := db.readPage(pageNo)
+	if err != nil {
+		return err
+	}
+	page = page[:db.usable]
+
+	// Page 1 starts with the 100-byte file header
+	hdr := 0
+	if pageNo == 1 {
+		hdr = 100
+	}
+	pageType := page[hdr]
+	hdrLen := 8
+	if pageType == 0x05 {
+		hdrLen = 12
+	}
+	ptrs := hdr + hdrLen
+	cells := int(binary.BigEndian.Uint16(page[hdr+3 : hdr+5]))
+	if ptrs+2*cells > len(page) {
+		return fmt.Errorf("page %d: cell count %d overruns the page", pageNo, cells)
+	}
+	// Cell content follows the pointer array
+	cell := func(i int) (int, error) {
+		off := int(binary.BigEndian.Uint16(page[ptrs+2*i:]))
+		if off < ptrs+2*cells || off >= len(page) {
+			return 0, fmt.Errorf("page %d cell %d: offset %d out of range", pageNo, i, off)
+		}
+		return off, nil
+	}
+
+	switch pageType {
+	case 0x05: // interior table page
+		for i := 0; i < cells; i++ {
+			off, err := cell(i)
+			if err != nil {
+				return err
+			}
+			if off+4 > len(page) {
+				return fmt.Errorf("page %d cell %d: truncated child pointer", pageNo, i)
+			}
+			child := int(binary.BigEndian.Uint32(page[off:]))
+			if err := db.walkTree(child, visited, fn); err != nil {
+				return err
+			}
+		}
+		right := int(binary.BigEndian.Uint32(page[hdr+8:]))
+		return db.walkTree(right, visited, fn)
+
+	case 0x0d: // leaf table page
+		for i := 0; i < cells; i++ {
+			off, err := cell(i)
+			if err != nil {
+				return err
+			}
+			payloadLen, n := sqliteVarint(page[off:])
+			off += n
+			rowid, n := sqliteVarint(page[off:])
+			off += n
+			if off >= len(page) {
+				return fmt.Errorf("page %d cell %d: truncated cell header", pageNo, i)
+			}
+
+			payload, err := db.readPayload(page, off, payloadLen)
+			if err != nil {
+				return fmt.Errorf("page %d cell %d: %w", pageNo, i, err)
+			}
+			values, err := decodeSQLiteRecord(payload)
+			if err != nil {
+				return fmt.Errorf("page %d cell %d: %w", pageNo, i, err)
+			}
+			if err := fn(int64(rowid), values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unexpected b-tree page type 0x%02x on page %d", pageType, pageNo)
+}
+
+// readPayload assembles a cell payload, following the overflow chain when it
+// does not fit on the leaf page. page is cut to the usable size.
+func (db *sqliteDB) readPayload(page []byte, off int, size uint64) ([]byte, error) {
+	// No payload is larger than the file holding it
+	if size > uint64(db.pages)*uint64(db.usable) {
+		return nil, fmt.Errorf("payload size %d exceeds the file", size)
+	}
+	total := int(size)
+	maxLocal := db.usable - 35
+	if total <= maxLocal {
+		if off+total > len(page) {
+			return nil, fmt.Errorf("payload overruns the page")
+		}
+		return page[off : off+total], nil
+	}
+
+	minLocal := (db.usable-12)*32/255 - 23
+	local := minLocal + (total-minLocal)%(db.usable-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if off+local+4 > len(page) {
+		return nil, fmt.Errorf("payload overruns the page")
+	}
+
+	payload := make([]byte, 0, total)
+	payload = append(payload, page[off:off+local]...)
+	next := int(binary.BigEndian.Uint32(page[off+local:]))
+	// Each overflow page adds data, so a cyclic chain ends at total too
+	for next != 0 && len(payload) < total {
+		overflow, err := db.readPage(next)
+		if err != nil {
+			return nil, err
+		}
+		next = int(binary.BigEndian.Uint32(overflow))
+		chunk := overflow[4:db.usable]
+		if remaining := total - len(payload); len(chunk) > remaining {
+			chunk = chunk[:remaining]
+		}
+		payload = append(payload, chunk...)
+	}
+	if len(payload) != total {
+		return nil, fmt.Errorf("truncated overflow chain")
+	}
+	return payload, nil
+}
+
+func decodeSQLiteRecord(rec []byte) ([]any, error) {
+	headerLen, n := sqliteVarint(rec)
+	if headerLen > uint64(len(rec)) {
+		return nil, fmt.Errorf("record header overruns payload")
+	}
+
+	var types []uint64
+	for pos := n; pos < int(headerLen); {
+		t, n := sqliteVarint(rec[pos:])
+		types = append(types, t)
+		pos += n
+	}
+
+	values := make([]any, 0, len(types))
+	body := rec[headerLen:]
+	for _, t := range types {
+		size := sqliteSerialSize(t)
+		if size < 0 || size > len(body) {
+			return nil, fmt.Errorf("record value overruns payload")
+		}
+		raw := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t >= 1 && t <= 6:
+			// Big-endian two's complement of 1, 2, 3, 4, 6 or 8 bytes
+			v := int64(0)
+			if raw[0]&0x80 != 0 {
+				v = -1
+			}
+			for _, b := range raw {
+				v = v<<8 | int64(b)
+			}
+			values = append(values, v)
+		case t == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(raw)))
+		case t == 8:
+			values = append(values, int64(0))
+		case t == 9:
+			values = append(values, int64(1))
+		case t >= 12 && t%2 == 0:
+			values = append(values, raw)
+		case t >= 13:
+			values = append(values, string(raw))
+		default:
+			return nil, fmt.Errorf("reserved serial type %d", t)
+		}
+	}
+	return values, nil
+}
+
+func sqliteSerialSize(t uint64) int {
+	switch {
+	case t <= 4:
+		return []int{0, 1, 2, 3, 4}[t]
+	case t == 5:
+		return 6
+	case t == 6, t == 7:
+		return 8
+	case t >= 12:
+		// Negative for sizes too large for an int; callers reject those
+		return int((t - 12) / 2)
+	}
+	return 0
+}
+
+// sqliteVarint decodes SQLite's big-endian 1-9 byte varint.
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 8 && i < len(b); i++ {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	if len(b) < 9 {
+		return v, len(b)
+	}
+	return v<<8 | uint64(b[8]), 9
+}
@@ -0,0 +1,86 @@
+//go:build darwin
+
+package collector
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// getUpdateStatus parses `softwareupdate -l` for pending updates and
+// `softwareupdate --history` for the last install.
+func getUpdateStatus() *UpdateStatus {
+	status := &UpdateStatus{Source: "softwareupdate"}
+
+	// softwareupdate prints progress to stderr and the list to stdout
+	cmd := exec.Command("softwareupdate", "-l")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err == nil {
+		status.PendingUpdates, status.PendingSecurityUpdates, status.RebootRequired = parseSoftwareUpdateList(out.String())
+	}
+
+	if out, err := exec.Command("softwareupdate", "--history").Output(); err == nil {
+		status.LastUpdated = parseSoftwareUpdateHistory(string(out))
+	}
+	return status
+}
+
+// parseSoftwareUpdateList counts the "* Label:" entries. Each is followed by
+// a detail line such as:
+//
+//	Title: macOS Sonoma 14.4.1, Version: 14.4.1, Size: 1234K, Recommended: YES, Action: restart,
+//
+// Entries whose label or title names a security release (Security Update,
+// Rapid Security Response, Background Security Improvement) or an XProtect
+// definitions update count as security updates. "Recommended: YES" is not
+// enough; Apple recommends major macOS upgrades too. RebootRequired is set
+// when a pending update will need a restart to apply.
+func parseSoftwareUpdateList(output string) (int, int, bool) {
+	pending, security := 0, 0
+	restart := false
+	label := ""
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "* Label:"):
+			pending++
+			label = strings.ToLower(trimmed)
+		case strings.HasPrefix(trimmed, "Title:"):
+			title, _, _ := strings.Cut(strings.ToLower(trimmed), ",")
+			if isSecurityUpdate(label) || isSecurityUpdate(title) {
+				security++
+			}
+			if strings.Contains(trimmed, "Action: restart") {
+				restart = true
+			}
+		}
+	}
+	return pending, security, restart
+}
+
+func isSecurityUpdate(name string) bool {
+	return strings.Contains(name, "security") || strings.Contains(name, "xprotect")
+}
+
+// parseSoftwareUpdateHistory returns the most recent install date from
+// `softwareupdate --history`, whose rows end in "MM/DD/YYYY, HH:MM:SS".
+func parseSoftwareUpdateHistory(output string) string {
+	var latest time.Time
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		stamp := strings.TrimSuffix(fields[len(fields)-2], ",") + " " + fields[len(fields)-1]
+		if t, err := time.Parse("01/02/2006 15:04:05", stamp); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	if latest.IsZero() {
+		return ""
+	}
+	return latest.Format("2006-01-02")
+}
@@ -0,0 +1,25 @@
+package collector
+
+import "testing"
+
+func TestParseSoftwareUpdateList(t *testing.T) {
+	output := `Software Update Tool
+
+Finding available software
+Software Update found the following new or updated software:
+* Label: macOS Sonoma 14.4.1-23E224
+	Title: macOS Sonoma 14.4.1, Version: 14.4.1, Size: 1234K, Recommended: YES, Action: restart,
+* Label: macOS Sequoia 15.0-24A335
+	Title: macOS Sequoia 15.0, Version: 15.0, Size: 6543210K, Recommended: YES, Action: restart,
+* Label: Background Security Improvement-26.1a
+	Title: Background Security Improvement (a), Version: 26.1a, Size: 120K, Recommended: YES,
+* Label: XProtectPlistConfigData_10_15-5284
+	Title: XProtectPlistConfigData, Version: 5284, Size: 300K, Recommended: YES, Action: none,
+* Label: Safari17.5VenturaAuto-17.5
+	Title: Safari, Version: 17.5, Size: 150000K, Recommended: YES,
+`
+	pending, security, restart := parseSoftwareUpdateList(output)
+	if pending != 5 || security != 2 || !restart {
+		t.Errorf("got pending=%d security=%d restart=%v, want 5, 2, true", pending, security, restart)
+	}
+}
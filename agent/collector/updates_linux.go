@@ -0,0 +1,249 @@
+//go:build linux
+
+package collector
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// getUpdateStatus reports pending updates from the local apt or dnf metadata
+// cache. Neither path refreshes the cache, so the counts are only as fresh as
+// the last `apt-get update` / `dnf makecache` run by the system timers.
+func getUpdateStatus() *UpdateStatus {
+	var status *UpdateStatus
+	if _, err := exec.LookPath("apt-get"); err == nil {
+		status = getAptUpdateStatus()
+	} else if _, err := exec.LookPath("dnf"); err == nil {
+		status = getDnfUpdateStatus()
+	} else {
+		status = &UpdateStatus{}
+	}
+	status.RebootRequired = linuxRebootRequired()
+	return status
+}
+
+func getAptUpdateStatus() *UpdateStatus {
+	status := &UpdateStatus{Source: "apt"}
+
+	// Simulated upgrade, no root required. dist-upgrade rather than upgrade
+	// so updates that pull in new dependencies are not held back:
+	// Inst libssl3 [3.0.11-1~deb12u1] (3.0.11-1~deb12u2 Debian-Security:12/stable-security [amd64])
+	out, err := exec.Command("apt-get", "-s", "-o", "Debug::NoLocking=true", "dist-upgrade").Output()
+	if err == nil {
+		status.PendingUpdates, status.PendingSecurityUpdates = parseAptSimulation(string(out))
+	}
+
+	// logrotate moves older entries to history.log.1.gz and onwards
+	rotated, _ := filepath.Glob("/var/log/apt/history.log.*.gz")
+	status.LastUpdated = getAptLastUpgrade(append([]string{"/var/log/apt/history.log"}, rotated...))
+	return status
+}
+
+func parseAptSimulation(output string) (int, int) {
+	pending, security := 0, 0
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "Inst ") {
+			continue
+		}
+		pending++
+		if strings.Contains(strings.ToLower(line), "-security") {
+			security++
+		}
+	}
+	return pending, security
+}
+
+// getAptLastUpgrade returns the End-Date of the most recent apt transaction
+// that upgraded packages, looking through the history log and its gzipped
+// rotations.
+func getAptLastUpgrade(historyPaths []string) string {
+	last := ""
+	for _, path := range historyPaths {
+		if date := readAptLastUpgrade(path); date > last {
+			last = date
+		}
+	}
+	return last
+}
+
+func readAptLastUpgrade(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return ""
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	// Entries are blocks of "Key: value" lines:
+	// Start-Date: 2024-03-01  06:25:13
+	// Upgrade: libssl3:amd64 (3.0.11-1~deb12u1, 3.0.11-1~deb12u2)
+	// End-Date: 2024-03-01  06:25:20
+	last := ""
+	upgraded := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "Start-Date:"):
+			upgraded = false
+		case strings.HasPrefix(line, "Upgrade:"):
+			upgraded = true
+		case strings.HasPrefix(line, "End-Date:") && upgraded:
+			fields := strings.Fields(strings.TrimPrefix(line, "End-Date:"))
+			if len(fields) > 0 && fields[0] > last {
+				last = fields[0]
+			}
+		}
+	}
+	return last
+}
+
+func getDnfUpdateStatus() *UpdateStatus {
+	status := &UpdateStatus{Source: "dnf"}
+
+	// -C keeps dnf on the cached metadata. check-update exits 100 when
+	// updates are available, so the error is expected.
+	out, _ := exec.Command("dnf", "-q", "-C", "check-update").Output()
+	status.PendingUpdates = countDnfPackageLines(string(out))
+
+	// Output: "FEDORA-2024-1234 Important/Sec. openssl-libs-1:3.1.1-4.fc39.x86_64"
+	if out, err := exec.Command("dnf", "-q", "-C", "updateinfo", "list", "--security").Output(); err == nil {
+		status.PendingSecurityUpdates = countDnfPackageLines(string(out))
+	}
+
+	for _, path := range []string{"/var/lib/dnf/history.sqlite", "/usr/lib/sysimage/libdnf5/transaction_history.sqlite"} {
+		if last := getDnfLastTransaction(path); last > status.LastUpdated {
+			status.LastUpdated = last
+		}
+	}
+	return status
+}
+
+// getDnfLastTransaction returns the end date of the latest transaction that
+// upgraded packages in a dnf (history.sqlite) or dnf5
+// (transaction_history.sqlite) history database, so installs and removals
+// don't read as patching. Both keep transactions in a trans table whose
+// dt_end column holds Unix seconds, 0 for one that never finished, and the
+// packages each touched in trans_item.
+func getDnfLastTransaction(path string) string {
+	db, err := openSQLite(path)
+	if err != nil {
+		return ""
+	}
+	defer db.Close()
+
+	upgraded := dnfUpgradeTransactions(db)
+	columns, err := db.tableColumns("trans")
+	if err != nil {
+		return ""
+	}
+	end := slices.Index(columns, "dt_end")
+	if end < 0 {
+		return ""
+	}
+
+	var latest int64
+	db.tableRows("trans", func(rowid int64, values []any) error {
+		if end < len(values) && upgraded[rowid] {
+			if t, ok := values[end].(int64); ok && t > latest {
+				latest = t
+			}
+		}
+		return nil
+	})
+	if latest == 0 {
+		return ""
+	}
+	return time.Unix(latest, 0).Format("2006-01-02")
+}
+
+// dnf's TransactionItemAction value for the package an upgrade installed
+// (libdnf/transaction/Types.hpp). dnf5 numbers actions through its
+// trans_item_action table instead.
+const dnfActionUpgrade = 6
+
+// dnfUpgradeTransactions returns the ids of transactions with at least one
+// upgraded package.
+func dnfUpgradeTransactions(db *sqliteDB) map[int64]bool {
+	upgraded := map[int64]bool{}
+	columns, err := db.tableColumns("trans_item")
+	if err != nil {
+		return upgraded
+	}
+	transID := slices.Index(columns, "trans_id")
+	action := slices.Index(columns, "action")
+	upgrade := int64(dnfActionUpgrade)
+	if action < 0 {
+		// dnf5
+		action = slices.Index(columns, "action_id")
+		upgrade = -1
+		db.tableRows("trans_item_action", func(rowid int64, values []any) error {
+			if len(values) > 1 && values[1] == "Upgrade" {
+				upgrade = rowid
+			}
+			return nil
+		})
+	}
+	if transID < 0 || action < 0 {
+		return upgraded
+	}
+
+	db.tableRows("trans_item", func(_ int64, values []any) error {
+		if transID < len(values) && action < len(values) && values[action] == upgrade {
+			if id, ok := values[transID].(int64); ok {
+				upgraded[id] = true
+			}
+		}
+		return nil
+	})
+	return upgraded
+}
+
+// countDnfPackageLines counts the three-column package rows in dnf output,
+// stopping at the "Obsoleting Packages" section.
+func countDnfPackageLines(output string) int {
+	count := 0
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+		if len(strings.Fields(line)) == 3 {
+			count++
+		}
+	}
+	return count
+}
+
+func linuxRebootRequired() bool {
+	// Debian/Ubuntu
+	if _, err := os.Stat("/var/run/reboot-required"); err == nil {
+		return true
+	}
+	// RHEL/Fedora: needs-restarting -r exits 1 when a reboot is needed
+	if _, err := exec.LookPath("needs-restarting"); err == nil {
+		cmd := exec.Command("needs-restarting", "-r")
+		if err := cmd.Run(); err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package collector
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetDnfLastTransaction(t *testing.T) {
+	// Both histories hold an upgrade, a later install and removal, and an
+	// unfinished upgrade; only the finished upgrade counts
+	want := time.Unix(1709290000, 0).Format("2006-01-02")
+	for _, fixture := range []string{"dnf-history.sqlite", "dnf5-history.sqlite"} {
+		if got := getDnfLastTransaction(filepath.Join("testdata", fixture)); got != want {
+			t.Errorf("%s: got %q, want %q", fixture, got, want)
+		}
+	}
+	if got := getDnfLastTransaction(filepath.Join("testdata", "rpmdb.sqlite")); got != "" {
+		t.Errorf("database without a trans table: got %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+//go:build windows
+
+package collector
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Counts pending updates through the Windows Update Agent COM API. The
+// search only consults the locally cached catalogue.
+const windowsPendingUpdatesScript = `$s = (New-Object -ComObject Microsoft.Update.Session).CreateUpdateSearcher()
+$s.Online = $false
+$r = $s.Search("IsInstalled=0 and IsHidden=0")
+$sec = @($r.Updates | Where-Object { $_.Categories | Where-Object { $_.Name -eq 'Security Updates' } }).Count
+Write-Output "$($r.Updates.Count);$sec"`
+
+// getUpdateStatus combines the Windows Update Agent pending list with the
+// hotfix history and the servicing stack's reboot flags.
+func getUpdateStatus() *UpdateStatus {
+	status := &UpdateStatus{Source: "windows_update"}
+
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsPendingUpdatesScript)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err == nil {
+		parts := strings.Split(strings.TrimSpace(out.String()), ";")
+		if len(parts) == 2 {
+			status.PendingUpdates, _ = strconv.Atoi(parts[0])
+			status.PendingSecurityUpdates, _ = strconv.Atoi(parts[1])
+		}
+	}
+
+	status.LastUpdated = getWindowsLastHotfixDate()
+	status.RebootRequired = windowsRebootRequired()
+	return status
+}
+
+// Lists hotfix install dates through Win32_QuickFixEngineering. Get-HotFix
+// turns InstalledOn into a DateTime, so the output is locale independent;
+// entries with an unreadable date are left out.
+const windowsHotfixDatesScript = `Get-HotFix | Where-Object { $_.InstalledOn } | ForEach-Object { $_.InstalledOn.ToString('yyyy-MM-dd') }`
+
+// getWindowsLastHotfixDate returns the newest hotfix install date.
+func getWindowsLastHotfixDate() string {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsHotfixDatesScript)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+
+	var latest time.Time
+	for _, line := range strings.Split(out.String(), "\n") {
+		if t, err := time.Parse("2006-01-02", strings.TrimSpace(line)); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	if latest.IsZero() {
+		return ""
+	}
+	return latest.Format("2006-01-02")
+}
+
+func windowsRebootRequired() bool {
+	keys := []string{
+		`HKLM\SOFTWARE\Microsoft\Windows\CurrentVersion\WindowsUpdate\Auto Update\RebootRequired`,
+		`HKLM\SOFTWARE\Microsoft\Windows\CurrentVersion\Component Based Servicing\RebootPending`,
+	}
+	for _, key := range keys {
+		if err := exec.Command("reg", "query", key).Run(); err == nil {
+			return true
+		}
+	}
+	return false
+}
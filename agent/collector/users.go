@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// primaryUserWindow is how far back logins are counted when picking the
+// primary user.
+const primaryUserWindow = 30 * 24 * time.Hour
+
+type UserAccount struct {
+	Username   string `json:"username"`
+	UID        string `json:"uid"` // Numeric UID on Unix, SID on Windows
+	FullName   string `json:"full_name,omitempty"`
+	HomeDir    string `json:"home_dir,omitempty"`
+	Admin      bool   `json:"admin"`
+	Disabled   bool   `json:"disabled,omitempty"`
+	LastLogin  string `json:"last_login,omitempty"` // RFC 3339
+	LoginCount int    `json:"login_count"`          // Logins within the primary user window
+}
+
+type LoginSession struct {
+	Username string `json:"username"`
+	Terminal string `json:"terminal,omitempty"` // e.g. "tty2", "pts/0", "console"
+	Host     string `json:"host,omitempty"`     // Remote host for SSH/RDP sessions
+	Since    string `json:"since,omitempty"`    // RFC 3339
+}
+
+// loginEvent is one interactive login taken from the platform's history.
+type loginEvent struct {
+	user     string
+	terminal string
+	time     time.Time
+}
+
+// summarizeLogins fills LastLogin and LoginCount on the accounts from the
+// login history and returns the primary user: whoever logged in most often
+// within the window, ties broken by the most recent login. Accounts not in
+// the list (system users, root) are ignored.
+func summarizeLogins(accounts []UserAccount, events []loginEvent, now time.Time) string {
+	index := map[string]int{}
+	for i, a := range accounts {
+		index[strings.ToLower(a.Username)] = i
+	}
+
+	last := map[string]time.Time{}
+	for _, e := range events {
+		i, ok := index[strings.ToLower(e.user)]
+		if !ok {
+			continue
+		}
+		if e.time.After(last[accounts[i].Username]) {
+			last[accounts[i].Username] = e.time
+		}
+		if now.Sub(e.time) <= primaryUserWindow {
+			accounts[i].LoginCount++
+		}
+	}
+
+	for i := range accounts {
+		if t, ok := last[accounts[i].Username]; ok && accounts[i].LastLogin == "" {
+			accounts[i].LastLogin = t.UTC().Format(time.RFC3339)
+		}
+	}
+
+	ranked := make([]UserAccount, 0, len(accounts))
+	for _, a := range accounts {
+		if a.LoginCount > 0 {
+			ranked = append(ranked, a)
+		}
+	}
+	if len(ranked) == 0 {
+		return ""
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].LoginCount != ranked[j].LoginCount {
+			return ranked[i].LoginCount > ranked[j].LoginCount
+		}
+		return last[ranked[i].Username].After(last[ranked[j].Username])
+	})
+	return ranked[0].Username
+}
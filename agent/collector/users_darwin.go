@@ -0,0 +1,130 @@
+//go:build darwin
+
+package collector
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getMacOSUsers returns the local accounts from Directory Services, the
+// sessions from `who` and the primary user computed from `last`.
+func getMacOSUsers() ([]UserAccount, []LoginSession, string) {
+	accounts := getMacOSAccounts()
+	now := time.Now()
+	primary := summarizeLogins(accounts, parseLastOutput(runCommand("last"), now), now)
+	return accounts, parseWhoOutput(runCommand("who"), now), primary
+}
+
+func runCommand(name string, args ...string) string {
+	cmd := exec.Command(name, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+	return out.String()
+}
+
+// getMacOSAccounts lists users with UID >= 500, skipping the underscore
+// service accounts. Members of the admin group are administrators.
+func getMacOSAccounts() []UserAccount {
+	accounts := []UserAccount{}
+
+	realNames := map[string]string{}
+	for _, line := range strings.Split(runCommand("dscl", ".", "-list", "/Users", "RealName"), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			realNames[fields[0]] = strings.Join(fields[1:], " ")
+		}
+	}
+
+	admins := map[string]bool{}
+	// GroupMembership: root alice
+	membership := runCommand("dscl", ".", "-read", "/Groups/admin", "GroupMembership")
+	for _, name := range strings.Fields(strings.TrimPrefix(strings.TrimSpace(membership), "GroupMembership:")) {
+		admins[name] = true
+	}
+
+	for _, line := range strings.Split(runCommand("dscl", ".", "-list", "/Users", "UniqueID"), "\n") {
+		// alice    501
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "_") {
+			continue
+		}
+		if uid, err := strconv.Atoi(fields[1]); err != nil || uid < 500 {
+			continue
+		}
+		accounts = append(accounts, UserAccount{
+			Username: fields[0],
+			UID:      fields[1],
+			FullName: realNames[fields[0]],
+			HomeDir:  "/Users/" + fields[0],
+			Admin:    admins[fields[0]],
+		})
+	}
+	return accounts
+}
+
+var lastDateRe = regexp.MustCompile(`[A-Z][a-z]{2} ([A-Z][a-z]{2} [ \d]\d \d\d:\d\d)`)
+
+// parseLastOutput parses BSD `last`, which omits the year:
+//
+//	alice     console                   Mon Oct  7 09:12   still logged in
+//	alice     ttys001  10.0.0.5         Sun Oct  6 18:40 - 19:02  (00:21)
+func parseLastOutput(output string, now time.Time) []loginEvent {
+	var events []loginEvent
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] == "reboot" || fields[0] == "shutdown" || fields[0] == "wtmp" {
+			continue
+		}
+		m := lastDateRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		events = append(events, loginEvent{user: fields[0], terminal: fields[1], time: withoutYear(m[1], now)})
+	}
+	return events
+}
+
+// parseWhoOutput parses `who`:
+//
+//	alice    console  Oct  7 09:12
+//	alice    ttys001  Oct  7 10:03 	(10.0.0.5)
+func parseWhoOutput(output string, now time.Time) []LoginSession {
+	sessions := []LoginSession{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		s := LoginSession{Username: fields[0], Terminal: fields[1]}
+		if t := withoutYear(strings.Join(fields[2:5], " "), now); !t.IsZero() {
+			s.Since = t.UTC().Format(time.RFC3339)
+		}
+		if len(fields) > 5 {
+			s.Host = strings.Trim(fields[5], "()")
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// withoutYear parses a timestamp lacking a year, assuming the most recent
+// year that does not put it in the future.
+func withoutYear(value string, now time.Time) time.Time {
+	// Collapse the padding in "Oct  7" so the layout matches either width
+	t, err := time.ParseInLocation("Jan 2 15:04", strings.Join(strings.Fields(value), " "), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	t = t.AddDate(now.Year()-t.Year(), 0, 0)
+	if t.After(now) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
@@ -0,0 +1,242 @@
+//go:build linux
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// glibc struct utmp on 64-bit Linux (also used for wtmp)
+const (
+	utmpRecordSize  = 384
+	utmpUserProcess = 7
+)
+
+// wtmpdb numbers its entry types differently: BOOT_TIME 1, RUNLEVEL 2,
+// USER_PROCESS 3
+const wtmpdbUserProcess = 3
+
+// getLinuxUsers returns the human accounts, the active sessions and the
+// primary user computed from wtmp (or wtmpdb on newer distributions).
+func getLinuxUsers() ([]UserAccount, []LoginSession, string) {
+	accounts := getLinuxHumanAccounts("/etc/passwd", "/etc/group")
+
+	var events []loginEvent
+	for _, path := range []string{"/var/log/wtmp.1", "/var/log/wtmp"} {
+		events = append(events, readWtmp(path)...)
+	}
+	events = append(events, readWtmpDB("/var/lib/wtmpdb/wtmp.db")...)
+
+	applyLastlog(accounts, "/var/log/lastlog", "/var/lib/lastlog/lastlog2.db")
+	primary := summarizeLogins(accounts, events, time.Now())
+
+	return accounts, getLinuxSessions("/var/run/utmp"), primary
+}
+
+// getLinuxHumanAccounts parses /etc/passwd and keeps regular users: UIDs in
+// the login.defs range with a usable login shell. Membership of sudo,
+// wheel or admin marks an account as an administrator.
+func getLinuxHumanAccounts(passwdPath, groupPath string) []UserAccount {
+	uidMin, uidMax := 1000, 60000
+	if f, err := os.Open("/etc/login.defs"); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 2 {
+				switch fields[0] {
+				case "UID_MIN":
+					uidMin, _ = strconv.Atoi(fields[1])
+				case "UID_MAX":
+					uidMax, _ = strconv.Atoi(fields[1])
+				}
+			}
+		}
+		f.Close()
+	}
+
+	admins := map[string]bool{}
+	if content, err := os.ReadFile(groupPath); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			// sudo:x:27:alice,bob
+			parts := strings.Split(line, ":")
+			if len(parts) == 4 && (parts[0] == "sudo" || parts[0] == "wheel" || parts[0] == "admin") {
+				for _, member := range strings.Split(parts[3], ",") {
+					if member != "" {
+						admins[member] = true
+					}
+				}
+			}
+		}
+	}
+
+	accounts := []UserAccount{}
+	content, err := os.ReadFile(passwdPath)
+	if err != nil {
+		return accounts
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		// alice:x:1000:1000:Alice Smith,,,:/home/alice:/bin/bash
+		parts := strings.Split(line, ":")
+		if len(parts) != 7 {
+			continue
+		}
+		uid, err := strconv.Atoi(parts[2])
+		if err != nil || uid < uidMin || uid > uidMax {
+			continue
+		}
+		shell := filepath.Base(parts[6])
+		if shell == "nologin" || shell == "false" || shell == "sync" {
+			continue
+		}
+		accounts = append(accounts, UserAccount{
+			Username: parts[0],
+			UID:      parts[2],
+			FullName: strings.Split(parts[4], ",")[0],
+			HomeDir:  parts[5],
+			Admin:    admins[parts[0]],
+		})
+	}
+	return accounts
+}
+
+type utmpRecord struct {
+	typ  int16
+	pid  int32
+	line string
+	user string
+	host string
+	time time.Time
+}
+
+func readUtmpFile(path string) []utmpRecord {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	cstr := func(b []byte) string {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return string(b)
+	}
+
+	var records []utmpRecord
+	for off := 0; off+utmpRecordSize <= len(content); off += utmpRecordSize {
+		r := content[off : off+utmpRecordSize]
+		records = append(records, utmpRecord{
+			typ:  int16(binary.LittleEndian.Uint16(r[0:])),
+			pid:  int32(binary.LittleEndian.Uint32(r[4:])),
+			line: cstr(r[8:40]),
+			user: cstr(r[44:76]),
+			host: cstr(r[76:332]),
+			time: time.Unix(int64(int32(binary.LittleEndian.Uint32(r[340:]))), 0),
+		})
+	}
+	return records
+}
+
+func readWtmp(path string) []loginEvent {
+	var events []loginEvent
+	for _, r := range readUtmpFile(path) {
+		if r.typ == utmpUserProcess && r.user != "" {
+			events = append(events, loginEvent{user: r.user, terminal: r.line, time: r.time})
+		}
+	}
+	return events
+}
+
+// readWtmpDB reads the sqlite login database that replaces wtmp on
+// distributions built with wtmpdb (Y2038-safe). Login is in microseconds.
+func readWtmpDB(path string) []loginEvent {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil
+	}
+	defer db.Close()
+
+	var events []loginEvent
+	// wtmp(ID, Type, User, Login, Logout, TTY, RemoteHost, Service)
+	db.tableRows("wtmp", func(_ int64, values []any) error {
+		if len(values) < 6 {
+			return nil
+		}
+		typ, _ := values[1].(int64)
+		user, _ := values[2].(string)
+		login, _ := values[3].(int64)
+		tty, _ := values[5].(string)
+		if typ == wtmpdbUserProcess && user != "" {
+			events = append(events, loginEvent{user: user, terminal: tty, time: time.UnixMicro(login)})
+		}
+		return nil
+	})
+	return events
+}
+
+// applyLastlog sets LastLogin from lastlog, which survives wtmp rotation.
+// The classic file is a sparse array of 292-byte records indexed by UID;
+// newer systems use the lastlog2 sqlite database keyed by name.
+func applyLastlog(accounts []UserAccount, lastlogPath, lastlog2Path string) {
+	if f, err := os.Open(lastlogPath); err == nil {
+		defer f.Close()
+		record := make([]byte, 292)
+		for i, a := range accounts {
+			uid, _ := strconv.ParseInt(a.UID, 10, 64)
+			if _, err := f.ReadAt(record, uid*292); err != nil {
+				continue
+			}
+			if ts := int32(binary.LittleEndian.Uint32(record)); ts > 0 {
+				accounts[i].LastLogin = time.Unix(int64(ts), 0).UTC().Format(time.RFC3339)
+			}
+		}
+	}
+
+	db, err := openSQLite(lastlog2Path)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	last := map[string]int64{}
+	// Lastlog2(Name, Time, TTY, RemoteHost, Service)
+	db.tableRows("Lastlog2", func(_ int64, values []any) error {
+		if len(values) >= 2 {
+			name, _ := values[0].(string)
+			ts, _ := values[1].(int64)
+			last[name] = ts
+		}
+		return nil
+	})
+	for i, a := range accounts {
+		if ts := last[a.Username]; ts > 0 {
+			accounts[i].LastLogin = time.Unix(ts, 0).UTC().Format(time.RFC3339)
+		}
+	}
+}
+
+// getLinuxSessions lists USER_PROCESS entries in utmp whose process is
+// still alive.
+func getLinuxSessions(utmpPath string) []LoginSession {
+	sessions := []LoginSession{}
+	for _, r := range readUtmpFile(utmpPath) {
+		if r.typ != utmpUserProcess || r.user == "" {
+			continue
+		}
+		if _, err := os.Stat("/proc/" + strconv.Itoa(int(r.pid))); err != nil {
+			continue
+		}
+		sessions = append(sessions, LoginSession{
+			Username: r.user,
+			Terminal: r.line,
+			Host:     r.host,
+			Since:    r.time.UTC().Format(time.RFC3339),
+		})
+	}
+	return sessions
+}
@@ -0,0 +1,243 @@
+//go:build windows
+
+package collector
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getWindowsUsers returns the local accounts, the sessions from
+// `query user` and the primary user computed from interactive logon
+// events (4624) in the Security log.
+func getWindowsUsers() ([]UserAccount, []LoginSession, string) {
+	accounts := getWindowsAccounts()
+	primary := summarizeLogins(accounts, getWindowsLogonEvents(), time.Now())
+	return accounts, getWindowsSessions(), primary
+}
+
+// getWindowsAccounts lists enabled and disabled local accounts whose RID is
+// 1000 or above, which leaves out Administrator, Guest, DefaultAccount and
+// WDAGUtilityAccount.
+func getWindowsAccounts() []UserAccount {
+	accounts := []UserAccount{}
+
+	cmd := exec.Command("wmic", "useraccount", "where", "LocalAccount=TRUE", "get", "Disabled,FullName,Name,SID", "/format:csv")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return accounts
+	}
+
+	admins := getWindowsAdministrators()
+	profiles := os.Getenv("SystemDrive") + `\Users`
+
+	// Node,Disabled,FullName,Name,SID
+	reader := csv.NewReader(strings.NewReader(strings.ReplaceAll(out.String(), "\r", "")))
+	reader.FieldsPerRecord = -1
+	records, _ := reader.ReadAll()
+	for _, r := range records {
+		if len(r) != 5 || r[0] == "Node" {
+			continue
+		}
+		sid := r[4]
+		rid, err := strconv.Atoi(sid[strings.LastIndex(sid, "-")+1:])
+		if err != nil || rid < 1000 {
+			continue
+		}
+		accounts = append(accounts, UserAccount{
+			Username: r[3],
+			UID:      sid,
+			FullName: r[2],
+			HomeDir:  filepath.Join(profiles, r[3]),
+			Admin:    admins[strings.ToLower(r[3])],
+			Disabled: strings.EqualFold(r[1], "TRUE"),
+		})
+	}
+	return accounts
+}
+
+// getWindowsAdministrators parses `net localgroup Administrators`. Members
+// are listed between the dashed separator and the completion message.
+func getWindowsAdministrators() map[string]bool {
+	admins := map[string]bool{}
+	cmd := exec.Command("net", "localgroup", "Administrators")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return admins
+	}
+
+	inMembers := false
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "---"):
+			inMembers = true
+		case !inMembers || line == "" || strings.HasPrefix(line, "The command completed"):
+		default:
+			// Local members are bare names; domain members are "DOMAIN\name"
+			if i := strings.LastIndex(line, `\`); i >= 0 {
+				line = line[i+1:]
+			}
+			admins[strings.ToLower(line)] = true
+		}
+	}
+	return admins
+}
+
+// getWindowsLogonEvents reads interactive (2), remote interactive (10) and
+// cached interactive (11) logons from the Security log within the primary
+// user window. Reading the Security log needs administrator rights, which
+// the agent service has.
+func getWindowsLogonEvents() []loginEvent {
+	query := "*[System[(EventID=4624) and TimeCreated[timediff(@SystemTime) <= " +
+		strconv.FormatInt(primaryUserWindow.Milliseconds(), 10) + "]]" +
+		" and EventData[Data[@Name='LogonType']='2' or Data[@Name='LogonType']='10' or Data[@Name='LogonType']='11']]"
+	cmd := exec.Command("wevtutil", "qe", "Security", "/q:"+query, "/f:xml")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil
+	}
+	return parseLogonEventsXML(out.Bytes())
+}
+
+// parseLogonEventsXML decodes the sequence of <Event> elements wevtutil
+// prints (there is no enclosing root element).
+func parseLogonEventsXML(data []byte) []loginEvent {
+	type event struct {
+		System struct {
+			TimeCreated struct {
+				SystemTime string `xml:"SystemTime,attr"`
+			}
+		}
+		EventData struct {
+			Data []struct {
+				Name  string `xml:"Name,attr"`
+				Value string `xml:",chardata"`
+			}
+		}
+	}
+
+	var events []loginEvent
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		var e event
+		if err := decoder.Decode(&e); err != nil {
+			if err != io.EOF {
+				return events
+			}
+			break
+		}
+		t, err := time.Parse(time.RFC3339Nano, e.System.TimeCreated.SystemTime)
+		if err != nil {
+			continue
+		}
+		le := loginEvent{time: t}
+		for _, d := range e.EventData.Data {
+			switch d.Name {
+			case "TargetUserName":
+				le.user = d.Value
+			case "LogonType":
+				le.terminal = map[string]string{"2": "console", "10": "rdp", "11": "cached"}[d.Value]
+			}
+		}
+		// Window Manager and Font Driver Host log interactive logons too
+		if le.user != "" && !strings.HasPrefix(le.user, "DWM-") && !strings.HasPrefix(le.user, "UMFD-") {
+			events = append(events, le)
+		}
+	}
+	return events
+}
+
+// getWindowsSessions parses `query user`, whose columns are fixed-width
+// and whose SESSIONNAME is blank for disconnected sessions:
+//
+//	 USERNAME              SESSIONNAME        ID  STATE   IDLE TIME  LOGON TIME
+//	>alice                 console             1  Active      none   10/7/2024 9:12 AM
+func getWindowsSessions() []LoginSession {
+	sessions := []LoginSession{}
+	cmd := exec.Command("query", "user")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	// query exits with 1 when nobody is logged on
+	cmd.Run()
+
+	lines := strings.Split(strings.ReplaceAll(out.String(), "\r", ""), "\n")
+	if len(lines) < 2 {
+		return sessions
+	}
+	header := lines[0]
+	sessionCol := strings.Index(header, "SESSIONNAME")
+	idCol := strings.Index(header, "ID")
+	logonCol := strings.Index(header, "LOGON TIME")
+	if sessionCol < 0 || idCol < 0 || logonCol < 0 {
+		return sessions
+	}
+
+	column := func(line string, from, to int) string {
+		if from >= len(line) {
+			return ""
+		}
+		if to > len(line) || to < 0 {
+			to = len(line)
+		}
+		return strings.TrimSpace(line[from:to])
+	}
+
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		s := LoginSession{
+			Username: strings.TrimPrefix(column(line, 0, sessionCol), ">"),
+		}
+		// IDs are right-aligned and can spill left of the ID header
+		if fields := strings.Fields(column(line, sessionCol, idCol)); len(fields) > 0 && !isDigits(fields[0]) {
+			s.Terminal = fields[0]
+		}
+		// Logon time follows the system locale; en-US is the common case
+		if t, err := time.ParseInLocation("1/2/2006 3:04 PM", column(line, logonCol, -1), time.Local); err == nil {
+			s.Since = t.UTC().Format(time.RFC3339)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// loadedUserSIDs lists the users whose registry hive is loaded under
+// HKEY_USERS, i.e. who are logged on or running processes. Built-in and
+// service accounts and the _Classes hives are left out.
+func loadedUserSIDs() []string {
+	cmd := exec.Command("reg", "query", "HKU")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil
+	}
+
+	// Output:
+	// HKEY_USERS\S-1-5-21-1004336348-1177238915-682003330-1001
+	// HKEY_USERS\S-1-5-21-1004336348-1177238915-682003330-1001_Classes
+	var sids []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		sid, ok := strings.CutPrefix(strings.TrimSpace(line), `HKEY_USERS\`)
+		if !ok || strings.HasSuffix(sid, "_Classes") {
+			continue
+		}
+		// Local and domain accounts, and Entra ID accounts
+		if strings.HasPrefix(sid, "S-1-5-21-") || strings.HasPrefix(sid, "S-1-12-1-") {
+			sids = append(sids, sid)
+		}
+	}
+	return sids
+}
@@ -0,0 +1,12 @@
+//go:build darwin
+
+package collector
+
+func getVirtHints() virtHints {
+	// hw.model is e.g. "VMware7,1", "Parallels-ARM" or "VirtualMac2,1"
+	// (Apple Virtualization framework) inside a VM.
+	return virtHints{
+		dmi:        []string{getSysctl("hw.model")},
+		vmmPresent: getSysctl("kern.hv_vmm_present") == "1",
+	}
+}
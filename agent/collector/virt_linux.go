@@ -0,0 +1,56 @@
+//go:build linux
+
+package collector
+
+import (
+	"os"
+	"strings"
+)
+
+func getVirtHints() virtHints {
+	hints := virtHints{
+		hypervisor:       getFileContent("/sys/hypervisor/type"), // "xen" on Xen guests
+		containerRuntime: detectLinuxContainerRuntime(),
+	}
+	for _, f := range []string{"sys_vendor", "product_name", "bios_vendor", "board_vendor", "chassis_asset_tag"} {
+		if v := getFileContent("/sys/class/dmi/id/" + f); v != "" {
+			hints.dmi = append(hints.dmi, v)
+		}
+	}
+	return hints
+}
+
+// detectLinuxContainerRuntime checks the marker files runtimes drop into
+// the container root, then PID 1's cgroup and environment.
+func detectLinuxContainerRuntime() string {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman"
+	}
+
+	cgroup := getFileContent("/proc/1/cgroup")
+	switch {
+	case strings.Contains(cgroup, "kubepods"):
+		return "kubernetes"
+	case strings.Contains(cgroup, "docker"):
+		return "docker"
+	case strings.Contains(cgroup, "libpod"):
+		return "podman"
+	case strings.Contains(cgroup, "lxc"):
+		return "lxc"
+	case strings.Contains(cgroup, "containerd"):
+		return "containerd"
+	}
+
+	// systemd-nspawn, LXC and others set container= for PID 1
+	if environ, err := os.ReadFile("/proc/1/environ"); err == nil {
+		for _, kv := range strings.Split(string(environ), "\x00") {
+			if value, ok := strings.CutPrefix(kv, "container="); ok && value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package collector
+
+func getVirtHints() virtHints {
+	// e.g. "Microsoft Corporation" / "Virtual Machine" on Hyper-V and Azure,
+	// "VMware, Inc." / "VMware7,1" on VMware
+	return virtHints{
+		dmi: []string{
+			getWmic("csproduct", "vendor"),
+			getWmic("csproduct", "name"),
+			getWmic("bios", "manufacturer"),
+			getWmic("systemenclosure", "SMBIOSAssetTag"),
+		},
+	}
+}
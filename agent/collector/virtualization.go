@@ -0,0 +1,251 @@
+package collector
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VirtualizationInfo tells the backend whether the asset is a VM or
+// container, and on which cloud it runs. It is embedded in SystemInfo so the
+// fields appear at the top level of the check-in.
+type VirtualizationInfo struct {
+	Virtual          bool   `json:"virtual"`
+	Hypervisor       string `json:"hypervisor,omitempty"`        // e.g. "kvm", "vmware", "hyperv", "xen", "virtualbox"
+	ContainerRuntime string `json:"container_runtime,omitempty"` // e.g. "docker", "podman", "kubernetes", "lxc"
+	CloudProvider    string `json:"cloud_provider,omitempty"`    // e.g. "aws", "gcp", "azure", "oracle", "digitalocean"
+	InstanceID       string `json:"instance_id,omitempty"`
+}
+
+// virtHints is what each platform can tell us cheaply before the shared
+// detection logic runs.
+type virtHints struct {
+	dmi              []string // Vendor/product/BIOS strings from SMBIOS
+	hypervisor       string   // Hypervisor reported directly by the OS (e.g. /sys/hypervisor/type)
+	containerRuntime string
+	vmmPresent       bool // OS says it runs under a hypervisor (e.g. macOS kern.hv_vmm_present)
+}
+
+// CPUID leaf 0x40000000 vendor signatures
+var hypervisorSignatures = map[string]string{
+	"KVMKVMKVM":    "kvm",
+	"VMwareVMware": "vmware",
+	"Microsoft Hv": "hyperv",
+	"XenVMMXenVMM": "xen",
+	"VBoxVBoxVBox": "virtualbox",
+	"TCGTCGTCGTCG": "qemu",
+	" lrpepyh  vr": "parallels",
+	"bhyve bhyve ": "bhyve",
+	"ACRNACRNACRN": "acrn",
+}
+
+// DMI substrings (lower case) that identify a hypervisor
+var dmiHypervisors = []struct{ match, hypervisor string }{
+	{"vmware", "vmware"},
+	{"virtualbox", "virtualbox"},
+	{"innotek", "virtualbox"},
+	{"virtual machine", "hyperv"}, // Microsoft Corporation "Virtual Machine"
+	{"hvm domu", "xen"},
+	{"xen", "xen"},
+	{"kvm", "kvm"},
+	{"qemu", "qemu"},
+	{"parallels", "parallels"},
+	{"bhyve", "bhyve"},
+	{"google compute engine", "kvm"},
+	{"virtualmac", "apple"},
+}
+
+// DMI substrings (lower case) that identify a cloud provider, so only its
+// metadata service is probed
+var dmiCloudProviders = []struct{ match, provider string }{
+	{"amazon ec2", "aws"},
+	{"google compute engine", "gcp"},              // Not just "google", which Chromebooks report too
+	{"7783-7084-3265-9085-8269-3286-77", "azure"}, // Azure chassis asset tag
+	{"oraclecloud", "oracle"},
+	{"digitalocean", "digitalocean"},
+}
+
+func detectVirtualization(hints virtHints, metadataURL string) VirtualizationInfo {
+	v := VirtualizationInfo{ContainerRuntime: hints.containerRuntime}
+	dmi := strings.ToLower(strings.Join(hints.dmi, " "))
+
+	// 1. CPUID is the most precise, except that Windows hosts with Hyper-V or
+	//    VBS enabled run in the root partition and also report "Microsoft Hv".
+	//    Only trust it when SMBIOS does not name a physical OEM.
+	if present, sig := cpuidHypervisor(); present {
+		name := hypervisorSignatures[strings.TrimRight(sig, "\x00")]
+		if name == "hyperv" && dmi != "" && !strings.Contains(dmi, "virtual machine") {
+			name = ""
+		} else if name == "" {
+			name = "unknown"
+		}
+		v.Hypervisor = name
+	}
+
+	// 2. What the OS tells us directly
+	if v.Hypervisor == "" && hints.hypervisor != "" {
+		v.Hypervisor = hints.hypervisor
+	}
+
+	// 3. SMBIOS vendor/product strings
+	if v.Hypervisor == "" || v.Hypervisor == "unknown" {
+		for _, h := range dmiHypervisors {
+			if strings.Contains(dmi, h.match) {
+				v.Hypervisor = h.hypervisor
+				break
+			}
+		}
+	}
+	if v.Hypervisor == "" && hints.vmmPresent {
+		v.Hypervisor = "unknown"
+	}
+
+	v.Virtual = v.Hypervisor != "" || v.ContainerRuntime != ""
+
+	// Bare-metal cloud instances are not virtual but still carry the
+	// provider's SMBIOS strings, so the hint alone is enough to probe.
+	hint := cloudHint(dmi)
+	if metadataURL != "" && (hint != "" || v.Virtual) {
+		v.CloudProvider, v.InstanceID = detectCloudInstance(metadataURL, hint)
+	}
+	return v
+}
+
+// cloudProbes fetch the instance ID from each provider's metadata service.
+var cloudProbes = []struct {
+	provider string
+	probe    func(client *http.Client, base string) (string, error)
+}{
+	{"aws", probeAWS},
+	{"gcp", probeGCP},
+	{"azure", probeAzure},
+	{"oracle", probeOracle},
+	{"digitalocean", probeDigitalOcean},
+}
+
+// cloudHint returns the provider the lower-cased SMBIOS strings name.
+func cloudHint(dmi string) string {
+	for _, c := range dmiCloudProviders {
+		if strings.Contains(dmi, c.match) {
+			return c.provider
+		}
+	}
+	return ""
+}
+
+// cloudInstance is the outcome of detectCloudInstance.
+type cloudInstance struct {
+	provider, id string
+}
+
+// Outcomes by metadata URL and hint. Instances do not move between clouds,
+// and probing every provider costs a timeout when none answers, so each is
+// looked up once per run; a hinted probe that failed is retried.
+var (
+	cloudCacheMu sync.Mutex
+	cloudCache   = map[[2]string]cloudInstance{}
+)
+
+// detectCloudInstance asks the metadata service at baseURL who it belongs
+// to. With a hint only that provider is tried; otherwise all at once, the
+// first in cloudProbes order to answer winning.
+func detectCloudInstance(baseURL, hint string) (string, string) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	key := [2]string{baseURL, hint}
+	cloudCacheMu.Lock()
+	defer cloudCacheMu.Unlock()
+	if c, ok := cloudCache[key]; ok {
+		return c.provider, c.id
+	}
+
+	client := &http.Client{Timeout: 1 * time.Second}
+	ids := make([]string, len(cloudProbes))
+	var wg sync.WaitGroup
+	for i, p := range cloudProbes {
+		if hint != "" && p.provider != hint {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if id, err := p.probe(client, baseURL); err == nil {
+				ids[i] = id
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := cloudInstance{provider: hint}
+	for i, id := range ids {
+		if id != "" {
+			result = cloudInstance{cloudProbes[i].provider, id}
+			break
+		}
+	}
+	if result.id != "" || hint == "" {
+		cloudCache[key] = result
+	}
+	return result.provider, result.id
+}
+
+func metadataGet(client *http.Client, method, url string, headers map[string]string) (string, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata %s returned status %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// probeAWS uses IMDSv2: fetch a session token, then the instance ID.
+func probeAWS(client *http.Client, base string) (string, error) {
+	token, err := metadataGet(client, "PUT", base+"/latest/api/token", map[string]string{
+		"X-aws-ec2-metadata-token-ttl-seconds": "60",
+	})
+	if err != nil {
+		return "", err
+	}
+	return metadataGet(client, "GET", base+"/latest/meta-data/instance-id", map[string]string{
+		"X-aws-ec2-metadata-token": token,
+	})
+}
+
+func probeGCP(client *http.Client, base string) (string, error) {
+	return metadataGet(client, "GET", base+"/computeMetadata/v1/instance/id", map[string]string{
+		"Metadata-Flavor": "Google",
+	})
+}
+
+func probeAzure(client *http.Client, base string) (string, error) {
+	return metadataGet(client, "GET", base+"/metadata/instance/compute/vmId?api-version=2021-02-01&format=text", map[string]string{
+		"Metadata": "true",
+	})
+}
+
+func probeOracle(client *http.Client, base string) (string, error) {
+	return metadataGet(client, "GET", base+"/opc/v2/instance/id", map[string]string{
+		"Authorization": "Bearer Oracle",
+	})
+}
+
+func probeDigitalOcean(client *http.Client, base string) (string, error) {
+	return metadataGet(client, "GET", base+"/metadata/v1/id", nil)
+}
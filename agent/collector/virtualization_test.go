@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// metadataStandIns mimic each provider's metadata service, including the
+// headers it insists on.
+var metadataStandIns = map[string]http.HandlerFunc{
+	"aws": func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/latest/api/token" && r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") != "":
+			w.Write([]byte("token-1"))
+		case r.URL.Path == "/latest/meta-data/instance-id" && r.Header.Get("X-aws-ec2-metadata-token") == "token-1":
+			w.Write([]byte("i-0123456789abcdef0"))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	},
+	"gcp": func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/computeMetadata/v1/instance/id" && r.Header.Get("Metadata-Flavor") == "Google" {
+			w.Write([]byte("4520031799277581759\n"))
+			return
+		}
+		http.NotFound(w, r)
+	},
+	"azure": func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metadata/instance/compute/vmId" && r.URL.Query().Get("format") == "text" && r.Header.Get("Metadata") == "true" {
+			w.Write([]byte("02aab8a4-74ef-476e-8182-f6d2ba4166a6"))
+			return
+		}
+		http.NotFound(w, r)
+	},
+	"oracle": func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/opc/v2/instance/id" && r.Header.Get("Authorization") == "Bearer Oracle" {
+			w.Write([]byte("ocid1.instance.oc1.iad.abc"))
+			return
+		}
+		http.NotFound(w, r)
+	},
+	"digitalocean": func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metadata/v1/id" {
+			w.Write([]byte("2756294"))
+			return
+		}
+		http.NotFound(w, r)
+	},
+}
+
+var metadataInstanceIDs = map[string]string{
+	"aws":          "i-0123456789abcdef0",
+	"gcp":          "4520031799277581759",
+	"azure":        "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+	"oracle":       "ocid1.instance.oc1.iad.abc",
+	"digitalocean": "2756294",
+}
+
+func TestDetectCloudInstance(t *testing.T) {
+	for provider, handler := range metadataStandIns {
+		t.Run(provider, func(t *testing.T) {
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			for _, hint := range []string{"", provider} {
+				gotProvider, gotID := detectCloudInstance(srv.URL, hint)
+				if gotProvider != provider || gotID != metadataInstanceIDs[provider] {
+					t.Errorf("detectCloudInstance(hint %q) = %q, %q; want %q, %q", hint, gotProvider, gotID, provider, metadataInstanceIDs[provider])
+				}
+			}
+		})
+	}
+}
+
+func TestDetectCloudInstanceCached(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		if provider, id := detectCloudInstance(srv.URL, ""); provider != "" || id != "" {
+			t.Fatalf("detectCloudInstance = %q, %q; want nothing", provider, id)
+		}
+	}
+	if n := int(requests.Load()); n != len(cloudProbes) {
+		t.Errorf("%d metadata requests for three detections, want %d", n, len(cloudProbes))
+	}
+}
+
+func TestCloudHint(t *testing.T) {
+	tests := []struct {
+		dmi  []string
+		want string
+	}{
+		{[]string{"Google", "Google Compute Engine"}, "gcp"},
+		{[]string{"Google", "Eve", "coreboot"}, ""}, // Chromebook
+		{[]string{"Amazon EC2", "m5.large"}, "aws"},
+		{[]string{"Microsoft Corporation", "Virtual Machine", "7783-7084-3265-9085-8269-3286-77"}, "azure"},
+		{[]string{"LENOVO", "20XW"}, ""},
+	}
+	for _, tt := range tests {
+		if got := cloudHint(strings.ToLower(strings.Join(tt.dmi, " "))); got != tt.want {
+			t.Errorf("cloudHint(%q) = %q, want %q", tt.dmi, got, tt.want)
+		}
+	}
+}
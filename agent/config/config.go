@@ -3,6 +3,10 @@ package config
 import (
 	"flag"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -10,7 +14,36 @@ type Config struct {
 	APIKey     string
 	TenantID   string
 	Interval   int // Seconds between check-ins
-	ScanRange  string // CIDR to scan (e.g. 192.168.1.0/24)
+	ScanRange  string // Targets to scan (e.g. 192.168.1.0/24,10.0.0.5-10.0.0.50)
+	LanguagePackages bool // Inventory pip/npm/gem/cargo/go packages
+	DockerSocket     string // Docker Engine API socket
+	ContainerdSocket string // containerd gRPC socket
+	CloudMetadataURL string // Cloud instance metadata service base URL
+	DataDir          string // Local state and backend-synced data files
+	MeteringInterval int    // Seconds between software usage samples, 0 disables metering
+	CertPaths        []string // Extra certificate files/directories to inventory
+	UploadSBOM       bool     // Upload a CycloneDX SBOM after every check-in
+	ScanARP          bool     // Solicit ARP from silent on-link hosts during scans
+	ScanMulticast    bool     // Discover devices by mDNS/DNS-SD and SSDP during scans
+	ScanPJL          bool     // Query every open JetDirect port with PJL
+	ScanExclude      []string // Addresses, CIDRs, ranges and hostnames never scanned
+	ScanAutoPrefix   int      // Largest subnet "-scan auto" scans whole
+	ScanAutoVirtual  bool     // "-scan auto" includes container, VM and VPN networks
+	ScanSchedule     string   // Cron expression for repeated scans; empty scans once
+	ScanDaemon       bool     // Keep scanning on the backend's or the default schedule
+	ScanPorts        string   // Port profiles, ports and ranges probed on online hosts
+	ScanReachPorts   string   // Ports tried on hosts that ignore ping
+	ScanTimeout      int      // Per-probe timeout in milliseconds
+	ScanConcurrency  int      // Hosts probed at once
+	ScanHostConcurrency int   // Ports probed at once on one host
+	ScanRate         int      // Probes per second across a scan, 0 is unlimited
+	ScanHostRate     int      // TCP connects and SNMP requests per second to one host, 0 is unlimited
+	SNMPCommunities  []string // SNMP v2c communities tried during scans
+	SNMPUser         string   // SNMPv3 user tried before the communities
+	SNMPAuthProtocol string
+	SNMPAuthPassword string
+	SNMPPrivProtocol string
+	SNMPPrivPassword string
 }
 
 func Load() *Config {
@@ -20,16 +53,90 @@ func Load() *Config {
 	flag.StringVar(&cfg.APIKey, "key", getEnv("ASSETRONICS_KEY", ""), "Agent API Key") // For future auth
 	flag.StringVar(&cfg.TenantID, "tenant", getEnv("ASSETRONICS_TENANT", ""), "Tenant ID/Slug") 
 	flag.IntVar(&cfg.Interval, "interval", 3600, "Check-in interval in seconds")
-	flag.StringVar(&cfg.ScanRange, "scan", "", "Network Scan CIDR (e.g. 192.168.1.0/24). If set, runs in Scanner mode.")
+	flag.BoolVar(&cfg.LanguagePackages, "lang-packages", getEnvBool("ASSETRONICS_LANG_PACKAGES", false), "Include language ecosystem packages (pip, npm, gem, cargo, go) in the software inventory")
+	flag.StringVar(&cfg.DockerSocket, "docker-socket", getEnv("ASSETRONICS_DOCKER_SOCKET", "/var/run/docker.sock"), "Docker Engine API socket (empty to disable)")
+	flag.StringVar(&cfg.ContainerdSocket, "containerd-socket", getEnv("ASSETRONICS_CONTAINERD_SOCKET", "/run/containerd/containerd.sock"), "containerd API socket (empty to disable)")
+	flag.StringVar(&cfg.CloudMetadataURL, "cloud-metadata-url", getEnv("ASSETRONICS_CLOUD_METADATA_URL", "http://169.254.169.254"), "Cloud instance metadata service base URL (empty to disable cloud detection)")
+	flag.StringVar(&cfg.DataDir, "data-dir", getEnv("ASSETRONICS_DATA_DIR", defaultDataDir()), "Directory for agent state and data files synced from the backend")
+	flag.IntVar(&cfg.MeteringInterval, "metering-interval", getEnvInt("ASSETRONICS_METERING_INTERVAL", 60), "Seconds between software usage samples (0 disables metering)")
+	certPaths := flag.String("cert-paths", getEnv("ASSETRONICS_CERT_PATHS", ""), "Comma-separated certificate files or directories to inventory in addition to the system stores")
+	flag.BoolVar(&cfg.UploadSBOM, "sbom", getEnvBool("ASSETRONICS_SBOM", false), "Upload a CycloneDX SBOM of the installed software after every check-in")
+	flag.StringVar(&cfg.ScanRange, "scan", "", "Comma-separated scan targets: CIDRs, address ranges, addresses and hostnames, IPv4 or IPv6 (e.g. 192.168.1.0/24,10.0.0.5-10.0.0.50). \"auto\" scans the directly attached subnets. If set, runs in Scanner mode.")
+	flag.IntVar(&cfg.ScanAutoPrefix, "scan-auto-prefix", getEnvInt("ASSETRONICS_SCAN_AUTO_PREFIX", 22), "Prefix length of the largest subnet -scan auto scans whole; larger subnets are narrowed to the one around this host's address")
+	flag.BoolVar(&cfg.ScanAutoVirtual, "scan-auto-virtual", getEnvBool("ASSETRONICS_SCAN_AUTO_VIRTUAL", false), "Include Docker, VM bridge and VPN interfaces and ranges in -scan auto")
+	flag.StringVar(&cfg.ScanSchedule, "scan-schedule", getEnv("ASSETRONICS_SCAN_SCHEDULE", ""), "Keep running and repeat scans on this cron schedule (e.g. \"0 2 * * *\", \"@hourly\", \"@every 30m\"); the backend's schedule takes precedence")
+	flag.BoolVar(&cfg.ScanDaemon, "scan-daemon", getEnvBool("ASSETRONICS_SCAN_DAEMON", false), "Keep running and repeat scans on the backend's schedule, or hourly")
+	scanExclude := flag.String("scan-exclude", getEnv("ASSETRONICS_SCAN_EXCLUDE", ""), "Comma-separated addresses, CIDRs, ranges and hostnames never to scan")
+	flag.BoolVar(&cfg.ScanARP, "scan-arp", getEnvBool("ASSETRONICS_SCAN_ARP", false), "Send ARP requests to silent hosts on directly attached subnets and report those that answer")
+	flag.BoolVar(&cfg.ScanMulticast, "scan-multicast", getEnvBool("ASSETRONICS_SCAN_MULTICAST", true), "Discover devices that announce themselves over mDNS/DNS-SD and SSDP/UPnP during scans")
+	flag.BoolVar(&cfg.ScanPJL, "scan-pjl", getEnvBool("ASSETRONICS_SCAN_PJL", false), "Ask every host with an open JetDirect port (9100-9102) for its printer model over PJL, not only hosts that otherwise look like printers")
+	flag.StringVar(&cfg.ScanPorts, "scan-ports", getEnv("ASSETRONICS_SCAN_PORTS", "standard"), "Ports probed on every host found by a scan: profile names (quick, standard, printers, iot, full), ports and ranges, comma-separated")
+	flag.StringVar(&cfg.ScanReachPorts, "scan-reach-ports", getEnv("ASSETRONICS_SCAN_REACH_PORTS", "quick"), "Ports tried on hosts that ignore ping, in the same format as -scan-ports")
+	flag.IntVar(&cfg.ScanTimeout, "scan-timeout", getEnvInt("ASSETRONICS_SCAN_TIMEOUT", 500), "Timeout in milliseconds for each scan probe (TCP connect, SNMP reply)")
+	flag.IntVar(&cfg.ScanConcurrency, "scan-concurrency", getEnvInt("ASSETRONICS_SCAN_CONCURRENCY", 50), "Hosts probed at once during scans")
+	flag.IntVar(&cfg.ScanHostConcurrency, "scan-host-concurrency", getEnvInt("ASSETRONICS_SCAN_HOST_CONCURRENCY", 1), "Ports probed at once on each host during scans")
+	flag.IntVar(&cfg.ScanRate, "scan-rate", getEnvInt("ASSETRONICS_SCAN_RATE", 0), "Probes per second across a scan (0 is unlimited)")
+	flag.IntVar(&cfg.ScanHostRate, "scan-host-rate", getEnvInt("ASSETRONICS_SCAN_HOST_RATE", 0), "TCP connects and SNMP requests per second to each host during scans (0 is unlimited)")
+	snmpCommunities := flag.String("snmp-community", getEnv("ASSETRONICS_SNMP_COMMUNITY", ""), "Comma-separated SNMP v2c communities to try during scans, e.g. \"public\" (empty disables v2c)")
+	flag.StringVar(&cfg.SNMPUser, "snmp-user", getEnv("ASSETRONICS_SNMP_USER", ""), "SNMPv3 user to try during scans before the v2c communities")
+	flag.StringVar(&cfg.SNMPAuthProtocol, "snmp-auth-protocol", getEnv("ASSETRONICS_SNMP_AUTH_PROTOCOL", "SHA"), "SNMPv3 authentication protocol: MD5, SHA, SHA-224, SHA-256, SHA-384 or SHA-512")
+	flag.StringVar(&cfg.SNMPAuthPassword, "snmp-auth-password", getEnv("ASSETRONICS_SNMP_AUTH_PASSWORD", ""), "SNMPv3 authentication password (empty for noAuthNoPriv)")
+	flag.StringVar(&cfg.SNMPPrivProtocol, "snmp-priv-protocol", getEnv("ASSETRONICS_SNMP_PRIV_PROTOCOL", "AES"), "SNMPv3 privacy protocol: DES or AES")
+	flag.StringVar(&cfg.SNMPPrivPassword, "snmp-priv-password", getEnv("ASSETRONICS_SNMP_PRIV_PASSWORD", ""), "SNMPv3 privacy password (empty for authNoPriv)")
 
 	flag.Parse()
 
+	for _, p := range strings.Split(*certPaths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.CertPaths = append(cfg.CertPaths, p)
+		}
+	}
+	for _, x := range strings.Split(*scanExclude, ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			cfg.ScanExclude = append(cfg.ScanExclude, x)
+		}
+	}
+	for _, c := range strings.Split(*snmpCommunities, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cfg.SNMPCommunities = append(cfg.SNMPCommunities, c)
+		}
+	}
+
 	return cfg
 }
 
+func defaultDataDir() string {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(getEnv("ProgramData", `C:\ProgramData`), "Assetronics")
+	case "darwin":
+		return "/Library/Application Support/Assetronics"
+	default:
+		return "/var/lib/assetronics"
+	}
+}
+
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"assetronics-agent/api"
+	"assetronics-agent/collector"
+	"assetronics-agent/sbom"
+	"assetronics-agent/vuln"
+)
+
+// runInventory implements the "inventory" command: collect once and write
+// the result locally instead of checking in.
+//
+//	assetronics-agent inventory --format cyclonedx --output sbom.json
+func runInventory(args []string, c collector.Collector, client *api.Client) error {
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+	format := fs.String("format", "json", "Output format: json (agent check-in payload) or cyclonedx (CycloneDX 1.5 SBOM)")
+	output := fs.String("output", "", "Write to this file instead of stdout")
+	upload := fs.Bool("upload", false, "Also upload the CycloneDX SBOM to the backend")
+	fs.Parse(args)
+
+	if *format != "json" && *format != "cyclonedx" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	info, err := c.Collect()
+	if err != nil {
+		return fmt.Errorf("collection failed: %w", err)
+	}
+	info.Vulnerabilities = vuln.New(client.Config.DataDir).Scan(info)
+
+	var document []byte
+	if *format == "cyclonedx" || *upload {
+		if document, err = json.MarshalIndent(sbom.Build(info), "", "  "); err != nil {
+			return err
+		}
+	}
+	if *upload {
+		if err := client.SendSBOM(document); err != nil {
+			return err
+		}
+	}
+	if *format == "json" {
+		if document, err = json.MarshalIndent(info, "", "  "); err != nil {
+			return err
+		}
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(append(document, '\n'))
+		return err
+	}
+	return os.WriteFile(*output, append(document, '\n'), 0644)
+}
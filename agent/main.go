@@ -1,17 +1,23 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"assetronics-agent/api"
 	"assetronics-agent/collector"
 	"assetronics-agent/config"
+	"assetronics-agent/metering"
+	"assetronics-agent/sbom"
 	"assetronics-agent/scanner"
+	"assetronics-agent/vuln"
 )
 
 func main() {
@@ -19,7 +25,20 @@ func main() {
 	apiClient := api.New(cfg)
 
 	// Determine platform-specific collector
-	sysCollector := collector.New()
+	sysCollector := collector.New(collector.Options{
+		LanguagePackages: cfg.LanguagePackages,
+		DockerSocket:     cfg.DockerSocket,
+		ContainerdSocket: cfg.ContainerdSocket,
+		CloudMetadataURL: cfg.CloudMetadataURL,
+		CertPaths:        cfg.CertPaths,
+	})
+
+	if flag.Arg(0) == "inventory" {
+		if err := runInventory(flag.Args()[1:], sysCollector, apiClient); err != nil {
+			log.Fatalf("Inventory failed: %v", err)
+		}
+		return
+	}
 
 	if cfg.TenantID == "" {
 		log.Fatal("Error: Tenant ID is required. Please provide it via -tenant flag or ASSETRONICS_TENANT env var.")
@@ -34,24 +53,24 @@ func main() {
 		log.Printf("Mode: Network Scanner")
 		log.Printf("Target Range: %s", cfg.ScanRange)
 		
-		results, err := scanner.Scan(cfg.ScanRange)
-		if err != nil {
-			log.Fatalf("Scan failed: %v", err)
-		}
-		log.Printf("Scan complete. Found %d devices.", len(results.Devices))
-		
-		if err := apiClient.SendScanResults(results); err != nil {
-			log.Fatalf("Failed to upload scan results: %v", err)
-		}
-		log.Printf("Results uploaded successfully.")
+		runScanner(cfg, apiClient)
 		return
 	}
 
 	log.Printf("Mode: Endpoint Agent")
 	log.Printf("Check-in Interval: %d seconds", cfg.Interval)
 
+	// Software usage metering samples in the background between check-ins
+	var meter *metering.Meter
+	stopMeter := make(chan struct{})
+	if cfg.MeteringInterval > 0 {
+		meter = metering.New(cfg.DataDir, time.Duration(cfg.MeteringInterval)*time.Second)
+		go meter.Run(stopMeter)
+	}
+	vulns := vuln.New(cfg.DataDir)
+
 	// Perform initial check-in
-	if err := performCheckIn(sysCollector, apiClient); err != nil {
+	if err := performCheckIn(sysCollector, apiClient, meter, vulns); err != nil {
 		log.Printf("Error during initial check-in: %v", err)
 	} else {
 		log.Printf("Initial check-in successful")
@@ -65,31 +84,111 @@ func main() {
 	for {
 		select {
 		case <-ticker.C:
-			if err := performCheckIn(sysCollector, apiClient); err != nil {
+			if err := performCheckIn(sysCollector, apiClient, meter, vulns); err != nil {
 				log.Printf("Error during check-in: %v", err)
 			} else {
 				log.Printf("Check-in successful")
 			}
 		case <-quit:
 			ticker.Stop()
+			close(stopMeter)
 			log.Println("Agent stopping...")
 			return
 		}
 	}
 }
 
-func performCheckIn(c collector.Collector, client *api.Client) error {
+// autoTargets lists the directly attached subnets for "-scan auto", one
+// scan and upload each.
+func autoTargets(cfg *config.Config) []string {
+	subnets := scanner.AutoSubnets(scanner.AutoOptions{MaxPrefix: cfg.ScanAutoPrefix, Virtual: cfg.ScanAutoVirtual})
+	var targets []string
+	for _, subnet := range subnets {
+		targets = append(targets, subnet.String())
+	}
+	log.Printf("Discovered subnets: %s", strings.Join(targets, ", "))
+	return targets
+}
+
+// scanOptions builds the scanner settings from the configuration.
+func scanOptions(cfg *config.Config) (scanner.Options, error) {
+	ports, err := scanner.ParsePorts(cfg.ScanPorts)
+	if err != nil {
+		return scanner.Options{}, fmt.Errorf("-scan-ports: %v", err)
+	}
+	reachPorts, err := scanner.ParsePorts(cfg.ScanReachPorts)
+	if err != nil {
+		return scanner.Options{}, fmt.Errorf("-scan-reach-ports: %v", err)
+	}
+	return scanner.Options{
+		ActiveARP:       cfg.ScanARP,
+		SNMP:            snmpCredentials(cfg),
+		Multicast:       cfg.ScanMulticast,
+		PJL:             cfg.ScanPJL,
+		Exclude:         cfg.ScanExclude,
+		Ports:           ports,
+		ReachPorts:      reachPorts,
+		Timeout:         time.Duration(cfg.ScanTimeout) * time.Millisecond,
+		Concurrency:     cfg.ScanConcurrency,
+		HostConcurrency: cfg.ScanHostConcurrency,
+		Rate:            cfg.ScanRate,
+		HostRate:        cfg.ScanHostRate,
+	}, nil
+}
+
+// snmpCredentials lists the configured SNMP credentials, v3 first.
+func snmpCredentials(cfg *config.Config) []scanner.SNMPCredentials {
+	var creds []scanner.SNMPCredentials
+	if cfg.SNMPUser != "" {
+		creds = append(creds, scanner.SNMPCredentials{
+			User:         cfg.SNMPUser,
+			AuthProtocol: cfg.SNMPAuthProtocol,
+			AuthPassword: cfg.SNMPAuthPassword,
+			PrivProtocol: cfg.SNMPPrivProtocol,
+			PrivPassword: cfg.SNMPPrivPassword,
+		})
+	}
+	for _, community := range cfg.SNMPCommunities {
+		creds = append(creds, scanner.SNMPCredentials{Community: community})
+	}
+	return creds
+}
+
+func performCheckIn(c collector.Collector, client *api.Client, meter *metering.Meter, vulns *vuln.Scanner) error {
 	info, err := c.Collect()
 	if err != nil {
 		return fmt.Errorf("collection failed: %w", err)
 	}
+	if meter != nil {
+		info.SoftwareUsage = meter.Usage()
+	}
+	info.Vulnerabilities = vulns.Scan(info)
 
 	// Log what we found (debug)
 	// log.Printf("Collected: %+v", info)
 
-	if err := client.CheckIn(info); err != nil {
+	resp, err := client.CheckIn(info)
+	if err != nil {
 		return fmt.Errorf("api check-in failed: %w", err)
 	}
 
+	// The check-in has landed, so apply it before the SBOM upload can fail
+	if meter != nil {
+		meter.Acknowledge(info.SoftwareUsage)
+		if resp.MeteredApps != nil {
+			meter.SetApps(resp.MeteredApps)
+		}
+	}
+
+	if client.Config.UploadSBOM {
+		document, err := json.Marshal(sbom.Build(info))
+		if err != nil {
+			return fmt.Errorf("failed to build SBOM: %w", err)
+		}
+		if err := client.SendSBOM(document); err != nil {
+			return fmt.Errorf("SBOM upload failed: %w", err)
+		}
+	}
+
 	return nil
 }
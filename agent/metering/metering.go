@@ -0,0 +1,223 @@
+// Package metering samples running processes and accumulates daily usage
+// of the applications the backend asks to meter, so that unused licenses
+// can be reclaimed.
+package metering
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"assetronics-agent/collector"
+)
+
+const stateFile = "metering.json"
+
+// App is a metered application as supplied by the backend. Executables are
+// matched case-insensitively against process image names without path or
+// ".exe", e.g. "winword", "Microsoft Word", "photoshop".
+type App struct {
+	Name        string   `json:"name"`
+	Executables []string `json:"executables"`
+}
+
+type process struct {
+	pid  int
+	name string // Image name without directory
+}
+
+// state is what the meter persists between samples and restarts.
+type state struct {
+	Apps      []App                               `json:"apps"`
+	Usage     map[string]*collector.SoftwareUsage `json:"usage"`             // Keyed by app + "|" + date
+	Running   []string                            `json:"running,omitempty"` // Apps seen in the last sample
+	SampledAt time.Time                           `json:"sampled_at,omitzero"`
+}
+
+type Meter struct {
+	mu       sync.Mutex
+	path     string
+	interval time.Duration
+	state    state
+	// Apps seen in the previous sample, to count launches; nil until the
+	// first sample, which cannot tell launches from apps already running
+	running map[string]bool
+
+	listProcesses func() ([]process, error)
+	foregroundPID func() int // 0 when unknown
+}
+
+// New loads any saved state from dataDir. Sampling starts with Run.
+func New(dataDir string, interval time.Duration) *Meter {
+	m := &Meter{
+		path:          filepath.Join(dataDir, stateFile),
+		interval:      interval,
+		state:         state{Usage: map[string]*collector.SoftwareUsage{}},
+		listProcesses: listProcesses,
+		foregroundPID: foregroundPID,
+	}
+	if content, err := os.ReadFile(m.path); err == nil {
+		if err := json.Unmarshal(content, &m.state); err != nil {
+			fmt.Printf("Warning: ignoring corrupt metering state %s: %v\n", m.path, err)
+		}
+		if m.state.Usage == nil {
+			m.state.Usage = map[string]*collector.SoftwareUsage{}
+		}
+	}
+	// After a quick restart the apps from the last sample are still the same
+	// processes, so carry them over rather than count them as launched
+	if time.Since(m.state.SampledAt) <= 2*interval {
+		m.running = map[string]bool{}
+		for _, app := range m.state.Running {
+			m.running[app] = true
+		}
+	}
+	return m
+}
+
+// Run samples processes every interval until stop is closed.
+func (m *Meter) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.sample(time.Now())
+		case <-stop:
+			return
+		}
+	}
+}
+
+// SetApps replaces the metered application list.
+func (m *Meter) SetApps(apps []App) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.Apps = apps
+	m.save()
+}
+
+// Usage returns a snapshot of the accumulated daily usage, oldest first.
+func (m *Meter) Usage() []collector.SoftwareUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := make([]collector.SoftwareUsage, 0, len(m.state.Usage))
+	for _, u := range m.state.Usage {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Date != usage[j].Date {
+			return usage[i].Date < usage[j].Date
+		}
+		return usage[i].App < usage[j].App
+	})
+	return usage
+}
+
+// Acknowledge drops days the backend has received. Today keeps
+// accumulating and is re-sent with every check-in, as are days that
+// changed after the snapshot was taken.
+func (m *Meter) Acknowledge(sent []collector.SoftwareUsage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	today := time.Now().Format("2006-01-02")
+	for _, s := range sent {
+		key := s.App + "|" + s.Date
+		if u, ok := m.state.Usage[key]; ok && s.Date < today && *u == s {
+			delete(m.state.Usage, key)
+		}
+	}
+	m.save()
+}
+
+func (m *Meter) sample(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.state.Apps) == 0 {
+		return
+	}
+
+	processes, err := m.listProcesses()
+	if err != nil {
+		fmt.Printf("Warning: metering could not list processes: %v\n", err)
+		return
+	}
+	foreground := m.foregroundPID()
+
+	// executable -> app names
+	lookup := map[string][]string{}
+	for _, app := range m.state.Apps {
+		for _, exe := range app.Executables {
+			exe = normalizeExecutable(exe)
+			lookup[exe] = append(lookup[exe], app.Name)
+		}
+	}
+
+	running := map[string]bool{}
+	focused := map[string]bool{}
+	for _, p := range processes {
+		for _, app := range lookup[normalizeExecutable(p.name)] {
+			running[app] = true
+			if foreground != 0 && p.pid == foreground {
+				focused[app] = true
+			}
+		}
+	}
+
+	date := now.Format("2006-01-02")
+	seconds := int64(m.interval / time.Second)
+	for app := range running {
+		key := app + "|" + date
+		u, ok := m.state.Usage[key]
+		if !ok {
+			u = &collector.SoftwareUsage{App: app, Date: date}
+			m.state.Usage[key] = u
+		}
+		u.RunSeconds += seconds
+		if focused[app] {
+			u.ForegroundSeconds += seconds
+		}
+		if m.running != nil && !m.running[app] {
+			u.Launches++
+		}
+		u.LastUsed = now.UTC().Format(time.RFC3339)
+	}
+	m.running = running
+	m.state.Running = m.state.Running[:0]
+	for app := range running {
+		m.state.Running = append(m.state.Running, app)
+	}
+	sort.Strings(m.state.Running)
+	m.state.SampledAt = now
+	m.save()
+}
+
+// save writes the state atomically. The caller holds m.mu.
+func (m *Meter) save() {
+	content, err := json.Marshal(m.state)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		fmt.Printf("Warning: could not create %s: %v\n", filepath.Dir(m.path), err)
+		return
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		fmt.Printf("Warning: could not save metering state: %v\n", err)
+		return
+	}
+	os.Rename(tmp, m.path)
+}
+
+func normalizeExecutable(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.TrimSuffix(name, ".exe")
+}
@@ -0,0 +1,175 @@
+package metering
+
+import (
+	"testing"
+	"time"
+
+	"assetronics-agent/collector"
+)
+
+// fakeMeter returns a meter in dir whose process list is whatever *procs
+// holds at sample time.
+func fakeMeter(dir string, procs *[]process, foreground *int) *Meter {
+	m := New(dir, time.Minute)
+	m.listProcesses = func() ([]process, error) { return *procs, nil }
+	m.foregroundPID = func() int { return *foreground }
+	return m
+}
+
+var testApps = []App{
+	{Name: "Microsoft Word", Executables: []string{"WINWORD.EXE", "Microsoft Word"}},
+	{Name: "Photoshop", Executables: []string{"photoshop"}},
+}
+
+func usageFor(t *testing.T, m *Meter, app, date string) collector.SoftwareUsage {
+	t.Helper()
+	for _, u := range m.Usage() {
+		if u.App == app && u.Date == date {
+			return u
+		}
+	}
+	t.Fatalf("no usage for %s on %s in %+v", app, date, m.Usage())
+	return collector.SoftwareUsage{}
+}
+
+func TestSampleCountsLaunches(t *testing.T) {
+	var procs []process
+	foreground := 0
+	m := fakeMeter(t.TempDir(), &procs, &foreground)
+	m.SetApps(testApps)
+
+	start := time.Date(2024, 6, 3, 9, 0, 0, 0, time.Local)
+	word := process{pid: 100, name: "winword.exe"}
+	steps := []struct {
+		procs      []process
+		foreground int
+	}{
+		{[]process{word}, 0},   // Already running at the first sample: not a launch
+		{[]process{word}, 100}, // Still running, now focused
+		{nil, 0},               // Closed
+		{[]process{{pid: 200, name: "WINWORD.EXE"}}, 0}, // Launched again
+		{[]process{{pid: 300, name: "bash"}}, 300},
+	}
+	for i, step := range steps {
+		procs, foreground = step.procs, step.foreground
+		m.sample(start.Add(time.Duration(i) * time.Minute))
+	}
+
+	u := usageFor(t, m, "Microsoft Word", "2024-06-03")
+	if u.RunSeconds != 180 || u.ForegroundSeconds != 60 || u.Launches != 1 {
+		t.Errorf("usage = %+v, want 180s run, 60s foreground, 1 launch", u)
+	}
+	if want := start.Add(3 * time.Minute).UTC().Format(time.RFC3339); u.LastUsed != want {
+		t.Errorf("LastUsed = %s, want %s", u.LastUsed, want)
+	}
+	if len(m.Usage()) != 1 {
+		t.Errorf("usage for apps that never ran: %+v", m.Usage())
+	}
+}
+
+func TestSampleWithoutApps(t *testing.T) {
+	listed := false
+	m := New(t.TempDir(), time.Minute)
+	m.listProcesses = func() ([]process, error) { listed = true; return nil, nil }
+	m.sample(time.Now())
+	if listed || len(m.Usage()) != 0 {
+		t.Error("sampled with nothing to meter")
+	}
+}
+
+func TestRestartCarriesRunningApps(t *testing.T) {
+	dir := t.TempDir()
+	procs := []process{{pid: 100, name: "photoshop"}}
+	foreground := 0
+	m := fakeMeter(dir, &procs, &foreground)
+	m.SetApps(testApps)
+	now := time.Now()
+	m.sample(now.Add(-time.Minute))
+
+	// A quick restart: Photoshop is the same process, not a new launch,
+	// and the app list survives
+	restarted := fakeMeter(dir, &procs, &foreground)
+	restarted.sample(now)
+	today := now.Format("2006-01-02")
+	if u := usageFor(t, restarted, "Photoshop", today); u.Launches != 0 || u.RunSeconds != 120 {
+		t.Errorf("after a quick restart: %+v, want 120s and no launch", u)
+	}
+
+	// The agent was down long enough that Photoshop may have been closed
+	// and reopened; the first sample can't tell, so it counts nothing
+	stale := fakeMeter(dir, &procs, &foreground)
+	stale.state.SampledAt = now.Add(-time.Hour)
+	stale.save()
+	stale = fakeMeter(dir, &procs, &foreground)
+	if stale.running != nil {
+		t.Fatalf("running carried over after an hour: %v", stale.running)
+	}
+	procs = nil
+	stale.sample(now)
+	procs = []process{{pid: 101, name: "photoshop"}}
+	stale.sample(now.Add(time.Minute))
+	if u := usageFor(t, stale, "Photoshop", today); u.Launches != 1 {
+		t.Errorf("launch after a long restart: %+v", u)
+	}
+}
+
+func TestSampleRollsOverAtMidnight(t *testing.T) {
+	procs := []process{{pid: 100, name: "Microsoft Word"}}
+	foreground := 100
+	m := fakeMeter(t.TempDir(), &procs, &foreground)
+	m.SetApps(testApps)
+
+	m.sample(time.Date(2024, 6, 3, 23, 58, 30, 0, time.Local))
+	m.sample(time.Date(2024, 6, 3, 23, 59, 30, 0, time.Local))
+	m.sample(time.Date(2024, 6, 4, 0, 0, 30, 0, time.Local))
+
+	if u := usageFor(t, m, "Microsoft Word", "2024-06-03"); u.RunSeconds != 120 || u.ForegroundSeconds != 120 {
+		t.Errorf("first day = %+v", u)
+	}
+	if u := usageFor(t, m, "Microsoft Word", "2024-06-04"); u.RunSeconds != 60 || u.Launches != 0 {
+		t.Errorf("second day = %+v; still running is not a launch", u)
+	}
+}
+
+func TestAcknowledge(t *testing.T) {
+	dir := t.TempDir()
+	procs := []process{{pid: 100, name: "photoshop"}, {pid: 101, name: "winword"}}
+	foreground := 0
+	m := fakeMeter(dir, &procs, &foreground)
+	m.SetApps(testApps)
+
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	m.sample(yesterday)
+	m.sample(now)
+	sent := m.Usage()
+	if len(sent) != 4 {
+		t.Fatalf("usage = %+v, want two apps on two days", sent)
+	}
+	// Word usage for yesterday changes after the snapshot is taken, e.g.
+	// a late sample; the backend hasn't seen that
+	m.state.Usage["Microsoft Word|"+yesterday.Format("2006-01-02")].RunSeconds += 60
+
+	m.Acknowledge(sent)
+	var left []string
+	for _, u := range m.Usage() {
+		left = append(left, u.App+" "+u.Date)
+	}
+	day, today := yesterday.Format("2006-01-02"), now.Format("2006-01-02")
+	want := []string{"Microsoft Word " + day, "Microsoft Word " + today, "Photoshop " + today}
+	if len(left) != len(want) {
+		t.Fatalf("after Acknowledge: %q, want %q", left, want)
+	}
+	for i := range want {
+		if left[i] != want[i] {
+			t.Errorf("after Acknowledge: %q, want %q", left, want)
+			break
+		}
+	}
+
+	// Acknowledged days stay gone across a restart
+	reloaded := New(dir, time.Minute)
+	if len(reloaded.Usage()) != 3 {
+		t.Errorf("reloaded usage = %+v", reloaded.Usage())
+	}
+}
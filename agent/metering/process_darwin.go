@@ -0,0 +1,54 @@
+//go:build darwin
+
+package metering
+
+import (
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// listProcesses uses ps, whose comm column is the full executable path,
+// e.g. /Applications/Microsoft Word.app/Contents/MacOS/Microsoft Word.
+func listProcesses() ([]process, error) {
+	out, err := exec.Command("ps", "-axo", "pid=,comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []process
+	for _, line := range strings.Split(string(out), "\n") {
+		pidField, comm, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(pidField)
+		if err != nil {
+			continue
+		}
+		processes = append(processes, process{pid: pid, name: filepath.Base(strings.TrimSpace(comm))})
+	}
+	return processes, nil
+}
+
+var lsappinfoPIDRe = regexp.MustCompile(`"pid"\s*=\s*(\d+)`)
+
+// foregroundPID asks LaunchServices for the frontmost application.
+func foregroundPID() int {
+	asn, err := exec.Command("lsappinfo", "front").Output()
+	if err != nil || len(strings.TrimSpace(string(asn))) == 0 {
+		return 0
+	}
+	// "pid"=1234
+	out, err := exec.Command("lsappinfo", "info", "-only", "pid", strings.TrimSpace(string(asn))).Output()
+	if err != nil {
+		return 0
+	}
+	if m := lsappinfoPIDRe.FindSubmatch(out); m != nil {
+		pid, _ := strconv.Atoi(string(m[1]))
+		return pid
+	}
+	return 0
+}
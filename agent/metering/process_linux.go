@@ -0,0 +1,47 @@
+//go:build linux
+
+package metering
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// listProcesses reads /proc. The image name comes from the exe link, which
+// is only readable for other users' processes when running as root; argv[0]
+// is the fallback.
+func listProcesses() ([]process, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		name := ""
+		if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+			// The binary was replaced, typically by an upgrade, while running
+			name = filepath.Base(strings.TrimSuffix(exe, " (deleted)"))
+		} else if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+			name = filepath.Base(string(bytes.SplitN(cmdline, []byte{0}, 2)[0]))
+		}
+		if name != "" {
+			processes = append(processes, process{pid: pid, name: name})
+		}
+	}
+	return processes, nil
+}
+
+// foregroundPID is not available on Linux: there is no display-server
+// independent way for a system service to see the focused window.
+func foregroundPID() int {
+	return 0
+}
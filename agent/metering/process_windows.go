@@ -0,0 +1,55 @@
+//go:build windows
+
+package metering
+
+import (
+	"encoding/csv"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// listProcesses uses tasklist.
+func listProcesses() ([]process, error) {
+	out, err := exec.Command("tasklist", "/fo", "csv", "/nh").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// "WINWORD.EXE","5124","Console","1","215,400 K"
+	records, err := csv.NewReader(strings.NewReader(strings.ReplaceAll(string(out), "\r", ""))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var processes []process
+	for _, r := range records {
+		if len(r) < 2 {
+			continue
+		}
+		if pid, err := strconv.Atoi(r[1]); err == nil {
+			processes = append(processes, process{pid: pid, name: r[0]})
+		}
+	}
+	return processes, nil
+}
+
+var (
+	user32                       = syscall.NewLazyDLL("user32.dll")
+	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
+	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+)
+
+// foregroundPID returns the process owning the foreground window. A
+// service in session 0 has no desktop and always gets 0 here; foreground
+// time is only metered when the agent runs in the user's session.
+func foregroundPID() int {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	if hwnd == 0 {
+		return 0
+	}
+	var pid uint32
+	procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	return int(pid)
+}
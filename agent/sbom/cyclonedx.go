@@ -0,0 +1,260 @@
+// Package sbom renders the collected software inventory as a CycloneDX
+// software bill of materials.
+package sbom
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"assetronics-agent/collector"
+)
+
+// MediaType is the content type for CycloneDX JSON documents.
+const MediaType = "application/vnd.cyclonedx+json"
+
+const specVersion = "1.5"
+
+// BOM is the subset of the CycloneDX 1.5 JSON schema the agent emits.
+type BOM struct {
+	BOMFormat    string      `json:"bomFormat"`
+	SpecVersion  string      `json:"specVersion"`
+	SerialNumber string      `json:"serialNumber"`
+	Version      int         `json:"version"`
+	Metadata     Metadata    `json:"metadata"`
+	Components   []Component `json:"components"`
+}
+
+type Metadata struct {
+	Timestamp string    `json:"timestamp"`
+	Tools     Tools     `json:"tools"`
+	Component Component `json:"component"` // The machine the inventory describes
+}
+
+type Tools struct {
+	Components []Component `json:"components"`
+}
+
+type Component struct {
+	BOMRef      string        `json:"bom-ref,omitempty"`
+	Type        string        `json:"type"` // "application", "library", "operating-system", "device"
+	Name        string        `json:"name"`
+	Version     string        `json:"version,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Supplier    *Organization `json:"supplier,omitempty"`
+	PURL        string        `json:"purl,omitempty"`
+	Properties  []Property    `json:"properties,omitempty"`
+}
+
+type Organization struct {
+	Name string `json:"name"`
+}
+
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Property names use the agent's own namespace, as CycloneDX recommends
+// for fields the schema has no slot for.
+const propertyPrefix = "assetronics:"
+
+// Build converts a collected SystemInfo into a CycloneDX BOM. Every
+// installed software entry becomes a component; the host itself is the
+// metadata component.
+func Build(info *collector.SystemInfo) *BOM {
+	bom := &BOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  specVersion,
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: Tools{Components: []Component{
+				{Type: "application", Name: "assetronics-agent", Supplier: &Organization{Name: "Assetronics"}},
+			}},
+			Component: Component{
+				BOMRef: "host",
+				Type:   "device",
+				Name:   info.Hostname,
+				Properties: properties(
+					"serial_number", info.SerialNumber,
+					"os", info.OS,
+					"make", info.Make,
+					"model", info.Model,
+				),
+			},
+		},
+		Components: []Component{},
+	}
+
+	refs := map[string]int{}
+	for _, sw := range info.InstalledSoftware {
+		c := Component{
+			Type:        componentType(sw.Source),
+			Name:        sw.Name,
+			Version:     sw.Version,
+			Description: sw.Description,
+			PURL:        PackageURL(sw, info.DistroID, info.DistroVersion),
+			Properties: properties(
+				"source", sw.Source,
+				"install_date", sw.InstallDate,
+				"install_path", sw.InstallPath,
+				"architecture", sw.Architecture,
+				"size_bytes", sizeString(sw.SizeBytes),
+			),
+		}
+		if sw.Vendor != "" {
+			c.Supplier = &Organization{Name: sw.Vendor}
+		}
+
+		// bom-refs must be unique; the same package can be installed for
+		// several users or architectures
+		ref := c.PURL
+		if ref == "" {
+			ref = sw.Name + "@" + sw.Version
+		}
+		refs[ref]++
+		if n := refs[ref]; n > 1 {
+			ref += "#" + strconv.Itoa(n)
+		}
+		c.BOMRef = ref
+
+		bom.Components = append(bom.Components, c)
+	}
+	return bom
+}
+
+// componentType treats language ecosystem packages as libraries and
+// everything the OS installs as applications.
+func componentType(source string) string {
+	switch source {
+	case collector.SourcePyPI, collector.SourceNpm, collector.SourceGem, collector.SourceCargo, collector.SourceGo:
+		return "library"
+	default:
+		return "application"
+	}
+}
+
+// PackageURL derives a purl (https://github.com/package-url/purl-spec)
+// from the package manager a Software entry came from. Software without a
+// package manager (macOS apps, Windows installers) has no purl.
+func PackageURL(sw collector.Software, distroID, distroVersion string) string {
+	if sw.Name == "" {
+		return ""
+	}
+
+	var purlType, namespace, name string
+	version := sw.Version
+	qualifiers := url.Values{}
+	if sw.Architecture != "" {
+		qualifiers.Set("arch", sw.Architecture)
+	}
+	// Without an os-release ID there is neither namespace nor distro
+	setDistro := func() {
+		if distroID == "" {
+			return
+		}
+		distro := distroID
+		if distroVersion != "" {
+			distro += "-" + distroVersion
+		}
+		qualifiers.Set("distro", distro)
+	}
+
+	switch sw.Source {
+	case collector.SourceDpkg:
+		purlType, namespace, name = "deb", distroID, sw.Name
+		setDistro()
+	case collector.SourceRPM:
+		purlType, namespace, name = "rpm", distroID, sw.Name
+		setDistro()
+		// The rpm purl type carries the epoch as a qualifier
+		if epoch, rest, ok := strings.Cut(version, ":"); ok {
+			version = rest
+			qualifiers.Set("epoch", epoch)
+		}
+	case collector.SourceApk:
+		purlType, namespace, name = "apk", distroID, sw.Name
+		setDistro()
+	case collector.SourcePacman:
+		purlType, namespace, name = "alpm", distroID, sw.Name
+	case collector.SourcePyPI:
+		// PyPI names are case-insensitive and treat _ as -
+		purlType, name = "pypi", strings.ReplaceAll(strings.ToLower(sw.Name), "_", "-")
+	case collector.SourceNpm:
+		purlType, name = "npm", sw.Name
+		if scope, pkg, ok := strings.Cut(sw.Name, "/"); ok && strings.HasPrefix(scope, "@") {
+			namespace, name = scope, pkg
+		}
+	case collector.SourceGem:
+		purlType, name = "gem", sw.Name
+	case collector.SourceCargo:
+		purlType, name = "cargo", sw.Name
+	case collector.SourceGo:
+		// The module path splits into namespace and name at the last slash
+		purlType, name = "golang", sw.Name
+		if i := strings.LastIndex(sw.Name, "/"); i >= 0 {
+			namespace, name = sw.Name[:i], sw.Name[i+1:]
+		}
+	case collector.SourceSnap, collector.SourceFlatpak, collector.SourceNix:
+		// No registered purl types; generic keeps them addressable
+		purlType, name = "generic", sw.Name
+		qualifiers = url.Values{"package_manager": {sw.Source}}
+	default:
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("pkg:" + purlType + "/")
+	if namespace != "" {
+		for _, seg := range strings.Split(namespace, "/") {
+			b.WriteString(purlEscape(seg) + "/")
+		}
+	}
+	b.WriteString(purlEscape(name))
+	if version != "" {
+		b.WriteString("@" + purlEscape(version))
+	}
+	if len(qualifiers) > 0 {
+		// Encode sorts keys, as the spec requires
+		b.WriteString("?" + qualifiers.Encode())
+	}
+	return b.String()
+}
+
+// purlEscape percent-encodes a purl segment. PathEscape leaves ':' and '@'
+// alone, but purl requires '@' (npm scopes, versions) to be encoded.
+func purlEscape(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "@", "%40")
+}
+
+// properties builds name/value pairs, skipping empty values.
+func properties(kv ...string) []Property {
+	var props []Property
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			props = append(props, Property{Name: propertyPrefix + kv[i], Value: kv[i+1]})
+		}
+	}
+	return props
+}
+
+func sizeString(n int64) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,58 @@
+package sbom
+
+import (
+	"testing"
+
+	"assetronics-agent/collector"
+)
+
+func TestPackageURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		sw            collector.Software
+		distroID      string
+		distroVersion string
+		want          string
+	}{
+		{"deb", collector.Software{Name: "openssl", Version: "3.0.11-1~deb12u2", Source: collector.SourceDpkg, Architecture: "amd64"},
+			"debian", "12", "pkg:deb/debian/openssl@3.0.11-1~deb12u2?arch=amd64&distro=debian-12"},
+		{"deb epoch", collector.Software{Name: "bind9-libs", Version: "1:9.18.19-1~deb12u1", Source: collector.SourceDpkg},
+			"debian", "12", "pkg:deb/debian/bind9-libs@1:9.18.19-1~deb12u1?distro=debian-12"},
+		{"rpm", collector.Software{Name: "bash", Version: "5.2.26-3.fc40", Source: collector.SourceRPM, Architecture: "x86_64"},
+			"fedora", "40", "pkg:rpm/fedora/bash@5.2.26-3.fc40?arch=x86_64&distro=fedora-40"},
+		{"rpm epoch", collector.Software{Name: "openssl", Version: "1:3.0.7-27.el9", Source: collector.SourceRPM, Architecture: "x86_64"},
+			"rhel", "9.4", "pkg:rpm/rhel/openssl@3.0.7-27.el9?arch=x86_64&distro=rhel-9.4&epoch=1"},
+		{"rpm without os-release", collector.Software{Name: "bash", Version: "5.1.8-6.el9", Source: collector.SourceRPM},
+			"", "", "pkg:rpm/bash@5.1.8-6.el9"},
+		{"apk", collector.Software{Name: "musl", Version: "1.2.4-r2", Source: collector.SourceApk, Architecture: "x86_64"},
+			"alpine", "3.19.1", "pkg:apk/alpine/musl@1.2.4-r2?arch=x86_64&distro=alpine-3.19.1"},
+		{"alpm", collector.Software{Name: "pacman", Version: "6.0.2-9", Source: collector.SourcePacman, Architecture: "x86_64"},
+			"arch", "", "pkg:alpm/arch/pacman@6.0.2-9?arch=x86_64"},
+		{"npm", collector.Software{Name: "lodash", Version: "4.17.21", Source: collector.SourceNpm},
+			"", "", "pkg:npm/lodash@4.17.21"},
+		{"npm scope", collector.Software{Name: "@babel/core", Version: "7.24.0", Source: collector.SourceNpm},
+			"", "", "pkg:npm/%40babel/core@7.24.0"},
+		{"pypi", collector.Software{Name: "Django_REST_framework", Version: "3.15.1", Source: collector.SourcePyPI},
+			"", "", "pkg:pypi/django-rest-framework@3.15.1"},
+		{"golang", collector.Software{Name: "github.com/spf13/cobra", Version: "v1.8.0", Source: collector.SourceGo},
+			"", "", "pkg:golang/github.com/spf13/cobra@v1.8.0"},
+		{"golang single segment", collector.Software{Name: "rsc.io", Version: "v1.0.0", Source: collector.SourceGo},
+			"", "", "pkg:golang/rsc.io@v1.0.0"},
+		{"gem", collector.Software{Name: "rails", Version: "7.1.3", Source: collector.SourceGem},
+			"", "", "pkg:gem/rails@7.1.3"},
+		{"cargo", collector.Software{Name: "serde", Version: "1.0.197", Source: collector.SourceCargo},
+			"", "", "pkg:cargo/serde@1.0.197"},
+		{"generic", collector.Software{Name: "firefox", Version: "125.0.2", Source: collector.SourceSnap, Architecture: "amd64"},
+			"ubuntu", "24.04", "pkg:generic/firefox@125.0.2?package_manager=snap"},
+		{"escaping", collector.Software{Name: "my pkg", Version: "1.0+b1", Source: collector.SourceFlatpak},
+			"", "", "pkg:generic/my%20pkg@1.0+b1?package_manager=flatpak"},
+		{"no package manager", collector.Software{Name: "Slack", Version: "4.36", Source: "macos_app"},
+			"", "", ""},
+		{"no name", collector.Software{Version: "1.0", Source: collector.SourceDpkg}, "debian", "12", ""},
+	}
+	for _, tt := range tests {
+		if got := PackageURL(tt.sw, tt.distroID, tt.distroVersion); got != tt.want {
+			t.Errorf("%s: PackageURL = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"assetronics-agent/api"
+	"assetronics-agent/config"
+	"assetronics-agent/scanner"
+	"assetronics-agent/schedule"
+)
+
+const (
+	scanStateFile       = "scans.json"
+	defaultScanSchedule = "@hourly"
+)
+
+// scanState is what scanner mode keeps between runs: the last uploaded
+// result of each target, to diff the next scan against, and the schedule
+// the backend asked for.
+type scanState struct {
+	Schedule string                         `json:"schedule,omitempty"`
+	Results  map[string]*scanner.ScanResult `json:"results"` // Keyed by target
+}
+
+func loadScanState(path string) *scanState {
+	state := &scanState{}
+	if content, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(content, state); err != nil {
+			fmt.Printf("Warning: ignoring corrupt scan state %s: %v\n", path, err)
+			state = &scanState{}
+		}
+	}
+	if state.Results == nil {
+		state.Results = map[string]*scanner.ScanResult{}
+	}
+	return state
+}
+
+// save writes the state atomically.
+func (s *scanState) save(path string) {
+	content, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Printf("Warning: could not create %s: %v\n", filepath.Dir(path), err)
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		fmt.Printf("Warning: could not save scan state: %v\n", err)
+		return
+	}
+	os.Rename(tmp, path)
+}
+
+// runScanner implements scanner mode. It scans once and exits, or with
+// -scan-schedule or -scan-daemon keeps running and scans again whenever
+// the backend's schedule, the configured one or the default fires.
+func runScanner(cfg *config.Config, client *api.Client) {
+	opts, err := scanOptions(cfg)
+	if err != nil {
+		log.Fatalf("Invalid scan settings: %v", err)
+	}
+	if cfg.ScanSchedule != "" {
+		if _, err := schedule.Parse(cfg.ScanSchedule); err != nil {
+			log.Fatalf("Invalid scan settings: %v", err)
+		}
+	}
+	statePath := filepath.Join(cfg.DataDir, scanStateFile)
+	state := loadScanState(statePath)
+
+	if !cfg.ScanDaemon && cfg.ScanSchedule == "" {
+		if err := scanAll(cfg, opts, client, state, statePath); err != nil {
+			log.Fatalf("Scanning failed: %v", err)
+		}
+		return
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	for {
+		// A scan can take a while; don't make a stop request wait for it
+		done := make(chan error, 1)
+		go func() {
+			done <- scanAll(cfg, opts, client, state, statePath)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				log.Printf("Error during scan: %v", err)
+			}
+		case <-quit:
+			log.Println("Scanner stopping...")
+			return
+		}
+
+		next := scanSchedule(cfg, state).Next(time.Now())
+		log.Printf("Next scan at %s", next.Format(time.RFC1123))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-quit:
+			timer.Stop()
+			log.Println("Scanner stopping...")
+			return
+		}
+	}
+}
+
+// scanSchedule prefers the backend's schedule over the configured one.
+func scanSchedule(cfg *config.Config, state *scanState) *schedule.Schedule {
+	if state.Schedule != "" {
+		s, err := schedule.Parse(state.Schedule)
+		if err == nil {
+			return s
+		}
+		fmt.Printf("Warning: ignoring backend scan schedule: %v\n", err)
+	}
+	spec := cfg.ScanSchedule
+	if spec == "" {
+		spec = defaultScanSchedule
+	}
+	s, _ := schedule.Parse(spec) // Validated at startup
+	return s
+}
+
+// scanAll scans each target, diffs the result against the previous scan of
+// the same target and uploads both. The previous result is only replaced
+// once the upload succeeded, so the next diff still covers what the backend
+// missed.
+func scanAll(cfg *config.Config, opts scanner.Options, client *api.Client, state *scanState, statePath string) error {
+	targets := []string{cfg.ScanRange}
+	if cfg.ScanRange == "auto" {
+		if targets = autoTargets(cfg); len(targets) == 0 {
+			return fmt.Errorf("no directly attached subnets to scan; pass -scan with the targets instead")
+		}
+	}
+
+	var errs []error
+	current := map[string]bool{}
+	for _, target := range targets {
+		current[target] = true
+		result, err := scanner.Scan(target, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scan of %s failed: %w", target, err))
+			continue
+		}
+		result.Diff = scanner.Diff(state.Results[target], result)
+		if d := result.Diff; d != nil {
+			log.Printf("Scan of %s complete. Found %d devices: %d new, %d departed, %d changed.", target, len(result.Devices), len(d.New), len(d.Departed), len(d.Changed))
+		} else {
+			log.Printf("Scan of %s complete. Found %d devices.", target, len(result.Devices))
+		}
+
+		policy, err := client.SendScanResults(result)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to upload scan results: %w", err))
+			continue
+		}
+		log.Printf("Results uploaded successfully.")
+		result.Diff = nil
+		state.Results[target] = result
+		switch {
+		case policy.Schedule == nil || *policy.Schedule == state.Schedule:
+		case *policy.Schedule == "":
+			log.Printf("Scan schedule reverted by the backend to the configured one")
+			state.Schedule = ""
+		default:
+			if _, err := schedule.Parse(*policy.Schedule); err != nil {
+				fmt.Printf("Warning: ignoring backend scan schedule: %v\n", err)
+			} else {
+				log.Printf("Scan schedule set by the backend: %s", *policy.Schedule)
+				state.Schedule = *policy.Schedule
+			}
+		}
+	}
+	// Forget targets no longer scanned, e.g. subnets "auto" stopped finding
+	for target := range state.Results {
+		if !current[target] {
+			delete(state.Results, target)
+		}
+	}
+	state.save(statePath)
+	return errors.Join(errs...)
+}
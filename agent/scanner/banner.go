@@ -0,0 +1,356 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Banner is what an open port told about the service behind it.
+type Banner struct {
+	Port        int    `json:"port"`
+	Service     string `json:"service"`          // "ssh", "http", "https", "smb", "rdp", "jetdirect", "rtsp", "ftp", "telnet", "smtp"
+	Banner      string `json:"banner,omitempty"` // SSH identification string, greeting line, printer model, RTSP server
+	HTTPServer  string `json:"http_server,omitempty"`
+	HTTPTitle   string `json:"http_title,omitempty"`
+	TLSSubject  string `json:"tls_subject,omitempty"`
+	TLSIssuer   string `json:"tls_issuer,omitempty"`
+	SMBDialect  string `json:"smb_dialect,omitempty"`  // Highest dialect the server accepted, e.g. "3.1.1"
+	RDPProtocol string `json:"rdp_protocol,omitempty"` // Security protocol the server selected: "rdp", "tls", "credssp", "credssp_early_auth"
+}
+
+// Well-known services by port. Ports not listed get the generic greeting
+// probe.
+var portServices = map[int]string{
+	21:   "ftp",
+	22:   "ssh",
+	23:   "telnet",
+	25:   "smtp",
+	80:   "http",
+	81:   "http",
+	443:  "https",
+	445:  "smb",
+	554:  "rtsp",
+	631:  "http", // IPP
+	2323: "telnet",
+	3389: "rdp",
+	8000: "http",
+	8080: "http",
+	8081: "http",
+	8443: "https",
+	8888: "http",
+	9100: "jetdirect",
+	9101: "jetdirect",
+	9102: "jetdirect",
+}
+
+// grabBanners probes each open port for its service details, as many at
+// once as ports are probed. JetDirect ports are only sent PJL when pjl is
+// set; otherwise they are listed without a banner.
+func (p prober) grabBanners(ip string, ports []int, pjl bool) []Banner {
+	banners := make([]Banner, len(ports))
+	sem := make(chan struct{}, p.parallel)
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		service, ok := portServices[port]
+		if !ok {
+			service = "unknown"
+		}
+		if service == "jetdirect" && !pjl {
+			banners[i] = Banner{Service: service, Port: port}
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, address, service string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			banners[i] = p.grabBanner(address, service)
+		}(i, net.JoinHostPort(ip, strconv.Itoa(port)), service)
+	}
+	wg.Wait()
+	return banners
+}
+
+// grabBanner connects to address and collects what the given service
+// reveals about itself. Failures leave the fields empty.
+func (p prober) grabBanner(address, service string) Banner {
+	b := Banner{Service: service}
+	switch service {
+	case "ssh", "ftp", "smtp", "telnet", "unknown":
+		b.Banner = p.readGreeting(address)
+	case "http", "https":
+		p.grabHTTP(address, service == "https", &b)
+	case "smb":
+		b.SMBDialect = p.negotiateSMB(address)
+	case "rdp":
+		b.RDPProtocol = p.negotiateRDP(address)
+	case "jetdirect":
+		b.Banner = p.queryPJL(address)
+	case "rtsp":
+		b.Banner = p.rtspServer(address)
+	}
+	if _, port, err := net.SplitHostPort(address); err == nil {
+		b.Port, _ = strconv.Atoi(port)
+	}
+	return b
+}
+
+// dialBanner connects within the scan's rate limits and connect timeout.
+func (p prober) dialBanner(address string) (net.Conn, error) {
+	conn, err := p.dial(address)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(exchangeTimeouts * p.timeout))
+	return conn, nil
+}
+
+// readGreeting returns the first line a server sends unprompted (SSH
+// identification, FTP/SMTP 220 greetings, telnet login banners). Servers
+// that talk first do so at once, so silent ones get one timeout.
+func (p prober) readGreeting(address string) string {
+	conn, err := p.dialBanner(address)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(p.timeout))
+	line, _ := bufio.NewReader(io.LimitReader(conn, 1024)).ReadString('\n')
+	return printable(line)
+}
+
+// printable drops telnet option negotiation and other control bytes.
+func printable(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == 0xff && i+2 < len(s): // IAC command option
+			i += 2
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// grabHTTP fetches / without following redirects and records the Server
+// header, the page title and, over TLS, the certificate.
+func (p prober) grabHTTP(address string, useTLS bool, b *Banner) {
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	client := &http.Client{
+		Timeout: exchangeTimeouts * p.timeout,
+		Transport: &http.Transport{
+			DialContext: func(context.Context, string, string) (net.Conn, error) { return p.dial(address) },
+			// Devices use self-signed certificates; only the names are wanted
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	req, err := http.NewRequest("GET", scheme+"://"+address+"/", nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", "assetronics-agent")
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	b.HTTPServer = resp.Header.Get("Server")
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		b.TLSSubject = cert.Subject.String()
+		b.TLSIssuer = cert.Issuer.String()
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if m := titleRe.FindSubmatch(body); m != nil {
+		title := strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+		if len(title) > 200 {
+			title = title[:200]
+		}
+		b.HTTPTitle = title
+	}
+}
+
+// SMB2 dialect revisions (MS-SMB2 2.2.3)
+var smbDialects = []struct {
+	revision uint16
+	name     string
+}{
+	{0x0202, "2.0.2"},
+	{0x0210, "2.1"},
+	{0x0300, "3.0"},
+	{0x0302, "3.0.2"},
+	{0x0311, "3.1.1"},
+}
+
+// negotiateSMB sends an SMB2 NEGOTIATE offering every dialect and returns
+// the one the server picked. Servers that only speak SMB1 answer with an
+// SMB1 header and are reported as "1".
+func (p prober) negotiateSMB(address string) string {
+	conn, err := p.dialBanner(address)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	msg := make([]byte, 64, 256)
+	copy(msg, "\xfeSMB")
+	binary.LittleEndian.PutUint16(msg[4:], 64) // StructureSize
+	binary.LittleEndian.PutUint16(msg[14:], 1) // CreditRequest
+
+	body := make([]byte, 36)
+	binary.LittleEndian.PutUint16(body[0:], 36)
+	binary.LittleEndian.PutUint16(body[2:], uint16(len(smbDialects)))
+	binary.LittleEndian.PutUint16(body[4:], 1) // Signing enabled
+	copy(body[12:28], "assetronics-scan")      // ClientGuid
+	msg = append(msg, body...)
+	for _, d := range smbDialects {
+		msg = binary.LittleEndian.AppendUint16(msg, d.revision)
+	}
+	// 3.1.1 requires a preauth integrity context, 8-byte aligned
+	for len(msg)%8 != 0 {
+		msg = append(msg, 0)
+	}
+	binary.LittleEndian.PutUint32(msg[64+28:], uint32(len(msg))) // NegotiateContextOffset
+	binary.LittleEndian.PutUint16(msg[64+32:], 1)                // NegotiateContextCount
+	ctx := []byte{1, 0, 38, 0, 0, 0, 0, 0, 1, 0, 32, 0, 1, 0}    // SHA-512, 32-byte salt
+	msg = append(msg, ctx...)
+	msg = append(msg, make([]byte, 32)...)
+
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(msg))) // NetBIOS session message
+	if _, err := conn.Write(append(frame, msg...)); err != nil {
+		return ""
+	}
+
+	resp := make([]byte, 4+72)
+	n, _ := io.ReadFull(conn, resp)
+	switch {
+	case n >= 8 && string(resp[4:8]) == "\xffSMB":
+		return "1"
+	case n < len(resp) || string(resp[4:8]) != "\xfeSMB" || binary.LittleEndian.Uint32(resp[12:]) != 0:
+		return ""
+	}
+	resp = resp[4:]
+	revision := binary.LittleEndian.Uint16(resp[64+4:])
+	for _, d := range smbDialects {
+		if d.revision == revision {
+			return d.name
+		}
+	}
+	return strconv.FormatUint(uint64(revision), 16)
+}
+
+// RDP security protocols (MS-RDPBCGR 2.2.1.1.1)
+var rdpProtocols = map[uint32]string{
+	0: "rdp",
+	1: "tls",
+	2: "credssp",
+	8: "credssp_early_auth",
+}
+
+// negotiateRDP sends an X.224 Connection Request offering TLS and CredSSP
+// and returns the protocol the server selected.
+func (p prober) negotiateRDP(address string) string {
+	conn, err := p.dialBanner(address)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	req := []byte{
+		0x03, 0x00, 0x00, 0x13, // TPKT, 19 bytes
+		0x0e, 0xe0, 0, 0, 0, 0, 0, // X.224 Connection Request
+		0x01, 0x00, 0x08, 0x00, 0x0b, 0x00, 0x00, 0x00, // RDP_NEG_REQ: SSL | HYBRID | HYBRID_EX
+	}
+	if _, err := conn.Write(req); err != nil {
+		return ""
+	}
+	resp := make([]byte, 19)
+	n, _ := io.ReadFull(conn, resp)
+	if n < 11 || resp[0] != 0x03 || resp[5] != 0xd0 {
+		return ""
+	}
+	if n < 19 {
+		return "rdp" // Legacy servers send no negotiation response
+	}
+	switch resp[11] {
+	case 0x02: // RDP_NEG_RSP
+		selected := binary.LittleEndian.Uint32(resp[15:])
+		if name, ok := rdpProtocols[selected]; ok {
+			return name
+		}
+		return strconv.FormatUint(uint64(selected), 10)
+	case 0x03: // RDP_NEG_FAILURE: the server insists on something else
+		return "negotiation_failed"
+	}
+	return ""
+}
+
+// queryPJL asks a JetDirect port for the printer model (@PJL INFO ID).
+func (p prober) queryPJL(address string) string {
+	conn, err := p.dialBanner(address)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	const uel = "\x1b%-12345X"
+	if _, err := conn.Write([]byte(uel + "@PJL INFO ID\r\n" + uel + "\r\n")); err != nil {
+		return ""
+	}
+	// @PJL INFO ID
+	// "HP LaserJet M402dn"
+	// \f
+	reader := bufio.NewReader(io.LimitReader(conn, 1024))
+	for {
+		line, err := reader.ReadString('\n')
+		if line = printable(line); line != "" && !strings.HasPrefix(line, "@PJL") {
+			return strings.Trim(line, `"`)
+		}
+		if err != nil {
+			return ""
+		}
+	}
+}
+
+// rtspServer sends an RTSP OPTIONS request and returns the Server header.
+func (p prober) rtspServer(address string) string {
+	conn, err := p.dialBanner(address)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("OPTIONS * RTSP/1.0\r\nCSeq: 1\r\n\r\n")); err != nil {
+		return ""
+	}
+	reader := bufio.NewReader(io.LimitReader(conn, 4096))
+	for {
+		line, err := reader.ReadString('\n')
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Server") {
+			return printable(value)
+		}
+		if err != nil || strings.TrimSpace(line) == "" {
+			return ""
+		}
+	}
+}
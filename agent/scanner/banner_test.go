@@ -0,0 +1,239 @@
+package scanner
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serveTCP accepts connections on a loopback port and hands each to
+// handle, returning the address.
+func serveTCP(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// serveHTTP starts an HTTP server answering / with the given Server
+// header and page, returning the address.
+func serveHTTP(t *testing.T, server, page string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", server)
+		io.WriteString(w, page)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.Listener.Addr().String()
+}
+
+func testProber() prober {
+	return prober{timeout: 500 * time.Millisecond, parallel: 1}
+}
+
+func TestGrabBannerAndClassify(t *testing.T) {
+	tests := []struct {
+		name    string
+		serve   func(t *testing.T) string
+		service string
+		port    int // Where the service normally listens, for Classify
+		vendor  string
+		want    Banner
+		devType string
+		os      string
+	}{
+		{
+			name: "HTTP printer page",
+			serve: func(t *testing.T) string {
+				return serveHTTP(t, "HP HTTP Server; HP LaserJet M402dn", "<html><head><title> HP LaserJet\n M402dn </title></head></html>")
+			},
+			service: "http", port: 80,
+			want:    Banner{Service: "http", HTTPServer: "HP HTTP Server; HP LaserJet M402dn", HTTPTitle: "HP LaserJet M402dn"},
+			devType: DeviceTypePrinter,
+		},
+		{
+			name: "SSH",
+			serve: func(t *testing.T) string {
+				return serveTCP(t, func(c net.Conn) { io.WriteString(c, "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.4\r\n") })
+			},
+			service: "ssh", port: 22,
+			want:    Banner{Service: "ssh", Banner: "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.4"},
+			devType: DeviceTypeServer, os: "Ubuntu Linux",
+		},
+		{
+			name: "IPP on a Canon printer",
+			serve: func(t *testing.T) string {
+				return serveHTTP(t, "CANON HTTP Server", "<title>Remote UI</title>")
+			},
+			service: "http", port: 631, vendor: "Canon Inc.",
+			want:    Banner{Service: "http", HTTPServer: "CANON HTTP Server", HTTPTitle: "Remote UI"},
+			devType: DeviceTypePrinter,
+		},
+		{
+			name: "RTSP camera",
+			serve: func(t *testing.T) string {
+				return serveTCP(t, func(c net.Conn) {
+					r := bufio.NewReader(c)
+					for {
+						line, err := r.ReadString('\n')
+						if err != nil || strings.TrimSpace(line) == "" {
+							break
+						}
+					}
+					io.WriteString(c, "RTSP/1.0 200 OK\r\nCSeq: 1\r\nServer: Hikvision-Webs\r\nPublic: OPTIONS, DESCRIBE\r\n\r\n")
+				})
+			},
+			service: "rtsp", port: 554,
+			want:    Banner{Service: "rtsp", Banner: "Hikvision-Webs"},
+			devType: DeviceTypeCamera,
+		},
+		{
+			name: "silent unknown port",
+			serve: func(t *testing.T) string {
+				return serveTCP(t, func(c net.Conn) { io.Copy(io.Discard, c) })
+			},
+			service: "unknown", port: 5000,
+			want:    Banner{Service: "unknown"},
+			devType: DeviceTypeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := time.Now()
+			b := testProber().grabBanner(tt.serve(t), tt.service)
+			if elapsed := time.Since(started); elapsed > 2*time.Second {
+				t.Errorf("grab took %v", elapsed)
+			}
+			b.Port = 0
+			if b != tt.want {
+				t.Errorf("grabBanner:\n got %+v\nwant %+v", b, tt.want)
+			}
+
+			b.Port = tt.port
+			d := Device{Ports: []int{tt.port}, Services: []Banner{b}, Vendor: tt.vendor}
+			Classify(&d)
+			if d.DeviceType != tt.devType || d.OSGuess != tt.os {
+				t.Errorf("Classify = %q/%q, want %q/%q", d.DeviceType, d.OSGuess, tt.devType, tt.os)
+			}
+		})
+	}
+}
+
+func TestClassifyPrinterVendors(t *testing.T) {
+	tests := []struct {
+		name   string
+		device Device
+		want   string
+	}{
+		{"Toshiba laptop", Device{Vendor: "Toshiba", Ports: []int{135, 445, 3389}}, DeviceTypeWorkstation},
+		{"Canon camera", Device{Vendor: "Canon Inc.", Ports: []int{80}}, DeviceTypeUnknown},
+		{"Toshiba MFP", Device{Vendor: "Toshiba TEC", Ports: []int{80, 9100}}, DeviceTypePrinter},
+		{"Brother printer", Device{Vendor: "Brother Industries, Ltd."}, DeviceTypePrinter},
+	}
+	for _, tt := range tests {
+		d := tt.device
+		Classify(&d)
+		if d.DeviceType != tt.want {
+			t.Errorf("%s: DeviceType = %q, want %q", tt.name, d.DeviceType, tt.want)
+		}
+	}
+}
+
+func TestGrabBannersRateLimited(t *testing.T) {
+	greeting := func(c net.Conn) { io.WriteString(c, "220 ready\r\n") }
+	var ports []int
+	for range 3 {
+		_, port, _ := net.SplitHostPort(serveTCP(t, greeting))
+		p, _ := strconv.Atoi(port)
+		ports = append(ports, p)
+	}
+
+	p := testProber()
+	p.parallel = 3
+	p.host = newRateLimiter(10)
+	started := time.Now()
+	banners := p.grabBanners("127.0.0.1", ports, false)
+	// Three connects at 10 per second are spaced 100ms apart
+	if elapsed := time.Since(started); elapsed < 200*time.Millisecond {
+		t.Errorf("3 banner grabs at 10/s took %v", elapsed)
+	}
+	for i, b := range banners {
+		if b.Port != ports[i] || b.Service != "unknown" || b.Banner != "220 ready" {
+			t.Errorf("banner %d = %+v", i, b)
+		}
+	}
+}
+
+func TestJetDirectNeedsPrinterSignal(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:9100")
+	if err != nil {
+		t.Skipf("port 9100 unavailable: %v", err)
+	}
+	defer l.Close()
+	var queried atomic.Bool
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			line, _ := bufio.NewReader(c).ReadString('\n')
+			if strings.Contains(line, "@PJL INFO ID") {
+				queried.Store(true)
+				io.WriteString(c, "@PJL INFO ID\r\n\"HP LaserJet M404dn\"\r\n\f")
+			}
+			c.Close()
+		}
+	}()
+
+	if b := testProber().grabBanners("127.0.0.1", []int{9100}, false); b[0] != (Banner{Service: "jetdirect", Port: 9100}) || queried.Load() {
+		t.Errorf("without a printer signal: %+v, queried = %v", b[0], queried.Load())
+	}
+	if b := testProber().grabBanners("127.0.0.1", []int{9100}, true); b[0].Banner != "HP LaserJet M404dn" {
+		t.Errorf("with a printer signal: %+v", b[0])
+	}
+
+	s := &scan{
+		mdnsHosts:   map[string]*mdnsHost{"10.0.0.3": {services: []*MDNSService{{Type: "_ipp._tcp"}}}},
+		upnpDevices: map[string]*UPnPDevice{"10.0.0.4": {DeviceType: "urn:schemas-upnp-org:device:Printer:1"}},
+	}
+	tests := []struct {
+		ip     string
+		device Device
+		want   bool
+	}{
+		{"10.0.0.1", Device{Ports: []int{22, 9100}}, false},
+		{"10.0.0.2", Device{Ports: []int{631, 9100}}, true},
+		{"10.0.0.3", Device{Ports: []int{9100}}, true},
+		{"10.0.0.4", Device{Ports: []int{9100}}, true},
+		{"10.0.0.5", Device{Ports: []int{9100}, SNMP: &SNMPInfo{Printer: &PrinterStatus{}}}, true},
+	}
+	for _, tt := range tests {
+		if got := s.looksLikePrinter(tt.ip, tt.device); got != tt.want {
+			t.Errorf("looksLikePrinter(%s, %v) = %v, want %v", tt.ip, tt.device.Ports, got, tt.want)
+		}
+	}
+}
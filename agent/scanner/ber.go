@@ -0,0 +1,193 @@
+package scanner
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// BER tags used by SNMP (RFC 3416, RFC 2578)
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berNull        = 0x05
+	berOID         = 0x06
+	berSequence    = 0x30
+
+	snmpIPAddress  = 0x40
+	snmpCounter32  = 0x41
+	snmpGauge32    = 0x42
+	snmpTimeTicks  = 0x43
+	snmpCounter64  = 0x46
+	snmpNoSuchObj  = 0x80
+	snmpNoSuchInst = 0x81
+	snmpEndOfView  = 0x82
+
+	pduGet      = 0xa0
+	pduGetNext  = 0xa1
+	pduResponse = 0xa2
+	pduGetBulk  = 0xa5
+	pduReport   = 0xa8
+)
+
+var errBER = errors.New("malformed BER")
+
+// berTLV wraps the concatenated contents in a tag and definite length.
+func berTLV(tag byte, contents ...[]byte) []byte {
+	n := 0
+	for _, c := range contents {
+		n += len(c)
+	}
+	b := []byte{tag}
+	switch {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n < 0x100:
+		b = append(b, 0x81, byte(n))
+	case n < 0x10000:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+	for _, c := range contents {
+		b = append(b, c...)
+	}
+	return b
+}
+
+// berInt encodes v as a minimal two's complement INTEGER.
+func berInt(v int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		if (v < 0x80 && v >= -0x80) || len(b) == 8 {
+			break
+		}
+		v >>= 8
+	}
+	return berTLV(berInteger, b)
+}
+
+func berOctets(b []byte) []byte {
+	return berTLV(berOctetString, b)
+}
+
+// berObjectID encodes a dotted OID such as "1.3.6.1.2.1.1.1.0".
+func berObjectID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, errBER
+	}
+	arcs := make([]uint64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, errBER
+		}
+		arcs[i] = v
+	}
+	content := appendBase128(nil, arcs[0]*40+arcs[1])
+	for _, arc := range arcs[2:] {
+		content = appendBase128(content, arc)
+	}
+	return berTLV(berOID, content), nil
+}
+
+func appendBase128(b []byte, v uint64) []byte {
+	var groups []byte
+	for {
+		groups = append([]byte{byte(v & 0x7f)}, groups...)
+		v >>= 7
+		if v == 0 {
+			break
+		}
+	}
+	for i := 0; i < len(groups)-1; i++ {
+		groups[i] |= 0x80
+	}
+	return append(b, groups...)
+}
+
+// parseTLV splits the first element off b. content and rest share b's
+// backing array.
+func parseTLV(b []byte) (tag byte, content, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errBER
+	}
+	tag = b[0]
+	n := int(b[1])
+	off := 2
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 4 || len(b) < 2+size {
+			return 0, nil, nil, errBER
+		}
+		n = 0
+		for _, c := range b[2 : 2+size] {
+			n = n<<8 | int(c)
+		}
+		off += size
+	}
+	if n < 0 || len(b)-off < n {
+		return 0, nil, nil, errBER
+	}
+	return tag, b[off : off+n], b[off+n:], nil
+}
+
+// parseExpect is parseTLV for an element whose tag is known.
+func parseExpect(b []byte, want byte) (content, rest []byte, err error) {
+	tag, content, rest, err := parseTLV(b)
+	if err == nil && tag != want {
+		err = errBER
+	}
+	return content, rest, err
+}
+
+func parseIntElem(b []byte) (int64, []byte, error) {
+	content, rest, err := parseExpect(b, berInteger)
+	if err != nil {
+		return 0, nil, err
+	}
+	return berSigned(content), rest, nil
+}
+
+func berSigned(content []byte) int64 {
+	var v int64
+	for i, c := range content {
+		if i == 0 && c&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+func berUnsigned(content []byte) uint64 {
+	var v uint64
+	for _, c := range content {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func berOIDString(content []byte) string {
+	var arcs []string
+	var v uint64
+	for _, c := range content {
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			continue
+		}
+		if len(arcs) == 0 {
+			first := v / 40
+			if first > 2 {
+				first = 2
+			}
+			arcs = append(arcs, strconv.FormatUint(first, 10), strconv.FormatUint(v-first*40, 10))
+		} else {
+			arcs = append(arcs, strconv.FormatUint(v, 10))
+		}
+		v = 0
+	}
+	return strings.Join(arcs, ".")
+}
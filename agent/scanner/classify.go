@@ -0,0 +1,326 @@
+package scanner
+
+import (
+	"strings"
+)
+
+// Device types reported in Device.DeviceType
+const (
+	DeviceTypePrinter     = "printer"
+	DeviceTypeSwitch      = "switch" // Switches, routers, firewalls and access points
+	DeviceTypeCamera      = "camera"
+	DeviceTypeNAS         = "nas"
+	DeviceTypeWorkstation = "workstation"
+	DeviceTypeServer      = "server"
+	DeviceTypeUPS         = "ups"
+	DeviceTypeMedia       = "media" // TVs, streaming devices, speakers and AirPlay displays
+	DeviceTypePhone       = "phone" // VoIP phones and conference room systems
+	DeviceTypeUnknown     = "unknown"
+)
+
+// facts is a scanned device flattened for the classifier rules.
+type facts struct {
+	ports    map[int]bool
+	services map[string]Banner
+	text     string // Lower-cased banners, titles, certificate names, sysDescr, hostname and vendor
+	vendor   string // Lower-cased MAC vendor and SNMP make
+	hostname string // Lower-cased
+	snmp     *SNMPInfo
+	sysDescr string          // Lower-cased
+	mdns     map[string]bool // Advertised DNS-SD service types
+	upnpType string          // Lower-cased UPnP device type
+}
+
+func deviceFacts(d *Device) *facts {
+	f := &facts{
+		ports:    map[int]bool{},
+		services: map[string]Banner{},
+		vendor:   strings.ToLower(d.Vendor + "\n" + d.Make),
+		hostname: strings.ToLower(d.Hostname),
+		snmp:     d.SNMP,
+		mdns:     map[string]bool{},
+	}
+	parts := []string{d.Hostname, d.Vendor, d.Make, d.Model}
+	if d.SNMP != nil {
+		f.sysDescr = strings.ToLower(d.SNMP.SysDescr)
+		parts = append(parts, d.SNMP.SysDescr)
+	}
+	for _, s := range d.MDNS {
+		f.mdns[s.Type] = true
+		parts = append(parts, s.Name)
+		for _, v := range s.Properties {
+			parts = append(parts, v)
+		}
+	}
+	if u := d.UPnP; u != nil {
+		f.upnpType = strings.ToLower(u.DeviceType)
+		parts = append(parts, u.FriendlyName, u.Manufacturer, u.ModelName, u.Server)
+	}
+	for _, p := range d.Ports {
+		f.ports[p] = true
+	}
+	for _, s := range d.Services {
+		if _, seen := f.services[s.Service]; !seen {
+			f.services[s.Service] = s
+		}
+		parts = append(parts, s.Banner, s.HTTPServer, s.HTTPTitle, s.TLSSubject, s.TLSIssuer)
+	}
+	f.text = strings.ToLower(strings.Join(parts, "\n"))
+	return f
+}
+
+func (f *facts) has(ports ...int) bool {
+	for _, p := range ports {
+		if !f.ports[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// printService reports a print port (JetDirect, IPP, LPD) or banner.
+func (f *facts) printService() bool {
+	return f.has(9100) || f.has(631) || f.has(515) || f.services["jetdirect"].Banner != "" || f.mentions(printerWords...)
+}
+
+func (f *facts) mentions(words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(f.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *facts) vendorIs(words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(f.vendor, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *facts) hostnameHas(words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(f.hostname, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *facts) describes(words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(f.sysDescr, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *facts) advertises(types ...string) bool {
+	for _, t := range types {
+		if f.mdns[t] {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *facts) upnp(words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(f.upnpType, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *facts) ssh(words ...string) bool {
+	banner := strings.ToLower(f.services["ssh"].Banner)
+	for _, w := range words {
+		if strings.Contains(banner, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// rule adds weight to a device type and/or an OS guess when it matches.
+// Weights are roughly "percent sure on this evidence alone".
+type rule struct {
+	deviceType string
+	os         string
+	weight     int
+	match      func(f *facts) bool
+}
+
+var printerVendors = []string{"epson", "xerox", "ricoh", "brother", "lexmark", "kyocera", "konica", "zebra"}
+
+// Vendors that also make cameras, laptops and TVs; they only count
+// alongside a print service
+var sidelinePrinterVendors = []string{"canon", "sharp", "toshiba"}
+
+// DNS-SD types of print services
+var printServiceTypes = []string{"_ipp._tcp", "_ipps._tcp", "_printer._tcp", "_pdl-datastream._tcp"}
+
+var printerWords = []string{"laserjet", "officejet", "deskjet", "pagewide", "printer", "bizhub", "imagerunner", "workcentre", "versalink", "ecosys", "aficio", "jetdirect", "web image monitor", "embedded web server"}
+var networkVendors = []string{"cisco", "juniper", "aruba", "ubiquiti", "routerboard", "arista", "extreme networks", "allied telesis", "netgear", "tp-link", "d-link", "linksys", "fortinet", "palo alto", "sonicwall", "watchguard", "check point", "sophos", "huawei", "pc engines"}
+
+var rules = []rule{
+	// Printers
+	{deviceType: DeviceTypePrinter, weight: 40, match: func(f *facts) bool { return f.has(9100) }},
+	{deviceType: DeviceTypePrinter, weight: 50, match: func(f *facts) bool { return f.services["jetdirect"].Banner != "" }},
+	{deviceType: DeviceTypePrinter, weight: 15, match: func(f *facts) bool { return f.has(631) }},
+	{deviceType: DeviceTypePrinter, weight: 50, match: func(f *facts) bool { return f.mentions(printerWords...) }},
+	{deviceType: DeviceTypePrinter, weight: 30, match: func(f *facts) bool {
+		return f.vendorIs(printerVendors...) || (f.vendorIs(sidelinePrinterVendors...) && f.printService())
+	}},
+	{deviceType: DeviceTypePrinter, weight: 80, match: func(f *facts) bool { return f.snmp != nil && f.snmp.Printer != nil }},
+	{deviceType: DeviceTypePrinter, weight: 60, match: func(f *facts) bool {
+		return f.advertises(printServiceTypes...) || f.upnp(":printer:")
+	}},
+
+	// TVs, streamers, speakers
+	{deviceType: DeviceTypeMedia, weight: 60, match: func(f *facts) bool {
+		return f.advertises("_googlecast._tcp", "_airplay._tcp", "_raop._tcp", "_spotify-connect._tcp", "_sonos._tcp", "_amzn-wplay._tcp")
+	}},
+	{deviceType: DeviceTypeMedia, weight: 40, match: func(f *facts) bool { return f.upnp(":mediarenderer:") }},
+	{deviceType: DeviceTypeMedia, weight: 50, match: func(f *facts) bool {
+		return f.mentions("chromecast", "apple tv", "appletv", "roku", "sonos", "fire tv", "bravia", "smart tv", "webos", "tizen", "vizio")
+	}},
+
+	// Phones and conference room systems
+	{deviceType: DeviceTypePhone, weight: 40, match: func(f *facts) bool { return f.advertises("_sip._udp", "_sip._tcp") }},
+	{deviceType: DeviceTypePhone, weight: 60, match: func(f *facts) bool {
+		return f.mentions("yealink", "polycom", "poly studio", "grandstream", "snom", "cisco ip phone", "avaya", "mitel", "crestron", "logitech rally")
+	}},
+
+	// UPSes
+	{deviceType: DeviceTypeUPS, weight: 80, match: func(f *facts) bool { return f.snmp != nil && f.snmp.UPS != nil }},
+	{deviceType: DeviceTypeUPS, weight: 50, match: func(f *facts) bool {
+		return f.mentions("smart-ups", "symmetra", "powerchute", "network management card", "ups network", "eaton 9", "powerware")
+	}},
+	{deviceType: DeviceTypeUPS, weight: 30, match: func(f *facts) bool { return f.vendorIs("american power conversion", "schneider electric") }},
+
+	// Cameras
+	{deviceType: DeviceTypeCamera, weight: 35, match: func(f *facts) bool { return f.has(554) }},
+	{deviceType: DeviceTypeCamera, weight: 55, match: func(f *facts) bool {
+		return f.mentions("hikvision", "dahua", "axis communications", "network camera", "ip camera", "ipcam", "nvr", "dvr", "webcam")
+	}},
+	{deviceType: DeviceTypeCamera, weight: 40, match: func(f *facts) bool { return f.vendorIs("hikvision", "dahua", "axis") }},
+
+	// NAS
+	{deviceType: DeviceTypeNAS, weight: 60, match: func(f *facts) bool {
+		return f.mentions("synology", "diskstation", "qnap", "readynas", "truenas", "freenas", "terramaster", "my cloud")
+	}},
+	{deviceType: DeviceTypeNAS, weight: 40, match: func(f *facts) bool { return f.vendorIs("synology", "icp electronics", "qnap", "western digital") }},
+
+	// Switches, routers, firewalls, access points
+	{deviceType: DeviceTypeSwitch, weight: 55, match: func(f *facts) bool {
+		return f.mentions("cisco ios", "routeros", "mikrotik", "edgeos", "edgeswitch", "unifi", "procurve", "aruba", "junos", "fortigate", "fortios", "sonicwall", "pfsense", "opnsense", "openwrt", "meraki", "catalyst", "netgear prosafe", "omada")
+	}},
+	{deviceType: DeviceTypeSwitch, weight: 40, match: func(f *facts) bool { return f.vendorIs(networkVendors...) }},
+	{deviceType: DeviceTypeSwitch, weight: 50, match: func(f *facts) bool { return f.upnp(":internetgatewaydevice:", ":wfadevice:") }},
+	{deviceType: DeviceTypeSwitch, weight: 10, match: func(f *facts) bool { return f.has(23) && !f.has(445) }},
+	{deviceType: DeviceTypeSwitch, weight: 50, match: func(f *facts) bool {
+		return f.describes("switch", "router", "firewall", "access point", "wireless controller")
+	}},
+	{deviceType: DeviceTypeSwitch, os: "Cisco IOS", weight: 40, match: func(f *facts) bool { return f.ssh("cisco") || f.mentions("cisco ios") }},
+	{deviceType: DeviceTypeSwitch, os: "MikroTik RouterOS", weight: 50, match: func(f *facts) bool { return f.ssh("rosssh") || f.mentions("routeros") }},
+
+	// Workstations and servers
+	{deviceType: DeviceTypeWorkstation, weight: 25, match: func(f *facts) bool { return f.has(445, 3389) }},
+	{deviceType: DeviceTypeWorkstation, weight: 40, match: func(f *facts) bool {
+		return f.hostnameHas("desktop-", "laptop-", "-pc", "-laptop", "-desktop", "macbook", "imac", "-mbp", "-ws", "ws-")
+	}},
+	{deviceType: DeviceTypeWorkstation, weight: 20, match: func(f *facts) bool {
+		return f.vendorIs("apple") && !f.has(9100)
+	}},
+	{deviceType: DeviceTypeWorkstation, weight: 40, match: func(f *facts) bool {
+		return f.advertises("_workstation._tcp") || (f.advertises("_device-info._tcp") && f.mentions("macbook", "imac", "macmini", "macpro"))
+	}},
+	{deviceType: DeviceTypeServer, weight: 40, match: func(f *facts) bool {
+		return f.hostnameHas("srv", "server", "-dc", "dc0", "dc1", "sql", "exch", "esx", "hyperv", "proxmox", "-db")
+	}},
+	{deviceType: DeviceTypeServer, weight: 40, match: func(f *facts) bool {
+		return f.mentions("microsoft-iis", "vmware esxi", "proxmox", "idrac", "integrated lights-out", "ilo ")
+	}},
+	{deviceType: DeviceTypeServer, weight: 30, match: func(f *facts) bool { return f.vendorIs("super micro", "vmware", "xensource", "ibm") }},
+	{deviceType: DeviceTypeServer, weight: 20, match: func(f *facts) bool {
+		return f.has(22) && !f.has(3389) && f.ssh("ubuntu", "debian", "el7", "el8", "el9", "freebsd")
+	}},
+
+	// OS guesses
+	{os: "Windows", weight: 50, match: func(f *facts) bool { return f.services["rdp"].RDPProtocol != "" }},
+	{os: "Windows", weight: 20, match: func(f *facts) bool { return f.has(445) && f.has(135) }},
+	{os: "Windows", weight: 40, match: func(f *facts) bool { return f.mentions("microsoft-iis", "microsoft-httpapi", "openssh_for_windows") }},
+	{os: "Ubuntu Linux", weight: 60, match: func(f *facts) bool { return f.ssh("ubuntu") || f.mentions("(ubuntu)") }},
+	{os: "Debian Linux", weight: 60, match: func(f *facts) bool { return f.ssh("debian") || f.mentions("(debian)") }},
+	{os: "Red Hat Enterprise Linux", weight: 40, match: func(f *facts) bool {
+		return f.mentions("(red hat enterprise linux)", "(rhel)", "(centos)", "(rocky linux)", "(almalinux)")
+	}},
+	{os: "FreeBSD", weight: 60, match: func(f *facts) bool { return f.ssh("freebsd") || strings.HasPrefix(f.sysDescr, "freebsd ") }},
+	{os: "Windows", weight: 60, match: func(f *facts) bool { return f.describes("software: windows") }},
+	{os: "Linux", weight: 40, match: func(f *facts) bool { return strings.HasPrefix(f.sysDescr, "linux ") }},
+	{os: "Junos", weight: 60, match: func(f *facts) bool { return f.describes("junos") }},
+	{os: "Embedded Linux", weight: 30, match: func(f *facts) bool {
+		return f.ssh("dropbear") || f.mentions("boa/", "lighttpd", "goahead", "mini_httpd", "thttpd")
+	}},
+	{os: "macOS", weight: 30, match: func(f *facts) bool { return f.vendorIs("apple") && (f.has(22) || f.has(445)) && !f.has(3389) }},
+	{os: "macOS", weight: 50, match: func(f *facts) bool {
+		return f.advertises("_device-info._tcp") && f.mentions("macbook", "imac", "macmini", "macpro")
+	}},
+	{os: "Linux", weight: 15, match: func(f *facts) bool { return f.ssh("openssh") && !f.ssh("windows") }},
+	{os: "Samba (Linux/Unix)", weight: 10, match: func(f *facts) bool { return f.has(445) && !f.has(135) && !f.has(3389) }},
+	{os: "Synology DSM", weight: 60, match: func(f *facts) bool { return f.mentions("synology", "diskstation") }},
+	{os: "QNAP QTS", weight: 60, match: func(f *facts) bool { return f.mentions("qnap") }},
+}
+
+// Classify guesses a device's type and OS from its open ports, banners,
+// SNMP, mDNS and UPnP data, hostname and MAC vendor. Each matching rule adds its weight to its type
+// and OS; the best-scoring type and OS win, and the type's total (capped
+// at 100) is the confidence.
+func Classify(d *Device) {
+	f := deviceFacts(d)
+	typeScores := map[string]int{}
+	osScores := map[string]int{}
+	for _, r := range rules {
+		if !r.match(f) {
+			continue
+		}
+		if r.deviceType != "" {
+			typeScores[r.deviceType] += r.weight
+		}
+		if r.os != "" {
+			osScores[r.os] += r.weight
+		}
+	}
+
+	d.DeviceType, d.Confidence = best(typeScores)
+	if d.DeviceType == "" {
+		d.DeviceType = DeviceTypeUnknown
+	}
+	d.OSGuess, _ = best(osScores)
+
+	// A Windows host with nothing else to go on is most likely a desktop
+	if d.DeviceType == DeviceTypeUnknown && d.OSGuess == "Windows" {
+		d.DeviceType, d.Confidence = DeviceTypeWorkstation, 20
+	}
+}
+
+// best returns the highest-scoring key, breaking ties by name so results
+// are stable.
+func best(scores map[string]int) (string, int) {
+	name, score := "", 0
+	for k, v := range scores {
+		if v > score || (v == score && k < name) {
+			name, score = k, v
+		}
+	}
+	if score > 100 {
+		score = 100
+	}
+	return name, score
+}
@@ -0,0 +1,111 @@
+package scanner
+
+import "slices"
+
+// ScanDiff is what changed on the scanned targets since the previous scan
+// of the same targets.
+type ScanDiff struct {
+	PreviousScan string         `json:"previous_scan"` // ScannedAt of the scan compared against
+	New          []Device       `json:"new_devices"`
+	Departed     []Device       `json:"departed_devices"`
+	Changed      []DeviceChange `json:"changed_devices"`
+}
+
+// DeviceChange is how a device that is still present differs from the
+// previous scan.
+type DeviceChange struct {
+	IP          string          `json:"ip"`
+	Mac         string          `json:"mac,omitempty"`
+	PreviousIP  string          `json:"previous_ip,omitempty"` // Set when a known MAC moved to another address
+	OpenedPorts []int           `json:"opened_ports,omitempty"`
+	ClosedPorts []int           `json:"closed_ports,omitempty"`
+	Hostname    *HostnameChange `json:"hostname,omitempty"`
+}
+
+type HostnameChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Diff compares a scan with the previous scan of the same targets, or
+// returns nil without one. Devices are matched by MAC address, so a host
+// that got a new DHCP lease is a change rather than a departure and an
+// arrival, and by IP address when either side has no MAC.
+func Diff(previous, current *ScanResult) *ScanDiff {
+	if previous == nil {
+		return nil
+	}
+	d := &ScanDiff{
+		PreviousScan: previous.ScannedAt,
+		New:          []Device{},
+		Departed:     []Device{},
+		Changed:      []DeviceChange{},
+	}
+
+	byMAC := map[string]int{}
+	byIP := map[string]int{}
+	for i, dev := range previous.Devices {
+		if dev.Mac != "" {
+			byMAC[dev.Mac] = i
+		}
+		byIP[dev.IP] = i
+	}
+	matched := make([]bool, len(previous.Devices))
+	match := func(dev Device) (int, bool) {
+		if i, ok := byMAC[dev.Mac]; ok && dev.Mac != "" && !matched[i] {
+			return i, true
+		}
+		if i, ok := byIP[dev.IP]; ok && !matched[i] {
+			prev := previous.Devices[i]
+			if prev.Mac == "" || dev.Mac == "" || prev.Mac == dev.Mac {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	for _, dev := range current.Devices {
+		i, ok := match(dev)
+		if !ok {
+			d.New = append(d.New, dev)
+			continue
+		}
+		matched[i] = true
+		if change, changed := compareDevices(previous.Devices[i], dev); changed {
+			d.Changed = append(d.Changed, change)
+		}
+	}
+	for i, dev := range previous.Devices {
+		if !matched[i] {
+			d.Departed = append(d.Departed, dev)
+		}
+	}
+	return d
+}
+
+func compareDevices(prev, cur Device) (DeviceChange, bool) {
+	c := DeviceChange{IP: cur.IP, Mac: cur.Mac}
+	changed := false
+	if prev.IP != cur.IP {
+		c.PreviousIP = prev.IP
+		changed = true
+	}
+	for _, p := range cur.Ports {
+		if !slices.Contains(prev.Ports, p) {
+			c.OpenedPorts = append(c.OpenedPorts, p)
+			changed = true
+		}
+	}
+	for _, p := range prev.Ports {
+		if !slices.Contains(cur.Ports, p) {
+			c.ClosedPorts = append(c.ClosedPorts, p)
+			changed = true
+		}
+	}
+	// A failed lookup is not a rename
+	if cur.Hostname != "" && cur.Hostname != prev.Hostname {
+		c.Hostname = &HostnameChange{From: prev.Hostname, To: cur.Hostname}
+		changed = true
+	}
+	return c, changed
+}
@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	previous := &ScanResult{
+		ScannedAt: "2024-06-01T02:00:00Z",
+		Devices: []Device{
+			{IP: "10.0.0.10", Mac: "aa:aa:aa:aa:aa:aa", Hostname: "laptop", Ports: []int{22, 80}},
+			{IP: "10.0.0.11", Mac: "bb:bb:bb:bb:bb:bb", Hostname: "nas", Ports: []int{443}},
+			{IP: "10.0.0.12", Hostname: "printer", Ports: []int{9100}}, // Routed, no MAC
+			{IP: "10.0.0.13", Mac: "dd:dd:dd:dd:dd:dd"},
+			{IP: "10.0.0.14", Hostname: "camera"},
+		},
+	}
+	current := &ScanResult{
+		Devices: []Device{
+			// New DHCP lease: matched by MAC, not departed and new
+			{IP: "10.0.0.20", Mac: "aa:aa:aa:aa:aa:aa", Hostname: "laptop", Ports: []int{22, 443}},
+			{IP: "10.0.0.11", Mac: "bb:bb:bb:bb:bb:bb", Hostname: "nas2", Ports: []int{443}},
+			// Failed reverse lookup is not a rename
+			{IP: "10.0.0.12", Ports: []int{9100}},
+			// Same address, different MAC: another device
+			{IP: "10.0.0.13", Mac: "ee:ee:ee:ee:ee:ee"},
+			// The laptop's old address, taken by a new device
+			{IP: "10.0.0.10", Mac: "ff:ff:ff:ff:ff:ff"},
+			// MAC now known for a device seen without one
+			{IP: "10.0.0.14", Mac: "cc:cc:cc:cc:cc:cc", Hostname: "camera", Ports: []int{554}},
+		},
+	}
+
+	d := Diff(previous, current)
+	if d.PreviousScan != "2024-06-01T02:00:00Z" {
+		t.Errorf("PreviousScan = %q", d.PreviousScan)
+	}
+	ips := func(devices []Device) []string {
+		var out []string
+		for _, dev := range devices {
+			out = append(out, dev.IP+" "+dev.Mac)
+		}
+		return out
+	}
+	if got, want := ips(d.New), []string{"10.0.0.13 ee:ee:ee:ee:ee:ee", "10.0.0.10 ff:ff:ff:ff:ff:ff"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("New = %q, want %q", got, want)
+	}
+	if got, want := ips(d.Departed), []string{"10.0.0.13 dd:dd:dd:dd:dd:dd"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Departed = %q, want %q", got, want)
+	}
+	wantChanged := []DeviceChange{
+		{IP: "10.0.0.20", Mac: "aa:aa:aa:aa:aa:aa", PreviousIP: "10.0.0.10", OpenedPorts: []int{443}, ClosedPorts: []int{80}},
+		{IP: "10.0.0.11", Mac: "bb:bb:bb:bb:bb:bb", Hostname: &HostnameChange{From: "nas", To: "nas2"}},
+		{IP: "10.0.0.14", Mac: "cc:cc:cc:cc:cc:cc", OpenedPorts: []int{554}},
+	}
+	if !reflect.DeepEqual(d.Changed, wantChanged) {
+		t.Errorf("Changed:\n got %+v\nwant %+v", d.Changed, wantChanged)
+	}
+}
+
+func TestDiffEdges(t *testing.T) {
+	if d := Diff(nil, &ScanResult{}); d != nil {
+		t.Errorf("Diff without a previous scan = %+v, want nil", d)
+	}
+	// Nothing changed: empty lists, not nulls, so the backend sees "no
+	// changes" rather than "no diff"
+	same := &ScanResult{Devices: []Device{{IP: "10.0.0.1", Ports: []int{22}}}}
+	d := Diff(same, same)
+	if d.New == nil || d.Departed == nil || d.Changed == nil || len(d.New)+len(d.Departed)+len(d.Changed) != 0 {
+		t.Errorf("unchanged scan: %+v", d)
+	}
+	// A hostname that appears for the first time is a change
+	d = Diff(same, &ScanResult{Devices: []Device{{IP: "10.0.0.1", Hostname: "db1", Ports: []int{22}}}})
+	if len(d.Changed) != 1 || *d.Changed[0].Hostname != (HostnameChange{From: "", To: "db1"}) {
+		t.Errorf("new hostname: %+v", d.Changed)
+	}
+}
@@ -0,0 +1,246 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	icmpEchoReply    = 0
+	icmpEchoRequest  = 8
+	icmp6EchoRequest = 128
+	icmp6EchoReply   = 129
+
+	pingAttempts      = 2
+	pingTimeout       = time.Second // Wait for replies after each round of requests
+	pingBatchSize     = 64          // Requests sent back to back before pausing
+	pingBatchInterval = 5 * time.Millisecond
+)
+
+// pinger sends ICMP echo requests from a single socket and matches the
+// replies to targets by identifier and sequence number, so a whole sweep
+// needs one socket instead of one ping process per address.
+type pinger struct {
+	conn net.PacketConn
+	id   uint16
+	// Linux datagram sockets rewrite the identifier to the socket's own
+	// and only deliver replies addressed to it
+	checkID  bool
+	datagram bool
+	v6       bool
+}
+
+// newPinger opens an ICMP socket for local's address family, bound to local
+// unless it is unspecified. It prefers an unprivileged datagram socket and
+// falls back to a raw socket, which needs root or Administrator.
+func newPinger(local netip.Addr) (*pinger, error) {
+	p := &pinger{id: uint16(os.Getpid()), checkID: true, v6: local.Is6()}
+	conn, dgramErr := listenICMPDatagram(local)
+	if dgramErr == nil {
+		p.conn, p.datagram = conn, true
+		p.checkID = runtime.GOOS != "linux"
+		return p, nil
+	}
+	network := "ip4:icmp"
+	if p.v6 {
+		network = "ip6:ipv6-icmp"
+	}
+	conn, err := net.ListenPacket(network, local.String())
+	if err != nil {
+		return nil, errors.Join(dgramErr, err)
+	}
+	p.conn = conn
+	return p, nil
+}
+
+func (p *pinger) Close() error {
+	return p.conn.Close()
+}
+
+// sweep pings every target, retrying the silent ones, and returns the
+// round-trip time of each target that replied. A non-nil limiter paces
+// the requests.
+func (p *pinger) sweep(targets []netip.Addr, limiter *rateLimiter) map[netip.Addr]time.Duration {
+	var mu sync.Mutex
+	rtts := map[netip.Addr]time.Duration{}
+	seqs := make(map[netip.Addr]uint16, len(targets))
+	for i, addr := range targets {
+		seqs[addr] = uint16(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := p.conn.ReadFrom(buf)
+			if err != nil {
+				if ne, ok := err.(net.Error); ok && ne.Timeout() {
+					return // Deadline set below: the sweep is over
+				}
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				continue
+			}
+			key, sent, ok := p.parseReply(buf[:n], from)
+			if !ok {
+				continue
+			}
+			mu.Lock()
+			if _, known := seqs[key]; known && sent.seq == seqs[key] {
+				if _, dup := rtts[key]; !dup {
+					rtts[key] = time.Since(sent.at)
+				}
+			}
+			mu.Unlock()
+		}
+	}()
+
+	for attempt := 0; attempt < pingAttempts; attempt++ {
+		sent := 0
+		for _, addr := range targets {
+			mu.Lock()
+			_, answered := rtts[addr]
+			mu.Unlock()
+			if answered {
+				continue
+			}
+			limiter.wait()
+			p.send(addr, seqs[addr])
+			sent++
+			if sent%pingBatchSize == 0 {
+				time.Sleep(pingBatchInterval)
+			}
+		}
+		if sent == 0 {
+			break
+		}
+		p.wait(&mu, rtts, len(targets))
+	}
+
+	p.conn.SetReadDeadline(time.Now())
+	<-done
+	return rtts
+}
+
+// wait returns after pingTimeout, or earlier once every target replied.
+func (p *pinger) wait(mu *sync.Mutex, rtts map[netip.Addr]time.Duration, total int) {
+	deadline := time.Now().Add(pingTimeout)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(rtts)
+		mu.Unlock()
+		if n == total {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// send writes one echo request. The payload carries the send time, so a
+// late reply to an earlier attempt still yields its own round-trip time.
+func (p *pinger) send(addr netip.Addr, seq uint16) {
+	msg := make([]byte, 16)
+	msg[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(msg[4:], p.id)
+	binary.BigEndian.PutUint16(msg[6:], seq)
+	binary.BigEndian.PutUint64(msg[8:], uint64(time.Now().UnixNano()))
+	if p.v6 {
+		// The kernel fills in the ICMPv6 checksum, which covers the IPv6
+		// pseudo-header (RFC 3542 3.1)
+		msg[0] = icmp6EchoRequest
+	} else {
+		binary.BigEndian.PutUint16(msg[2:], icmpChecksum(msg))
+	}
+
+	var dst net.Addr = &net.IPAddr{IP: addr.AsSlice(), Zone: addr.Zone()}
+	if p.datagram {
+		dst = &net.UDPAddr{IP: addr.AsSlice(), Zone: addr.Zone()}
+	}
+	for retry := 0; retry < 3; retry++ {
+		_, err := p.conn.WriteTo(msg, dst)
+		// The socket buffer fills up on large sweeps; give it a moment
+		if !errors.Is(err, syscall.ENOBUFS) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type echoSent struct {
+	seq uint16
+	at  time.Time
+}
+
+// parseReply decodes an echo reply and returns its source and the request
+// it answers.
+func (p *pinger) parseReply(b []byte, from net.Addr) (netip.Addr, echoSent, bool) {
+	reply := byte(icmpEchoReply)
+	if p.v6 {
+		reply = icmp6EchoReply
+	}
+	// Raw sockets on some systems, and macOS datagram sockets, include
+	// the IPv4 header. An ICMP message never starts with 0x4_. IPv6
+	// sockets never include the header.
+	if !p.v6 && len(b) >= 20 && b[0]>>4 == 4 {
+		hlen := int(b[0]&0x0f) * 4
+		if len(b) < hlen {
+			return netip.Addr{}, echoSent{}, false
+		}
+		b = b[hlen:]
+	}
+	if len(b) < 16 || b[0] != reply || b[1] != 0 {
+		return netip.Addr{}, echoSent{}, false
+	}
+	if p.checkID && binary.BigEndian.Uint16(b[4:]) != p.id {
+		return netip.Addr{}, echoSent{}, false
+	}
+
+	var ip net.IP
+	var zone string
+	switch a := from.(type) {
+	case *net.IPAddr:
+		ip, zone = a.IP, a.Zone
+	case *net.UDPAddr:
+		ip, zone = a.IP, a.Zone
+	default:
+		return netip.Addr{}, echoSent{}, false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}, echoSent{}, false
+	}
+	addr = addr.Unmap()
+	if addr.Is6() && addr.IsLinkLocalUnicast() {
+		addr = addr.WithZone(zone)
+	}
+	sent := echoSent{
+		seq: binary.BigEndian.Uint16(b[6:]),
+		at:  time.Unix(0, int64(binary.BigEndian.Uint64(b[8:]))),
+	}
+	return addr, sent, true
+}
+
+// icmpChecksum is the Internet checksum (RFC 1071) of an ICMP message
+// whose checksum field is zero.
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,49 @@
+//go:build linux || darwin
+
+package scanner
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+)
+
+// listenICMPDatagram opens an unprivileged ICMP socket (SOCK_DGRAM with
+// IPPROTO_ICMP or IPPROTO_ICMPV6) for local's family, bound to local unless
+// it is unspecified. macOS allows it for every user; Linux only for groups
+// in net.ipv4.ping_group_range.
+func listenICMPDatagram(local netip.Addr) (net.PacketConn, error) {
+	family, proto := syscall.AF_INET, syscall.IPPROTO_ICMP
+	var sa syscall.Sockaddr
+	if local.Is6() {
+		family, proto = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+		if !local.IsUnspecified() {
+			sa6 := &syscall.SockaddrInet6{Addr: local.As16()}
+			if local.Zone() != "" {
+				iface, err := net.InterfaceByName(local.Zone())
+				if err != nil {
+					return nil, err
+				}
+				sa6.ZoneId = uint32(iface.Index)
+			}
+			sa = sa6
+		}
+	} else if !local.IsUnspecified() {
+		sa = &syscall.SockaddrInet4{Addr: local.As4()}
+	}
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM, proto)
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(fd)
+	if sa != nil {
+		if err := syscall.Bind(fd, sa); err != nil {
+			syscall.Close(fd)
+			return nil, err
+		}
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close() // FilePacketConn holds its own duplicate
+	return net.FilePacketConn(f)
+}
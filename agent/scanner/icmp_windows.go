@@ -0,0 +1,15 @@
+//go:build windows
+
+package scanner
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+)
+
+// listenICMPDatagram is unavailable: Windows has no datagram ICMP sockets,
+// so the sweep needs a raw socket and therefore Administrator rights.
+func listenICMPDatagram(local netip.Addr) (net.PacketConn, error) {
+	return nil, errors.New("datagram ICMP sockets are not supported on Windows")
+}
@@ -0,0 +1,322 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MDNSService is a DNS-SD service instance a device advertised.
+type MDNSService struct {
+	Name       string            `json:"name"` // Instance name, e.g. "Living Room TV"
+	Type       string            `json:"type"` // e.g. "_googlecast._tcp"
+	Port       int               `json:"port,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"` // Identifying TXT keys only
+}
+
+// mdnsHost is what one address announced over multicast DNS.
+type mdnsHost struct {
+	hostname string
+	services []*MDNSService
+}
+
+const (
+	dnsTypeA   = 1
+	dnsTypePTR = 12
+	dnsTypeTXT = 16
+	dnsTypeSRV = 33
+
+	dnsSDMetaQuery = "_services._dns-sd._udp.local"
+)
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Service types asked for directly, for responders that ignore the
+// DNS-SD meta-query
+var mdnsServiceTypes = []string{
+	"_airplay._tcp", "_raop._tcp", "_googlecast._tcp", "_spotify-connect._tcp", "_sonos._tcp", "_amzn-wplay._tcp",
+	"_ipp._tcp", "_ipps._tcp", "_printer._tcp", "_pdl-datastream._tcp", "_uscan._tcp",
+	"_device-info._tcp", "_workstation._tcp", "_smb._tcp", "_ssh._tcp", "_http._tcp",
+	"_sip._udp", "_hap._tcp",
+}
+
+// TXT keys that identify the device (RFC 6763 keys are case-insensitive;
+// these are the spellings devices use)
+var mdnsTXTKeys = map[string]bool{
+	"md": true, "fn": true, // Google Cast model and friendly name
+	"model": true, "am": true, "rpMd": true, // Apple model identifiers
+	"ty": true, "usb_MFG": true, "usb_MDL": true, "product": true, "note": true, // Printers
+	"manufacturer": true, "vendor": true, "serialNumber": true,
+}
+
+// browseMDNS asks for DNS-SD services on the local link and collects the
+// answers, plus any unsolicited announcements, for window. Results are
+// keyed by the IPv4 address that sent them.
+func (p prober) browseMDNS(window time.Duration) map[string]*mdnsHost {
+	b := &mdnsBrowser{hosts: map[string]*mdnsHost{}, types: map[string]bool{}}
+
+	// Queries from an ephemeral port get unicast replies (RFC 6762 6.7);
+	// the socket joined to the group hears multicast replies and
+	// announcements
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return b.hosts
+	}
+	conns := []*net.UDPConn{conn}
+	if passive, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup); err == nil {
+		conns = append(conns, passive)
+	}
+
+	deadline := time.Now().Add(window)
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		c.SetReadDeadline(deadline)
+		wg.Add(1)
+		go func(c *net.UDPConn) {
+			defer wg.Done()
+			b.listen(c)
+		}(c)
+	}
+
+	p.global.wait()
+	conn.WriteToUDP(mdnsQuery(append([]string{dnsSDMetaQuery}, localNames(mdnsServiceTypes)...)), mdnsGroup)
+	// Ask again for the types the meta-query turned up
+	time.Sleep(min(window/3, time.Second))
+	var learned []string
+	b.mu.Lock()
+	for t := range b.types {
+		learned = append(learned, t)
+	}
+	b.mu.Unlock()
+	p.global.wait()
+	conn.WriteToUDP(mdnsQuery(append([]string{dnsSDMetaQuery}, localNames(learned)...)), mdnsGroup)
+
+	wg.Wait()
+	for _, c := range conns {
+		c.Close()
+	}
+	return b.hosts
+}
+
+type mdnsBrowser struct {
+	mu    sync.Mutex
+	hosts map[string]*mdnsHost
+	types map[string]bool // Service types seen in meta-query answers
+}
+
+func (b *mdnsBrowser) listen(conn *net.UDPConn) {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		ip := from.IP.To4()
+		if ip == nil {
+			continue
+		}
+		records, err := parseDNSResponse(buf[:n])
+		if err != nil {
+			continue
+		}
+		b.mu.Lock()
+		b.absorb(ip.String(), records)
+		b.mu.Unlock()
+	}
+}
+
+// absorb attributes a response's records to the address that sent it: a
+// responder only answers for its own services.
+func (b *mdnsBrowser) absorb(ip string, records []dnsRecord) {
+	host := func() *mdnsHost {
+		if b.hosts[ip] == nil {
+			b.hosts[ip] = &mdnsHost{}
+		}
+		return b.hosts[ip]
+	}
+	// Instance names are "<instance>._<service>._tcp.local"
+	service := func(instance []string) *MDNSService {
+		if len(instance) < 4 || !strings.HasPrefix(instance[1], "_") || (instance[2] != "_tcp" && instance[2] != "_udp") {
+			return nil
+		}
+		name, typ := instance[0], instance[1]+"."+instance[2]
+		h := host()
+		for _, s := range h.services {
+			if s.Name == name && s.Type == typ {
+				return s
+			}
+		}
+		s := &MDNSService{Name: name, Type: typ}
+		h.services = append(h.services, s)
+		return s
+	}
+
+	for _, r := range records {
+		switch r.rtype {
+		case dnsTypePTR:
+			target, err := r.name(0)
+			if err != nil {
+				continue
+			}
+			if strings.EqualFold(strings.Join(r.owner, "."), dnsSDMetaQuery) {
+				if len(target) >= 2 {
+					b.types[target[0]+"."+target[1]] = true
+				}
+				continue
+			}
+			service(target)
+		case dnsTypeSRV:
+			if len(r.rdata) < 7 {
+				continue
+			}
+			if s := service(r.owner); s != nil {
+				s.Port = int(binary.BigEndian.Uint16(r.rdata[4:]))
+			}
+			if target, err := r.name(6); err == nil && len(target) > 0 {
+				host().hostname = strings.Join(target, ".")
+			}
+		case dnsTypeTXT:
+			s := service(r.owner)
+			if s == nil {
+				continue
+			}
+			for rest := r.rdata; len(rest) > 0 && len(rest) > int(rest[0]); rest = rest[1+int(rest[0]):] {
+				key, value, _ := strings.Cut(string(rest[1:1+int(rest[0])]), "=")
+				if mdnsTXTKeys[key] && value != "" {
+					if s.Properties == nil {
+						s.Properties = map[string]string{}
+					}
+					s.Properties[key] = value
+				}
+			}
+		case dnsTypeA:
+			if len(r.rdata) == 4 && net.IP(r.rdata).String() == ip && len(r.owner) > 0 {
+				host().hostname = strings.Join(r.owner, ".")
+			}
+		}
+	}
+}
+
+// mdnsIdentity picks the make and model from the services' TXT records.
+func mdnsIdentity(services []MDNSService) (manufacturer, model string) {
+	for _, s := range services {
+		p := s.Properties
+		manufacturer = firstNonEmpty(manufacturer, p["usb_MFG"], p["manufacturer"], p["vendor"])
+		model = firstNonEmpty(model, p["usb_MDL"], p["md"], p["model"], p["am"], p["rpMd"], p["ty"], p["product"])
+	}
+	return manufacturer, model
+}
+
+func localNames(types []string) []string {
+	var names []string
+	for _, t := range types {
+		names = append(names, t+".local")
+	}
+	return names
+}
+
+// mdnsQuery builds a query asking for the PTR records of each name.
+func mdnsQuery(names []string) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[4:], uint16(len(names)))
+	for _, name := range names {
+		for _, label := range strings.Split(name, ".") {
+			msg = append(msg, byte(len(label)))
+			msg = append(msg, label...)
+		}
+		msg = append(msg, 0)
+		msg = binary.BigEndian.AppendUint16(msg, dnsTypePTR)
+		msg = binary.BigEndian.AppendUint16(msg, 1) // IN
+	}
+	return msg
+}
+
+// dnsRecord is a resource record from a DNS message. Names inside rdata
+// may be compressed against the whole message, so it is kept.
+type dnsRecord struct {
+	owner []string // Labels
+	rtype uint16
+	rdata []byte
+	msg   []byte
+	off   int // Offset of rdata in msg
+}
+
+// name decodes a domain name at offset off within the rdata.
+func (r dnsRecord) name(off int) ([]string, error) {
+	labels, _, err := readDNSName(r.msg, r.off+off)
+	return labels, err
+}
+
+var errDNS = errors.New("malformed DNS message")
+
+// parseDNSResponse returns the answer, authority and additional records
+// of a response; queries are rejected.
+func parseDNSResponse(msg []byte) ([]dnsRecord, error) {
+	if len(msg) < 12 || msg[2]&0x80 == 0 {
+		return nil, errDNS
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	count := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+	off := 12
+	for i := 0; i < questions; i++ {
+		_, next, err := readDNSName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4
+	}
+	var records []dnsRecord
+	for i := 0; i < count; i++ {
+		owner, next, err := readDNSName(msg, off)
+		if err != nil || next+10 > len(msg) {
+			return records, errDNS
+		}
+		rtype := binary.BigEndian.Uint16(msg[next:])
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		if start+length > len(msg) {
+			return records, errDNS
+		}
+		records = append(records, dnsRecord{owner: owner, rtype: rtype, rdata: msg[start : start+length], msg: msg, off: start})
+		off = start + length
+	}
+	return records, nil
+}
+
+// readDNSName decodes a possibly compressed name and returns its labels
+// and the offset just past it.
+func readDNSName(msg []byte, off int) ([]string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return nil, 0, errDNS
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return labels, end, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) || jumps > 32 {
+				return nil, 0, errDNS
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			jumps++
+		default:
+			if off+1+n > len(msg) {
+				return nil, 0, errDNS
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
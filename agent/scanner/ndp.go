@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+var allNodes = netip.MustParseAddr("ff02::1")
+
+// discoverIPv6 finds the hosts of an IPv6 prefix too large to sweep. On
+// each link where this host has an address in the prefix it pings the
+// all-nodes group from that address, so hosts answer from their own
+// address in the prefix; then it adds the prefix's entries from the
+// neighbor table, which also lists hosts that ignore multicast echo but
+// talked to this one recently.
+func discoverIPv6(prefix netip.Prefix) ([]netip.Addr, error) {
+	found := map[netip.Addr]bool{}
+	attached := false
+	var pingErr error
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, _ := iface.Addrs()
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			local, ok := netip.AddrFromSlice(ipnet.IP)
+			if !ok || !local.Is6() || local.Is4In6() || !prefix.Contains(local) {
+				continue
+			}
+			if local.IsLinkLocalUnicast() {
+				local = local.WithZone(iface.Name)
+			}
+			attached = true
+			p, err := newPinger(local)
+			if err != nil {
+				pingErr = err
+				break
+			}
+			for _, addr := range p.echoAllNodes(iface.Name) {
+				if prefix.Contains(addr.WithZone("")) {
+					found[addr] = true
+				}
+			}
+			p.Close()
+			break
+		}
+	}
+
+	for key := range readNeighbors() {
+		if addr, err := netip.ParseAddr(key); err == nil && addr.Is6() && prefix.Contains(addr.WithZone("")) {
+			found[addr] = true
+		}
+	}
+	switch {
+	case !attached && len(found) == 0:
+		return nil, fmt.Errorf("%s is not on a directly attached link and too large to sweep; list its addresses instead", prefix)
+	case pingErr != nil && len(found) == 0:
+		return nil, fmt.Errorf("IPv6 neighbor discovery in %s: %v", prefix, pingErr)
+	}
+
+	hosts := make([]netip.Addr, 0, len(found))
+	for addr := range found {
+		hosts = append(hosts, addr)
+	}
+	slices.SortFunc(hosts, netip.Addr.Compare)
+	return hosts, nil
+}
+
+// echoAllNodes pings ff02::1 on the named interface and returns every
+// address that answers within pingTimeout.
+func (p *pinger) echoAllNodes(iface string) []netip.Addr {
+	group := allNodes.WithZone(iface)
+	// Echo requests get lost too; ask twice
+	for attempt := 0; attempt < pingAttempts; attempt++ {
+		p.send(group, 0)
+	}
+
+	var hosts []netip.Addr
+	seen := map[netip.Addr]bool{}
+	p.conn.SetReadDeadline(time.Now().Add(pingTimeout))
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := p.conn.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() || errors.Is(err, net.ErrClosed) {
+				return hosts
+			}
+			continue
+		}
+		if addr, _, ok := p.parseReply(buf[:n], from); ok && !seen[addr] {
+			seen[addr] = true
+			hosts = append(hosts, addr)
+		}
+	}
+}
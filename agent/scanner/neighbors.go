@@ -0,0 +1,130 @@
+package scanner
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// neighbor is a resolved entry of the OS's ARP or IPv6 neighbor table.
+// Tables are keyed by address, with the interface as the zone of IPv6
+// link-local addresses ("fe80::1%eth0").
+type neighbor struct {
+	mac net.HardwareAddr
+	// The kernel saw the host answer recently (Linux NUD_REACHABLE). Other
+	// systems do not expose entry state, so their entries are unconfirmed.
+	confirmed bool
+}
+
+// How long solicited hosts get to answer ARP before the table is re-read
+const arpWait = time.Second
+
+// parseNeighborMAC parses the MAC notations of the various arp tools
+// ("0:11:22:3:44:55", "00-11-22-03-44-55") and rejects the placeholder,
+// broadcast and multicast entries they list alongside real neighbors.
+func parseNeighborMAC(s string) net.HardwareAddr {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == '-' })
+	if len(parts) != 6 {
+		return nil
+	}
+	for i, p := range parts {
+		if len(p) == 1 {
+			parts[i] = "0" + p
+		}
+	}
+	mac, err := net.ParseMAC(strings.Join(parts, ":"))
+	if err != nil || mac[0]&0x01 != 0 {
+		return nil
+	}
+	for _, b := range mac {
+		if b != 0 {
+			return mac
+		}
+	}
+	return nil
+}
+
+// localMACs maps this host's own addresses to their interface's MAC, since
+// the neighbor tables never list them.
+func localMACs() map[string]net.HardwareAddr {
+	macs := map[string]net.HardwareAddr{}
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if len(iface.HardwareAddr) == 0 {
+			continue
+		}
+		addrs, _ := iface.Addrs()
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok {
+				macs[neighborKey(ipnet.IP, iface.Name)] = iface.HardwareAddr
+			}
+		}
+	}
+	return macs
+}
+
+// neighborKey is the table key of ip as seen on the named interface.
+func neighborKey(ip net.IP, iface string) string {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ip.String()
+	}
+	addr = addr.Unmap()
+	if addr.Is6() && addr.IsLinkLocalUnicast() {
+		addr = addr.WithZone(iface)
+	}
+	return addr.String()
+}
+
+// onLink reports whether ip is on a directly attached subnet, where ARP
+// can reach it.
+func onLink(ip net.IP, subnets []*net.IPNet) bool {
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func attachedSubnets() []*net.IPNet {
+	var subnets []*net.IPNet
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, _ := iface.Addrs()
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				subnets = append(subnets, ipnet)
+			}
+		}
+	}
+	return subnets
+}
+
+// solicitARP makes the kernel send ARP requests for the on-link targets by
+// sending each one a datagram to the discard port. Hosts that answer ARP
+// show up in the neighbor table even when they drop everything else.
+func solicitARP(targets []string) int {
+	subnets := attachedSubnets()
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return 0
+	}
+	defer conn.Close()
+
+	sent := 0
+	for _, target := range targets {
+		ip := net.ParseIP(target)
+		if ip == nil || !onLink(ip, subnets) {
+			continue
+		}
+		if _, err := conn.WriteToUDP(nil, &net.UDPAddr{IP: ip, Port: 9}); err == nil {
+			sent++
+		}
+	}
+	return sent
+}
@@ -0,0 +1,52 @@
+//go:build darwin
+
+package scanner
+
+import (
+	"net/netip"
+	"os/exec"
+	"strings"
+)
+
+// readNeighbors parses `arp -an` and `ndp -an`:
+//
+//	? (192.168.1.1) at 0:11:22:33:44:55 on en0 ifscope [ethernet]
+//	? (192.168.1.7) at (incomplete) on en0 ifscope [ethernet]
+//
+//	Neighbor                      Linklayer Address  Netif Expire    St Flgs Prbs
+//	fe80::1%en0                   0:11:22:33:44:55     en0 23h59m58s S  R
+func readNeighbors() map[string]neighbor {
+	neighbors := map[string]neighbor{}
+	if out, err := exec.Command("arp", "-an").Output(); err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			lp, rp := strings.Index(line, "("), strings.Index(line, ")")
+			_, rest, ok := strings.Cut(line, " at ")
+			if lp < 0 || rp < lp || !ok {
+				continue
+			}
+			fields := strings.Fields(rest)
+			if len(fields) == 0 {
+				continue
+			}
+			if mac := parseNeighborMAC(fields[0]); mac != nil {
+				neighbors[line[lp+1:rp]] = neighbor{mac: mac}
+			}
+		}
+	}
+	if out, err := exec.Command("ndp", "-an").Output(); err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			addr, err := netip.ParseAddr(fields[0])
+			if err != nil {
+				continue
+			}
+			if mac := parseNeighborMAC(fields[1]); mac != nil {
+				neighbors[addr.String()] = neighbor{mac: mac}
+			}
+		}
+	}
+	return neighbors
+}
@@ -0,0 +1,104 @@
+//go:build linux
+
+package scanner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Neighbor states (include/uapi/linux/neighbour.h)
+const (
+	nudReachable = 0x02
+	ndaDst       = 1
+	ndaLLAddr    = 2
+)
+
+// readNeighbors dumps the IPv4 and IPv6 neighbor tables over netlink
+// (RTM_GETNEIGH), falling back to /proc/net/arp.
+func readNeighbors() map[string]neighbor {
+	if neighbors, err := netlinkNeighbors(); err == nil {
+		return neighbors
+	}
+	return procNetARP()
+}
+
+func netlinkNeighbors() (map[string]neighbor, error) {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors := map[string]neighbor{}
+	for _, m := range msgs {
+		// struct ndmsg: family, pad, pad, ifindex (4), state (2), flags, type
+		if m.Header.Type != syscall.RTM_NEWNEIGH || len(m.Data) < 12 {
+			continue
+		}
+		index := int(binary.NativeEndian.Uint32(m.Data[4:8]))
+		state := binary.NativeEndian.Uint16(m.Data[8:10])
+		var ip net.IP
+		var mac net.HardwareAddr
+		for attrs := m.Data[12:]; len(attrs) >= 4; {
+			length := int(binary.NativeEndian.Uint16(attrs[0:2]))
+			if length < 4 || length > len(attrs) {
+				break
+			}
+			value := attrs[4:length]
+			switch binary.NativeEndian.Uint16(attrs[2:4]) {
+			case ndaDst:
+				ip = net.IP(value)
+			case ndaLLAddr:
+				mac = parseNeighborMAC(net.HardwareAddr(value).String())
+			}
+			// Attributes are padded to 4 bytes
+			next := (length + 3) &^ 3
+			if next > len(attrs) {
+				break
+			}
+			attrs = attrs[next:]
+		}
+		if ip != nil && mac != nil {
+			zone := ""
+			if iface, err := net.InterfaceByIndex(index); err == nil {
+				zone = iface.Name
+			}
+			neighbors[neighborKey(ip, zone)] = neighbor{mac: mac, confirmed: state&nudReachable != 0}
+		}
+	}
+	return neighbors, nil
+}
+
+// procNetARP reads the complete entries (flags 0x2) of /proc/net/arp:
+//
+//	IP address       HW type     Flags       HW address            Mask     Device
+//	192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0
+func procNetARP() map[string]neighbor {
+	neighbors := map[string]neighbor{}
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return neighbors
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[2] == "0x0" {
+			continue
+		}
+		if mac := parseNeighborMAC(fields[3]); mac != nil {
+			neighbors[fields[0]] = neighbor{mac: mac}
+		}
+	}
+	return neighbors
+}
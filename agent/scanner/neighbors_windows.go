@@ -0,0 +1,34 @@
+//go:build windows
+
+package scanner
+
+import (
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// readNeighbors parses `arp -a` and `netsh interface ipv6 show neighbors`.
+// Only the entry lines are used; the interface headers are localized.
+//
+//	192.168.1.1           00-11-22-33-44-55     dynamic
+//	fe80::1               00-11-22-33-44-55     Reachable
+func readNeighbors() map[string]neighbor {
+	neighbors := map[string]neighbor{}
+	for _, args := range [][]string{{"arp", "-a"}, {"netsh", "interface", "ipv6", "show", "neighbors"}} {
+		out, err := exec.Command(args[0], args[1:]...).Output()
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+				continue
+			}
+			if mac := parseNeighborMAC(fields[1]); mac != nil {
+				neighbors[fields[0]] = neighbor{mac: mac}
+			}
+		}
+	}
+	return neighbors
+}
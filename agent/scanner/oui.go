@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The embedded registry is refreshed from upstream with `go generate`.
+//
+//go:generate curl -fsSL -o oui/oui.txt https://standards-oui.ieee.org/oui/oui.txt
+
+//go:embed oui/oui.txt
+var embeddedOUIs []byte
+
+// ouiRegistry maps upper-case hex MAC prefixes to organization names. MA-L
+// assignments are 6 hex digits; MA-M and MA-S (from the CSV files) are 7
+// and 9.
+type ouiRegistry map[string]string
+
+var (
+	ouiOnce sync.Once
+	ouis    ouiRegistry
+)
+
+// loadOUIRegistry prefers the OS's copy of the IEEE registry from the
+// ieee-data or hwdata package, which is kept up to date, over the one
+// embedded in the binary.
+func loadOUIRegistry() ouiRegistry {
+	groups := [][]string{
+		{"/usr/share/ieee-data/oui.csv", "/usr/share/ieee-data/mam.csv", "/usr/share/ieee-data/oui36.csv"},
+		{"/usr/share/ieee-data/oui.txt"},
+		{"/usr/share/hwdata/oui.txt"},
+	}
+	for _, paths := range groups {
+		reg := ouiRegistry{}
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			if strings.HasSuffix(path, ".csv") {
+				reg.parseCSV(content)
+			} else {
+				reg.parseText(content)
+			}
+		}
+		if len(reg) > 0 {
+			return reg
+		}
+	}
+	reg := ouiRegistry{}
+	reg.parseText(embeddedOUIs)
+	return reg
+}
+
+func getOUIRegistry() ouiRegistry {
+	ouiOnce.Do(func() { ouis = loadOUIRegistry() })
+	return ouis
+}
+
+// parseText reads the "(hex)" lines of oui.txt:
+//
+//	00-00-0C   (hex)		Cisco Systems, Inc
+func (reg ouiRegistry) parseText(content []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		prefix, rest, ok := strings.Cut(scanner.Text(), "(hex)")
+		if !ok {
+			continue
+		}
+		prefix = strings.ReplaceAll(strings.TrimSpace(prefix), "-", "")
+		if len(prefix) == 6 && isHex(prefix) {
+			reg[strings.ToUpper(prefix)] = strings.TrimSpace(rest)
+		}
+	}
+}
+
+// parseCSV reads the registry CSV exports:
+//
+//	MA-L,00000C,"Cisco Systems, Inc",170 WEST TASMAN DRIVE SAN JOSE CA US 95134
+func (reg ouiRegistry) parseCSV(content []byte) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil || len(record) < 3 || !strings.HasPrefix(record[0], "MA-") {
+			continue
+		}
+		if prefix := strings.ToUpper(record[1]); isHex(prefix) {
+			reg[prefix] = strings.TrimSpace(record[2])
+		}
+	}
+}
+
+// lookup returns the organization a MAC address was assigned to, trying
+// the longest (MA-S) prefix first. Locally administered addresses, such as
+// randomized Wi-Fi MACs and most VM defaults, have no vendor.
+func (reg ouiRegistry) lookup(mac net.HardwareAddr) string {
+	if len(mac) < 6 || mac[0]&0x02 != 0 {
+		return ""
+	}
+	hex := strings.ToUpper(strings.ReplaceAll(mac.String(), ":", ""))
+	for _, n := range []int{9, 7, 6} {
+		if vendor, ok := reg[hex[:n]]; ok {
+			return vendor
+		}
+	}
+	return ""
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return s != ""
+}
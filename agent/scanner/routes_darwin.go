@@ -0,0 +1,59 @@
+//go:build darwin
+
+package scanner
+
+import (
+	"net/netip"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// onLinkRoutes parses the network routes of `netstat -rn -f inet` whose
+// gateway is a link rather than an address. Destinations drop trailing
+// zero octets and, when classful, the prefix length:
+//
+//	Destination        Gateway            Flags           Netif Expire
+//	default            192.168.1.1        UGScg             en0
+//	192.168.1          link#6             UCS               en0      !
+//	10.20/16           link#6             UCS               en0      !
+func onLinkRoutes() []onLinkRoute {
+	out, err := exec.Command("netstat", "-rn", "-f", "inet").Output()
+	if err != nil {
+		return nil
+	}
+	var routes []onLinkRoute
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[1], "link#") || strings.Contains(fields[2], "H") {
+			continue
+		}
+		if prefix, ok := parseBSDDestination(fields[0]); ok {
+			routes = append(routes, onLinkRoute{prefix: prefix, iface: fields[3]})
+		}
+	}
+	return routes
+}
+
+func parseBSDDestination(dest string) (netip.Prefix, bool) {
+	dest, bitsText, hasBits := strings.Cut(dest, "/")
+	octets := strings.Split(dest, ".")
+	if len(octets) > 4 {
+		return netip.Prefix{}, false
+	}
+	bits := 8 * len(octets)
+	for len(octets) < 4 {
+		octets = append(octets, "0")
+	}
+	addr, err := netip.ParseAddr(strings.Join(octets, "."))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	if hasBits {
+		if bits, err = strconv.Atoi(bitsText); err != nil {
+			return netip.Prefix{}, false
+		}
+	}
+	prefix, err := addr.Prefix(bits)
+	return prefix, err == nil
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package scanner
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// onLinkRoutes dumps the main IPv4 routing table over netlink
+// (RTM_GETROUTE) and keeps the link-scope unicast routes.
+func onLinkRoutes() []onLinkRoute {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETROUTE, syscall.AF_INET)
+	if err != nil {
+		return nil
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil
+	}
+
+	var routes []onLinkRoute
+	for _, m := range msgs {
+		// struct rtmsg: family, dst_len, src_len, tos, table, protocol,
+		// scope, type, flags (4)
+		if m.Header.Type != syscall.RTM_NEWROUTE || len(m.Data) < 12 {
+			continue
+		}
+		dstLen, table, scope, typ := int(m.Data[1]), m.Data[4], m.Data[6], m.Data[7]
+		if table != syscall.RT_TABLE_MAIN || scope != syscall.RT_SCOPE_LINK || typ != syscall.RTN_UNICAST || dstLen == 0 {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(&m)
+		if err != nil {
+			continue
+		}
+		var dst netip.Addr
+		var iface string
+		for _, a := range attrs {
+			switch a.Attr.Type {
+			case syscall.RTA_DST:
+				dst, _ = netip.AddrFromSlice(a.Value)
+			case syscall.RTA_OIF:
+				if len(a.Value) == 4 {
+					if i, err := net.InterfaceByIndex(int(binary.NativeEndian.Uint32(a.Value))); err == nil {
+						iface = i.Name
+					}
+				}
+			}
+		}
+		if dst.IsValid() && iface != "" {
+			routes = append(routes, onLinkRoute{prefix: netip.PrefixFrom(dst, dstLen), iface: iface})
+		}
+	}
+	return routes
+}
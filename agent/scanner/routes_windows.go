@@ -0,0 +1,57 @@
+//go:build windows
+
+package scanner
+
+import (
+	"net"
+	"net/netip"
+	"os/exec"
+	"strings"
+)
+
+// onLinkRoutes parses the IPv4 routes of `route print -4` whose gateway is
+// not an address ("On-link", localized). The interface is given by its
+// address:
+//
+//	Network Destination        Netmask          Gateway       Interface  Metric
+//	      192.168.1.0    255.255.255.0         On-link     192.168.1.20    281
+func onLinkRoutes() []onLinkRoute {
+	out, err := exec.Command("route", "print", "-4").Output()
+	if err != nil {
+		return nil
+	}
+	ifaceByAddr := map[string]string{}
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		addrs, _ := iface.Addrs()
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok {
+				ifaceByAddr[ipnet.IP.String()] = iface.Name
+			}
+		}
+	}
+
+	var routes []onLinkRoute
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		dest, err1 := netip.ParseAddr(fields[0])
+		mask := net.ParseIP(fields[1]).To4()
+		if err1 != nil || mask == nil || net.ParseIP(fields[len(fields)-3]) != nil {
+			continue
+		}
+		// The gateway may be several words once localized; the interface
+		// and metric are always the last two fields
+		iface, ok := ifaceByAddr[fields[len(fields)-2]]
+		if !ok {
+			continue
+		}
+		bits, _ := net.IPMask(mask).Size()
+		if prefix, err := dest.Prefix(bits); err == nil && bits > 0 {
+			routes = append(routes, onLinkRoute{prefix: prefix, iface: iface})
+		}
+	}
+	return routes
+}
@@ -1,10 +1,13 @@
 package scanner
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"net"
-	"os/exec"
-	"runtime"
+	"net/netip"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -13,66 +16,347 @@ import (
 type Device struct {
 	IP       string   `json:"ip"`
 	Hostname string   `json:"hostname"`
-	Mac      string   `json:"mac"` // MAC is hard to get without ARP table access or root, might skip for now or use ARP cli
+	Mac      string   `json:"mac"` // From the ARP table, so only for hosts on directly attached subnets
 	Ports    []int    `json:"open_ports"`
-	Vendor   string   `json:"vendor"` // Can be inferred from MAC OUI if we had it
+	Vendor   string   `json:"vendor"` // IEEE registry organization for the MAC's OUI
 	Status   string   `json:"status"` // "online"
+	RTTMs    float64  `json:"rtt_ms,omitempty"` // ICMP round-trip time; 0 if the host only answered TCP
+	Services   []Banner `json:"services,omitempty"` // What each open port revealed
+	DeviceType string   `json:"device_type"` // One of the DeviceType constants
+	OSGuess    string   `json:"os_guess,omitempty"`
+	Confidence int      `json:"confidence"` // 0-100, for DeviceType
+	Make         string    `json:"make,omitempty"` // From SNMP
+	Model        string    `json:"model,omitempty"`
+	SerialNumber string    `json:"serial_number,omitempty"`
+	SNMP         *SNMPInfo `json:"snmp,omitempty"`
+	MDNS         []MDNSService `json:"mdns,omitempty"` // DNS-SD services the device advertised
+	UPnP         *UPnPDevice   `json:"upnp,omitempty"`
+	DiscoveredBy []string      `json:"discovered_by"` // "icmp", "ndp", "arp", "mdns", "ssdp", "tcp"
 }
 
 type ScanResult struct {
-	Range   string   `json:"range"`
-	Devices []Device `json:"devices"`
+	Range     string    `json:"range"`
+	ScannedAt string    `json:"scanned_at"` // RFC 3339, when the scan started
+	Devices   []Device  `json:"devices"`
+	Diff      *ScanDiff `json:"diff,omitempty"` // Changes since the previous scan, filled in by the caller
 }
 
-// Scan performs a network scan on the given CIDR
-func Scan(cidr string) (*ScanResult, error) {
-	ip, ipnet, err := net.ParseCIDR(cidr)
+// Options tunes a network scan.
+type Options struct {
+	ActiveARP bool              // Solicit ARP from silent on-link hosts and count those that answer as online
+	SNMP      []SNMPCredentials // Tried in order against every online host; empty disables SNMP
+	Multicast bool              // Browse mDNS/DNS-SD and search SSDP on the local link
+	PJL       bool              // Query JetDirect ports on every host, not only those that otherwise look like printers
+	Exclude   []string          // Addresses, CIDRs, ranges and hostnames never probed
+
+	Ports           []int         // TCP ports probed on every online host; nil uses the "standard" profile
+	ReachPorts      []int         // Ports tried on hosts that ignored ping; nil uses the "quick" profile
+	Timeout         time.Duration // Wait for each connect or reply, times exchangeTimeouts for exchanges; 0 uses 500ms
+	Concurrency     int           // Hosts probed at once; 0 uses 50
+	HostConcurrency int           // Ports probed at once on one host; 0 probes them one by one
+	Rate            int           // Probes of any kind per second across the scan; 0 is unlimited
+	HostRate        int           // TCP connects and SNMP requests per second to one host; 0 is unlimited
+}
+
+// withDefaults fills in the tuning left unset.
+func (o Options) withDefaults() Options {
+	if o.Ports == nil {
+		o.Ports, _ = ParsePorts("standard")
+	}
+	if o.ReachPorts == nil {
+		o.ReachPorts, _ = ParsePorts("quick")
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 500 * time.Millisecond
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 50
+	}
+	if o.HostConcurrency <= 0 {
+		o.HostConcurrency = 1
+	}
+	return o
+}
+
+// How long mDNS and SSDP discovery listen for answers and announcements
+const multicastWindow = 3 * time.Second
+
+// Addresses swept and probed at a time, so large ranges are never held in
+// memory whole
+const scanBatchSize = 4096
+
+// Scan probes the targets: a comma-separated list of CIDRs, address ranges
+// (10.0.0.5-10.0.0.50), addresses and hostnames, IPv4 or IPv6. See
+// ParseTargets.
+func Scan(spec string, opts Options) (*ScanResult, error) {
+	started := time.Now().UTC()
+	opts = opts.withDefaults()
+	for _, c := range opts.SNMP {
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("invalid SNMP credentials: %v", err)
+		}
+	}
+	targets, err := ParseTargets(spec, opts.Exclude)
 	if err != nil {
-		return nil, fmt.Errorf("invalid CIDR: %v", err)
+		return nil, err
+	}
+
+	s := &scan{
+		opts:    opts,
+		targets: targets,
+		rate:    newRateLimiter(opts.Rate),
+		ndp:     map[netip.Addr]bool{},
+		pingers: map[bool]*pinger{},
+	}
+	defer s.close()
+
+	// Multicast discovery runs alongside the first sweep and finds devices
+	// that drop ICMP and TCP probes but announce themselves
+	if opts.Multicast {
+		probe := prober{timeout: opts.Timeout, parallel: 1, global: s.rate}
+		s.discovery.Add(2)
+		go func() {
+			defer s.discovery.Done()
+			s.mdnsHosts = probe.browseMDNS(multicastWindow)
+		}()
+		go func() {
+			defer s.discovery.Done()
+			s.upnpDevices = probe.searchSSDP(multicastWindow, targets)
+		}()
+	}
+
+	// IPv6 prefixes are too large to sweep; ask the link who is there
+	var discovered []netip.Addr
+	for _, prefix := range targets.discover {
+		addrs, err := discoverIPv6(prefix)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		for _, addr := range addrs {
+			if s.ndp[addr] || targets.excluded(addr) {
+				continue
+			}
+			s.ndp[addr] = true
+			if !targets.covers(addr) {
+				discovered = append(discovered, addr)
+			}
+		}
 	}
 
-	var ips []string
-	for ip := ip.Mask(ipnet.Mask); ipnet.Contains(ip); inc(ip) {
-		ips = append(ips, ip.String())
+	foundDevices := []Device{}
+	batch := make([]netip.Addr, 0, scanBatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			foundDevices = append(foundDevices, s.probe(batch)...)
+			batch = batch[:0]
+		}
+	}
+	for addr := range targets.all() {
+		if batch = append(batch, addr); len(batch) == scanBatchSize {
+			flush()
+		}
+	}
+	for _, addr := range discovered {
+		if batch = append(batch, addr); len(batch) == scanBatchSize {
+			flush()
+		}
 	}
+	flush()
+	s.discovery.Wait()
+
+	// Read the neighbor tables last: the probes above resolved every
+	// on-link host
+	neighbors := readNeighbors()
+	local := localMACs()
+	registry := getOUIRegistry()
+	for i := range foundDevices {
+		d := &foundDevices[i]
+		mac := neighbors[d.IP].mac
+		if mac == nil {
+			mac = local[d.IP]
+		}
+		if mac != nil {
+			d.Mac = mac.String()
+			d.Vendor = registry.lookup(mac)
+		}
+		Classify(d)
+	}
+
+	return &ScanResult{
+		Range:     spec,
+		ScannedAt: started.Format(time.RFC3339),
+		Devices:   foundDevices,
+	}, nil
+}
+
+// scan is the state shared by the batches of one Scan.
+type scan struct {
+	opts        Options
+	targets     *Targets
+	rate        *rateLimiter
+	discovery   sync.WaitGroup
+	mdnsHosts   map[string]*mdnsHost
+	upnpDevices map[string]*UPnPDevice
+	ndp         map[netip.Addr]bool // Found by IPv6 neighbor discovery
+	pingers     map[bool]*pinger    // By IPv6; nil once ICMP proved unavailable
+}
 
-	// Remove network and broadcast addresses (simple heuristic: first and last)
-	if len(ips) > 2 {
-		ips = ips[1 : len(ips)-1]
+func (s *scan) close() {
+	for _, p := range s.pingers {
+		if p != nil {
+			p.Close()
+		}
 	}
+}
 
-	results := make(chan Device, len(ips))
+// sweep pings the batch, one ICMP sweep per address family; hosts that
+// stay silent get the TCP fallback.
+func (s *scan) sweep(batch []netip.Addr) map[netip.Addr]time.Duration {
+	families := map[bool][]netip.Addr{}
+	for _, addr := range batch {
+		families[addr.Is6()] = append(families[addr.Is6()], addr)
+	}
+	rtts := map[netip.Addr]time.Duration{}
+	for v6, addrs := range families {
+		p, opened := s.pingers[v6]
+		if !opened {
+			local := netip.IPv4Unspecified()
+			if v6 {
+				local = netip.IPv6Unspecified()
+			}
+			var err error
+			if p, err = newPinger(local); err != nil {
+				fmt.Printf("Warning: ICMP unavailable, detecting hosts by TCP connect only: %v\n", err)
+			}
+			s.pingers[v6] = p
+		}
+		if p != nil {
+			maps.Copy(rtts, p.sweep(addrs, s.rate))
+		}
+	}
+	return rtts
+}
+
+// probe finds which addresses of the batch are online and gathers what
+// each one reveals.
+func (s *scan) probe(batch []netip.Addr) []Device {
+	opts := s.opts
+	rtts := s.sweep(batch)
+
+	// Hosts that drop ICMP and TCP still have to answer ARP on the local
+	// segment
+	arpAlive := map[string]bool{}
+	if opts.ActiveARP {
+		before := readNeighbors()
+		var silent []string
+		for _, addr := range batch {
+			_, pinged := rtts[addr]
+			_, known := before[addr.String()]
+			if addr.Is4() && !pinged && !known {
+				silent = append(silent, addr.String())
+			}
+		}
+		if solicitARP(silent) > 0 {
+			time.Sleep(arpWait)
+		}
+		for ip, n := range readNeighbors() {
+			if _, known := before[ip]; n.confirmed || !known {
+				arpAlive[ip] = true
+			}
+		}
+	}
+
+	s.discovery.Wait()
+
+	results := make(chan Device, len(batch))
 	var wg sync.WaitGroup
 
 	// Worker pool pattern could be better, but for /24 (254 IPs), straight goroutines are "okay" in Go.
 	// Limiting concurrency is safer.
-	semaphore := make(chan struct{}, 50) // Max 50 concurrent scans
+	semaphore := make(chan struct{}, opts.Concurrency)
 
-	for _, targetIP := range ips {
+	for _, target := range batch {
 		wg.Add(1)
-		go func(ip string) {
+		go func(addr netip.Addr) {
 			defer wg.Done()
 			semaphore <- struct{}{} // Acquire
 			defer func() { <-semaphore }() // Release
+			probe := prober{timeout: opts.Timeout, parallel: opts.HostConcurrency, global: s.rate, host: newRateLimiter(opts.HostRate)}
+			ip := addr.String()
 
-			if isReachable(ip) {
+			rtt, pinged := rtts[addr]
+			var via []string
+			if pinged {
+				via = append(via, "icmp")
+			}
+			if s.ndp[addr] {
+				via = append(via, "ndp")
+			}
+			if addr.Is4() && arpAlive[ip] {
+				via = append(via, "arp")
+			}
+			if s.mdnsHosts[ip] != nil {
+				via = append(via, "mdns")
+			}
+			if s.upnpDevices[ip] != nil {
+				via = append(via, "ssdp")
+			}
+			if len(via) == 0 && probe.reachable(ip, opts.ReachPorts) {
+				via = append(via, "tcp")
+			}
+			if len(via) > 0 {
 				d := Device{
 					IP:     ip,
 					Status: "online",
+					DiscoveredBy: via,
+				}
+				if pinged {
+					d.RTTMs = float64(rtt.Microseconds()) / 1000
 				}
 				
 				// Resolve Hostname
-				names, _ := net.LookupAddr(ip)
+				names := probe.lookupAddr(ip)
 				if len(names) > 0 {
 					d.Hostname = strings.TrimSuffix(names[0], ".")
 				}
 
+				if len(opts.SNMP) > 0 {
+					if info, err := probe.querySNMP(net.JoinHostPort(ip, strconv.Itoa(snmpPort)), opts.SNMP); err == nil {
+						d.SNMP = info
+						d.Make, d.Model, d.SerialNumber = info.identity()
+						if d.Hostname == "" {
+							d.Hostname = info.SysName
+						}
+					}
+				}
+
 				// Scan common ports to guess type
-				d.Ports = scanPorts(ip)
+				d.Ports = probe.open(ip, opts.Ports)
+				d.Services = probe.grabBanners(ip, d.Ports, opts.PJL || s.looksLikePrinter(ip, d))
+
+				// Fill in what SNMP didn't say from UPnP, then mDNS
+				if u := s.upnpDevices[ip]; u != nil {
+					d.UPnP = u
+					d.Make = firstNonEmpty(d.Make, u.Manufacturer)
+					d.Model = firstNonEmpty(d.Model, u.ModelName, u.ModelNumber)
+					d.SerialNumber = firstNonEmpty(d.SerialNumber, u.SerialNumber)
+				}
+				if h := s.mdnsHosts[ip]; h != nil {
+					for _, svc := range h.services {
+						d.MDNS = append(d.MDNS, *svc)
+					}
+					manufacturer, model := mdnsIdentity(d.MDNS)
+					d.Make = firstNonEmpty(d.Make, manufacturer)
+					d.Model = firstNonEmpty(d.Model, model)
+					d.Hostname = firstNonEmpty(d.Hostname, h.hostname)
+				}
+				// Last resort: the name it was given as a target
+				d.Hostname = firstNonEmpty(d.Hostname, s.targets.names[addr])
 				
 				results <- d
 			}
-		}(targetIP)
+		}(target)
 	}
 
 	go func() {
@@ -80,81 +364,107 @@ func Scan(cidr string) (*ScanResult, error) {
 		close(results)
 	}()
 
-	foundDevices := []Device{}
+	var devices []Device
 	for d := range results {
-		foundDevices = append(foundDevices, d)
+		devices = append(devices, d)
 	}
-
-	return &ScanResult{
-		Range:   cidr,
-		Devices: foundDevices,
-	}, nil
+	return devices
 }
 
-func inc(ip net.IP) {
-	for j := len(ip) - 1; j >= 0; j-- {
-		ip[j]++
-		if ip[j] > 0 {
-			break
+// looksLikePrinter reports whether anything besides an open JetDirect port
+// says the host prints: IPP, the SNMP Printer MIB, or a print service
+// announced over mDNS or UPnP. Anything listening on 9100 (node_exporter,
+// for one) would otherwise get PJL, and a printer that doesn't speak it
+// prints the probe as a junk page.
+func (s *scan) looksLikePrinter(ip string, d Device) bool {
+	if slices.Contains(d.Ports, 631) || (d.SNMP != nil && d.SNMP.Printer != nil) {
+		return true
+	}
+	if u := s.upnpDevices[ip]; u != nil && strings.Contains(strings.ToLower(u.DeviceType), ":printer:") {
+		return true
+	}
+	if h := s.mdnsHosts[ip]; h != nil {
+		for _, svc := range h.services {
+			if slices.Contains(printServiceTypes, svc.Type) {
+				return true
+			}
 		}
 	}
+	return false
 }
 
-func isReachable(ip string) bool {
-	// 1. Try simple Ping
-	if ping(ip) {
-		return true
-	}
-	// 2. Fallback: Try a quick TCP connect to common ports (80, 443, 135, 445)
-	// Some firewalls block ICMP but allow SMB/HTTP
-	ports := []int{80, 443, 135, 445, 22}
-	for _, p := range ports {
-		if checkPort(ip, p) {
+// Exchanges of several round trips (banner grabs, UPnP descriptions,
+// reverse DNS) may take this many timeouts
+const exchangeTimeouts = 4
+
+// prober makes the network probes for one host, keeping to the scan's
+// timeout and rate limits.
+type prober struct {
+	timeout  time.Duration
+	parallel int          // Ports probed at once
+	global   *rateLimiter // Shared by the whole scan
+	host     *rateLimiter
+}
+
+// reachable is the fallback for hosts that did not answer ICMP: some
+// firewalls block ping but allow SMB/HTTP/SSH.
+func (p prober) reachable(ip string, ports []int) bool {
+	for start := 0; start < len(ports); start += p.parallel {
+		if len(p.open(ip, ports[start:min(start+p.parallel, len(ports))])) > 0 {
 			return true
 		}
 	}
 	return false
 }
 
-func ping(ip string) bool {
-	var cmd *exec.Cmd
-	
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.Command("ping", "-n", "1", "-w", "500", ip)
-	case "darwin":
-		cmd = exec.Command("ping", "-c", "1", "-W", "500", ip) // -W is in ms on some macOS pings? No, usually ms. wait.. macOS ping -W is ms? man ping says -W wait time in ms.
-		// Actually macOS ping -W is milliseconds? Let's check. man says: -W waittime. "Time in milliseconds to wait for a reply".
-		// But BSD ping -W is usually in ms. Linux ping -W is seconds. 
-		// To be safe, standard timeout 1 second.
-		cmd = exec.Command("ping", "-c", "1", "-t", "1", ip) 
-	default: // Linux
-		cmd = exec.Command("ping", "-c", "1", "-W", "1", ip) // -W in seconds
+// open returns the ports that accepted a connection, in order.
+func (p prober) open(ip string, ports []int) []int {
+	accepted := make([]bool, len(ports))
+	sem := make(chan struct{}, p.parallel)
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i, port int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			accepted[i] = p.check(ip, port)
+		}(i, port)
 	}
+	wg.Wait()
 
-	err := cmd.Run()
-	return err == nil
-}
-
-func scanPorts(ip string) []int {
 	var open []int
-	// Common ports for fingerprinting
-	targets := []int{21, 22, 23, 80, 443, 445, 3389, 8080, 9100} // 9100 = printer
-	
-	for _, p := range targets {
-		if checkPort(ip, p) {
-			open = append(open, p)
+	for i, ok := range accepted {
+		if ok {
+			open = append(open, ports[i])
 		}
 	}
 	return open
 }
 
-func checkPort(ip string, port int) bool {
-	address := fmt.Sprintf("%s:%d", ip, port)
-	conn, err := net.DialTimeout("tcp", address, 500*time.Millisecond)
+func (p prober) check(ip string, port int) bool {
+	conn, err := p.dial(net.JoinHostPort(ip, strconv.Itoa(port)))
 	if err != nil {
 		return false
 	}
 	conn.Close()
 	return true
 }
+
+// dial connects to address once the scan-wide and per-host rate limits
+// allow.
+func (p prober) dial(address string) (net.Conn, error) {
+	p.global.wait()
+	p.host.wait()
+	return net.DialTimeout("tcp", address, p.timeout)
+}
+
+// lookupAddr returns the PTR names for ip. The query goes to the resolver
+// rather than the host, so only the scan-wide limit applies.
+func (p prober) lookupAddr(ip string) []string {
+	p.global.wait()
+	ctx, cancel := context.WithTimeout(context.Background(), exchangeTimeouts*p.timeout)
+	defer cancel()
+	names, _ := net.DefaultResolver.LookupAddr(ctx, ip)
+	return names
+}
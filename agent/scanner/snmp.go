@@ -0,0 +1,539 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SNMPCredentials is one set of credentials to try against a device. A
+// User selects SNMPv3; otherwise Community is used with v2c.
+type SNMPCredentials struct {
+	Community string
+
+	User         string
+	AuthProtocol string // "MD5", "SHA", "SHA-224", "SHA-256", "SHA-384" or "SHA-512"; SHA if empty
+	AuthPassword string // Empty for noAuthNoPriv
+	PrivProtocol string // "DES" or "AES"; AES if empty
+	PrivPassword string // Empty for authNoPriv
+}
+
+func (c SNMPCredentials) validate() error {
+	if c.User == "" {
+		if c.Community == "" {
+			return errors.New("SNMP credentials need a community or a user")
+		}
+		return nil
+	}
+	_, err := newUSM(c)
+	return err
+}
+
+const (
+	snmpPort     = 161
+	snmpAttempts = 2
+	snmpMaxSize  = 65507 // Largest UDP payload, advertised as msgMaxSize
+	snmpBulkSize = 25    // max-repetitions per GETBULK
+	snmpMaxWalk  = 2000  // Variables per walk, so huge tables can't stall a scan
+)
+
+var errSNMPTimeout = errors.New("no SNMP response")
+
+// USM statistics carried in reports (RFC 3414 5)
+var usmReports = map[string]string{
+	"1.3.6.1.6.3.15.1.1.1.0": "unsupported security level",
+	"1.3.6.1.6.3.15.1.1.2.0": "not in time window",
+	"1.3.6.1.6.3.15.1.1.3.0": "unknown user name",
+	"1.3.6.1.6.3.15.1.1.4.0": "unknown engine ID",
+	"1.3.6.1.6.3.15.1.1.5.0": "wrong digest",
+	"1.3.6.1.6.3.15.1.1.6.0": "decryption error",
+}
+
+const usmNotInTimeWindow = "1.3.6.1.6.3.15.1.1.2.0"
+
+// varbind is one variable from a response, decoded lazily.
+type varbind struct {
+	oid   string
+	tag   byte
+	value []byte
+}
+
+// exists is false for NULL and the v2 exceptions (noSuchObject,
+// noSuchInstance, endOfMibView).
+func (v varbind) exists() bool {
+	switch v.tag {
+	case berNull, snmpNoSuchObj, snmpNoSuchInst, snmpEndOfView:
+		return false
+	}
+	return true
+}
+
+func (v varbind) text() string {
+	switch v.tag {
+	case berOctetString:
+		s := strings.TrimRight(string(v.value), "\x00")
+		if !utf8.ValidString(s) {
+			s = strings.ToValidUTF8(s, "")
+		}
+		return strings.TrimSpace(s)
+	case berOID:
+		return berOIDString(v.value)
+	case snmpIPAddress:
+		if len(v.value) == 4 {
+			return net.IP(v.value).String()
+		}
+	case berInteger, snmpCounter32, snmpGauge32, snmpTimeTicks, snmpCounter64:
+		return strconv.FormatInt(v.int(), 10)
+	}
+	return ""
+}
+
+// int decodes INTEGER as signed and the application types as unsigned;
+// anything else is 0.
+func (v varbind) int() int64 {
+	switch v.tag {
+	case berInteger:
+		return berSigned(v.value)
+	case snmpCounter32, snmpGauge32, snmpTimeTicks, snmpCounter64:
+		return int64(berUnsigned(v.value))
+	}
+	return 0
+}
+
+type snmpPDU struct {
+	pduType     byte
+	id          int32 // request-id, or msgID for v3
+	errorStatus int64
+	varbinds    []varbind
+
+	// Security parameters of the v3 message that carried the PDU
+	engineID      []byte
+	boots         int32
+	engineTime    int32
+	authenticated bool // Carried a valid HMAC
+}
+
+// snmpSession talks to one agent with one set of credentials.
+type snmpSession struct {
+	probe     prober // Timeout and rate limits for each request
+	conn      net.Conn
+	version   string // "2c" or "3"
+	community string
+	usm       *usm
+	requestID int32
+}
+
+func dialSNMP(address string, creds SNMPCredentials, probe prober) (*snmpSession, error) {
+	s := &snmpSession{probe: probe, version: "2c", community: creds.Community}
+	if creds.User != "" {
+		u, err := newUSM(creds)
+		if err != nil {
+			return nil, err
+		}
+		s.version, s.usm = "3", u
+	}
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	if s.usm != nil {
+		// Discovery: an unauthenticated empty request draws a report
+		// carrying the agent's engine ID, boots and time
+		resp, err := s.roundTrip(pduGet, 0, 0, nil)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if len(resp.engineID) == 0 {
+			conn.Close()
+			return nil, errors.New("SNMPv3 engine discovery failed")
+		}
+		s.usm.discovered(resp.engineID, resp.boots, resp.engineTime)
+	}
+	return s, nil
+}
+
+func (s *snmpSession) Close() error {
+	return s.conn.Close()
+}
+
+// get fetches scalar or instance OIDs; the result has one varbind per OID,
+// in order.
+func (s *snmpSession) get(oids ...string) ([]varbind, error) {
+	vbs, err := s.request(pduGet, 0, 0, oids)
+	if err == nil && len(vbs) != len(oids) {
+		err = errors.New("SNMP response does not match the request")
+	}
+	return vbs, err
+}
+
+// walk returns the variables under root in OID order.
+func (s *snmpSession) walk(root string) ([]varbind, error) {
+	var out []varbind
+	prefix := root + "."
+	next := root
+	for len(out) < snmpMaxWalk {
+		vbs, err := s.request(pduGetBulk, 0, snmpBulkSize, []string{next})
+		if err != nil {
+			return out, err
+		}
+		if len(vbs) == 0 {
+			return out, nil
+		}
+		for _, vb := range vbs {
+			if vb.tag == snmpEndOfView || !strings.HasPrefix(vb.oid, prefix) {
+				return out, nil
+			}
+			out = append(out, vb)
+		}
+		last := vbs[len(vbs)-1].oid
+		if compareOIDs(last, next) <= 0 {
+			return out, errors.New("SNMP agent returned OIDs out of order")
+		}
+		next = last
+	}
+	return out, nil
+}
+
+// column walks one table column and returns its values keyed by row index
+// (the OID suffix after the column), plus the indexes in table order.
+func (s *snmpSession) column(oid string) (map[string]varbind, []string) {
+	vbs, _ := s.walk(oid)
+	values := map[string]varbind{}
+	var order []string
+	for _, vb := range vbs {
+		index := strings.TrimPrefix(vb.oid, oid+".")
+		values[index] = vb
+		order = append(order, index)
+	}
+	return values, order
+}
+
+// request sends a PDU and returns the response's variables. A v3 agent
+// whose clock moved on is resynchronized once.
+func (s *snmpSession) request(pduType byte, nonRepeaters, maxRepetitions int, oids []string) ([]varbind, error) {
+	for resync := 0; ; resync++ {
+		resp, err := s.roundTrip(pduType, nonRepeaters, maxRepetitions, oids)
+		if err != nil {
+			return nil, err
+		}
+		if resp.pduType == pduReport {
+			var oid string
+			if len(resp.varbinds) > 0 {
+				oid = resp.varbinds[0].oid
+			}
+			// Only an authenticated report may move the clock (RFC 3414
+			// 3.2 step 7b)
+			if oid == usmNotInTimeWindow && s.usm != nil && (s.usm.auth == nil || resp.authenticated) && resync == 0 {
+				s.usm.discovered(resp.engineID, resp.boots, resp.engineTime)
+				continue
+			}
+			if reason, ok := usmReports[oid]; ok {
+				return nil, fmt.Errorf("SNMPv3: %s", reason)
+			}
+			return nil, fmt.Errorf("SNMP report %s", oid)
+		}
+		if resp.errorStatus != 0 {
+			return nil, fmt.Errorf("SNMP error status %d", resp.errorStatus)
+		}
+		return resp.varbinds, nil
+	}
+}
+
+// roundTrip sends a request, retrying once, and returns the first
+// response that matches it.
+func (s *snmpSession) roundTrip(pduType byte, nonRepeaters, maxRepetitions int, oids []string) (*snmpPDU, error) {
+	buf := make([]byte, snmpMaxSize)
+	for attempt := 0; attempt < snmpAttempts; attempt++ {
+		s.requestID++
+		id := s.requestID
+		pdu, err := encodePDU(pduType, id, nonRepeaters, maxRepetitions, oids)
+		if err != nil {
+			return nil, err
+		}
+		var msg []byte
+		if s.usm != nil {
+			msg, err = s.encodeV3(id, pdu)
+		} else {
+			msg = berTLV(berSequence, berInt(1), berOctets([]byte(s.community)), pdu)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.probe.global.wait()
+		s.probe.host.wait()
+		if _, err := s.conn.Write(msg); err != nil {
+			return nil, err
+		}
+
+		s.conn.SetReadDeadline(time.Now().Add(s.probe.timeout))
+		for {
+			n, err := s.conn.Read(buf)
+			if err != nil {
+				if ne, ok := err.(net.Error); ok && ne.Timeout() {
+					break
+				}
+				// ICMP port unreachable: nothing listens on 161
+				return nil, err
+			}
+			var resp *snmpPDU
+			if s.usm != nil {
+				resp, err = s.decodeV3(buf[:n:n])
+			} else {
+				resp, err = s.decodeV2c(buf[:n])
+			}
+			if err == nil && resp.id == id && (resp.pduType == pduResponse || resp.pduType == pduReport) {
+				return resp, nil
+			}
+		}
+	}
+	return nil, errSNMPTimeout
+}
+
+func encodePDU(pduType byte, id int32, a, b int, oids []string) ([]byte, error) {
+	var vbs []byte
+	for _, oid := range oids {
+		enc, err := berObjectID(oid)
+		if err != nil {
+			return nil, fmt.Errorf("bad OID %q", oid)
+		}
+		vbs = append(vbs, berTLV(berSequence, enc, berTLV(berNull))...)
+	}
+	return berTLV(pduType, berInt(int64(id)), berInt(int64(a)), berInt(int64(b)), berTLV(berSequence, vbs)), nil
+}
+
+func decodePDU(b []byte) (*snmpPDU, error) {
+	tag, content, _, err := parseTLV(b)
+	if err != nil {
+		return nil, err
+	}
+	p := &snmpPDU{pduType: tag}
+	id, content, err := parseIntElem(content)
+	if err != nil {
+		return nil, err
+	}
+	p.id = int32(id)
+	if p.errorStatus, content, err = parseIntElem(content); err != nil {
+		return nil, err
+	}
+	if _, content, err = parseIntElem(content); err != nil {
+		return nil, err
+	}
+	list, _, err := parseExpect(content, berSequence)
+	if err != nil {
+		return nil, err
+	}
+	for len(list) > 0 {
+		var vb []byte
+		if vb, list, err = parseExpect(list, berSequence); err != nil {
+			return nil, err
+		}
+		name, rest, err := parseExpect(vb, berOID)
+		if err != nil {
+			return nil, err
+		}
+		tag, value, _, err := parseTLV(rest)
+		if err != nil {
+			return nil, err
+		}
+		p.varbinds = append(p.varbinds, varbind{oid: berOIDString(name), tag: tag, value: value})
+	}
+	return p, nil
+}
+
+func (s *snmpSession) decodeV2c(b []byte) (*snmpPDU, error) {
+	msg, _, err := parseExpect(b, berSequence)
+	if err != nil {
+		return nil, err
+	}
+	version, msg, err := parseIntElem(msg)
+	if err != nil || version != 1 {
+		return nil, errBER
+	}
+	if _, msg, err = parseExpect(msg, berOctetString); err != nil {
+		return nil, err
+	}
+	return decodePDU(msg)
+}
+
+// v3Message is the parsed envelope of an SNMPv3 message. The byte slices
+// point into the message.
+type v3Message struct {
+	msgID      int32
+	flags      byte
+	engineID   []byte
+	boots      int32
+	engineTime int32
+	authParams []byte
+	privParams []byte
+	data       []byte // ScopedPDU or encrypted OCTET STRING element
+}
+
+func parseV3(b []byte) (*v3Message, error) {
+	msg, _, err := parseExpect(b, berSequence)
+	if err != nil {
+		return nil, err
+	}
+	version, msg, err := parseIntElem(msg)
+	if err != nil || version != 3 {
+		return nil, errBER
+	}
+	global, msg, err := parseExpect(msg, berSequence)
+	if err != nil {
+		return nil, err
+	}
+	m := &v3Message{}
+	id, global, err := parseIntElem(global)
+	if err != nil {
+		return nil, err
+	}
+	m.msgID = int32(id)
+	if _, global, err = parseIntElem(global); err != nil {
+		return nil, err
+	}
+	flags, global, err := parseExpect(global, berOctetString)
+	if err != nil || len(flags) != 1 {
+		return nil, errBER
+	}
+	m.flags = flags[0]
+	if model, _, err := parseIntElem(global); err != nil || model != 3 {
+		return nil, errBER
+	}
+
+	secParams, msg, err := parseExpect(msg, berOctetString)
+	if err != nil {
+		return nil, err
+	}
+	m.data = msg
+	sec, _, err := parseExpect(secParams, berSequence)
+	if err != nil {
+		return nil, err
+	}
+	if m.engineID, sec, err = parseExpect(sec, berOctetString); err != nil {
+		return nil, err
+	}
+	boots, sec, err := parseIntElem(sec)
+	if err != nil {
+		return nil, err
+	}
+	engineTime, sec, err := parseIntElem(sec)
+	if err != nil {
+		return nil, err
+	}
+	m.boots, m.engineTime = int32(boots), int32(engineTime)
+	if _, sec, err = parseExpect(sec, berOctetString); err != nil { // User name
+		return nil, err
+	}
+	if m.authParams, sec, err = parseExpect(sec, berOctetString); err != nil {
+		return nil, err
+	}
+	if m.privParams, _, err = parseExpect(sec, berOctetString); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *snmpSession) encodeV3(id int32, pdu []byte) ([]byte, error) {
+	u := s.usm
+	flags := byte(usmFlagReportable)
+	user := ""
+	if len(u.engineID) > 0 {
+		flags |= u.flags()
+		user = u.user
+	}
+	boots, engineTime := u.now()
+
+	data := berTLV(berSequence, berOctets(u.engineID), berOctets(nil), pdu)
+	var authParams, privParams []byte
+	if flags&usmFlagPriv != 0 {
+		encrypted, params, err := u.encrypt(data, boots, engineTime)
+		if err != nil {
+			return nil, err
+		}
+		data, privParams = berOctets(encrypted), params
+	}
+	if flags&usmFlagAuth != 0 {
+		authParams = make([]byte, u.auth.macLen)
+	}
+	sec := berTLV(berSequence,
+		berOctets(u.engineID), berInt(int64(boots)), berInt(int64(engineTime)),
+		berOctets([]byte(user)), berOctets(authParams), berOctets(privParams))
+	global := berTLV(berSequence, berInt(int64(id)), berInt(snmpMaxSize), berOctets([]byte{flags}), berInt(3))
+	msg := berTLV(berSequence, berInt(3), global, berOctets(sec), data)
+
+	if flags&usmFlagAuth != 0 {
+		m, err := parseV3(msg)
+		if err != nil {
+			return nil, err
+		}
+		u.sign(msg, m.authParams)
+	}
+	return msg, nil
+}
+
+func (s *snmpSession) decodeV3(b []byte) (*snmpPDU, error) {
+	m, err := parseV3(b)
+	if err != nil {
+		return nil, err
+	}
+	u := s.usm
+	authenticated := m.flags&usmFlagAuth != 0
+	if authenticated && !u.verify(b, m.authParams) {
+		return nil, errors.New("SNMPv3 authentication failed")
+	}
+	data := m.data
+	if m.flags&usmFlagPriv != 0 {
+		encrypted, _, err := parseExpect(data, berOctetString)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = u.decrypt(encrypted, m.privParams, m.boots, m.engineTime); err != nil {
+			return nil, err
+		}
+	}
+	scoped, _, err := parseExpect(data, berSequence)
+	if err != nil {
+		return nil, err
+	}
+	if _, scoped, err = parseExpect(scoped, berOctetString); err != nil { // contextEngineID
+		return nil, err
+	}
+	if _, scoped, err = parseExpect(scoped, berOctetString); err != nil { // contextName
+		return nil, err
+	}
+	p, err := decodePDU(scoped)
+	if err != nil {
+		return nil, err
+	}
+	// Once the engine is known, responses must come at the session's
+	// security level, or anyone could answer in the agent's name. Reports
+	// only ever fail a request, so they may be unauthenticated.
+	if len(u.engineID) > 0 && p.pduType == pduResponse && m.flags&u.flags() != u.flags() {
+		return nil, errors.New("SNMPv3 response below the session's security level")
+	}
+	p.id = m.msgID
+	p.engineID, p.boots, p.engineTime = m.engineID, m.boots, m.engineTime
+	p.authenticated = authenticated
+	return p, nil
+}
+
+// compareOIDs orders dotted OIDs arc by arc.
+func compareOIDs(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, _ := strconv.ParseUint(as[i], 10, 64)
+		y, _ := strconv.ParseUint(bs[i], 10, 64)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(as) - len(bs)
+}
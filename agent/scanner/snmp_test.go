@@ -0,0 +1,208 @@
+package scanner
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAgent answers SNMP GETs for a fixed set of string OIDs on a
+// loopback UDP port.
+type fakeAgent struct {
+	community string
+	creds     SNMPCredentials // v3 user; empty for v2c only
+	engineID  []byte
+	values    map[string]string
+	// tamper, if set, corrupts v3 responses: "noauth" drops the security
+	// level to noAuthNoPriv, "badmac" breaks the HMAC
+	tamper string
+}
+
+func (a *fakeAgent) serve(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, snmpMaxSize)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			msg := append([]byte{}, buf[:n]...)
+			if reply := a.handle(t, msg); reply != nil {
+				conn.WriteTo(reply, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func (a *fakeAgent) response(id int32, oids []string) []byte {
+	var vbs []byte
+	for _, oid := range oids {
+		enc, _ := berObjectID(oid)
+		value := berTLV(snmpNoSuchObj)
+		if v, ok := a.values[oid]; ok {
+			value = berOctets([]byte(v))
+		}
+		vbs = append(vbs, berTLV(berSequence, enc, value)...)
+	}
+	return berTLV(pduResponse, berInt(int64(id)), berInt(0), berInt(0), berTLV(berSequence, vbs))
+}
+
+func (a *fakeAgent) handle(t *testing.T, msg []byte) []byte {
+	if m, err := parseV3(msg); err == nil {
+		return a.handleV3(t, msg, m)
+	}
+	body, _, err := parseExpect(msg, berSequence)
+	if err != nil {
+		return nil
+	}
+	_, body, _ = parseIntElem(body)
+	community, body, err := parseExpect(body, berOctetString)
+	if err != nil || string(community) != a.community {
+		return nil
+	}
+	req, err := decodePDU(body)
+	if err != nil {
+		return nil
+	}
+	var oids []string
+	for _, vb := range req.varbinds {
+		oids = append(oids, vb.oid)
+	}
+	return berTLV(berSequence, berInt(1), berOctets(community), a.response(req.id, oids))
+}
+
+// agentSession encodes and decodes as the authoritative engine, reusing
+// the client's USM code with keys localized to the agent's engine ID.
+func (a *fakeAgent) agentSession(t *testing.T, creds SNMPCredentials) *snmpSession {
+	u, err := newUSM(creds)
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	u.discovered(a.engineID, 7, 1000)
+	return &snmpSession{version: "3", usm: u}
+}
+
+func (a *fakeAgent) handleV3(t *testing.T, msg []byte, m *v3Message) []byte {
+	if len(m.engineID) == 0 {
+		// Discovery: report usmStatsUnknownEngineIDs, unauthenticated
+		noAuth := a.agentSession(t, SNMPCredentials{User: a.creds.User})
+		pdu, _ := encodePDU(pduReport, m.msgID, 0, 0, []string{"1.3.6.1.6.3.15.1.1.4.0"})
+		reply, _ := noAuth.encodeV3(m.msgID, pdu)
+		return reply
+	}
+
+	s := a.agentSession(t, a.creds)
+	req, err := s.decodeV3(msg)
+	if err != nil {
+		t.Errorf("agent could not decode request: %v", err)
+		return nil
+	}
+	var oids []string
+	for _, vb := range req.varbinds {
+		oids = append(oids, vb.oid)
+	}
+	pdu := a.response(req.id, oids)
+
+	if a.tamper == "noauth" {
+		s = a.agentSession(t, SNMPCredentials{User: a.creds.User})
+	}
+	reply, err := s.encodeV3(req.id, pdu)
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	if a.tamper == "badmac" {
+		sent, _ := parseV3(reply)
+		sent.authParams[0] ^= 0xff
+	}
+	return reply
+}
+
+func TestSNMPGet(t *testing.T) {
+	values := map[string]string{
+		oidSysDescr: "HP ETHERNET MULTI-ENVIRONMENT",
+		oidSysName:  "printer-2f",
+	}
+	tests := []struct {
+		name  string
+		agent fakeAgent
+		creds SNMPCredentials
+	}{
+		{"v2c", fakeAgent{community: "public"}, SNMPCredentials{Community: "public"}},
+		{"v3 noAuthNoPriv", fakeAgent{creds: SNMPCredentials{User: "monitor"}}, SNMPCredentials{User: "monitor"}},
+		{"v3 SHA/AES", fakeAgent{creds: SNMPCredentials{User: "monitor", AuthPassword: "authpass123", PrivPassword: "privpass123"}},
+			SNMPCredentials{User: "monitor", AuthPassword: "authpass123", PrivPassword: "privpass123"}},
+		{"v3 MD5/DES", fakeAgent{creds: SNMPCredentials{User: "monitor", AuthProtocol: "MD5", AuthPassword: "authpass123", PrivProtocol: "DES", PrivPassword: "privpass123"}},
+			SNMPCredentials{User: "monitor", AuthProtocol: "MD5", AuthPassword: "authpass123", PrivProtocol: "DES", PrivPassword: "privpass123"}},
+		{"v3 SHA-256 authNoPriv", fakeAgent{creds: SNMPCredentials{User: "monitor", AuthProtocol: "SHA-256", AuthPassword: "authpass123"}},
+			SNMPCredentials{User: "monitor", AuthProtocol: "SHA-256", AuthPassword: "authpass123"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := tt.agent
+			agent.engineID = []byte("\x80\x00\x1f\x88\x80fake-engine")
+			agent.values = values
+			s, err := dialSNMP(agent.serve(t), tt.creds, testProber())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			vbs, err := s.get(oidSysDescr, oidSysName, oidSysLocation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{vbs[0].text(), vbs[1].text()}
+			if got[0] != values[oidSysDescr] || got[1] != values[oidSysName] || vbs[2].exists() {
+				t.Errorf("get = %q, sysLocation exists %v", got, vbs[2].exists())
+			}
+		})
+	}
+}
+
+func TestSNMPv3RejectsUnauthenticatedResponses(t *testing.T) {
+	creds := SNMPCredentials{User: "monitor", AuthPassword: "authpass123"}
+	for _, tamper := range []string{"noauth", "badmac"} {
+		t.Run(tamper, func(t *testing.T) {
+			t.Parallel()
+			agent := fakeAgent{creds: creds, engineID: []byte("fake-engine"), values: map[string]string{oidSysName: "spoofed"}, tamper: tamper}
+			s, err := dialSNMP(agent.serve(t), creds, testProber())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			vbs, err := s.get(oidSysName)
+			if err == nil || !strings.Contains(err.Error(), "no SNMP response") {
+				t.Errorf("get = %v, %v; want the spoofed response ignored", vbs, err)
+			}
+		})
+	}
+}
+
+func TestSNMPTimeout(t *testing.T) {
+	// An agent with another community stays silent
+	agent := fakeAgent{community: "private"}
+	p := testProber()
+	p.timeout = 100 * time.Millisecond
+	s, err := dialSNMP(agent.serve(t), SNMPCredentials{Community: "public"}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	started := time.Now()
+	if _, err := s.get(oidSysName); err != errSNMPTimeout {
+		t.Errorf("get = %v, want %v", err, errSNMPTimeout)
+	}
+	// One wait per attempt
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("get gave up after %v with a %v timeout", elapsed, p.timeout)
+	}
+}
@@ -0,0 +1,360 @@
+package scanner
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// SNMPInfo is what a device's SNMP agent reported about it.
+type SNMPInfo struct {
+	Version       string           `json:"version"` // "2c" or "3"
+	SysDescr      string           `json:"sys_descr"`
+	SysObjectID   string           `json:"sys_object_id"`
+	SysName       string           `json:"sys_name,omitempty"`
+	SysLocation   string           `json:"sys_location,omitempty"`
+	SysContact    string           `json:"sys_contact,omitempty"`
+	UptimeSeconds int64            `json:"uptime_seconds,omitempty"`
+	Entities      []PhysicalEntity `json:"entities,omitempty"` // ENTITY-MIB components that report a serial number
+	Printer       *PrinterStatus   `json:"printer,omitempty"`
+	UPS           *UPSStatus       `json:"ups,omitempty"`
+}
+
+// PhysicalEntity is an entPhysicalTable row: a chassis, module, power
+// supply, transceiver and so on.
+type PhysicalEntity struct {
+	Index            int    `json:"index"`
+	Class            string `json:"class"` // "chassis", "module", "powerSupply", "fan", "port", "stack", ...
+	Name             string `json:"name,omitempty"`
+	Description      string `json:"description,omitempty"`
+	Manufacturer     string `json:"manufacturer,omitempty"`
+	Model            string `json:"model,omitempty"`
+	SerialNumber     string `json:"serial_number"`
+	SoftwareRevision string `json:"software_revision,omitempty"`
+}
+
+// PrinterStatus comes from the Printer MIB (RFC 3805).
+type PrinterStatus struct {
+	Model        string          `json:"model,omitempty"` // hrDeviceDescr
+	SerialNumber string          `json:"serial_number,omitempty"`
+	PageCount    int64           `json:"page_count"` // prtMarkerLifeCount, summed over markers
+	Supplies     []PrinterSupply `json:"supplies,omitempty"`
+}
+
+type PrinterSupply struct {
+	Description string `json:"description"`
+	Type        string `json:"type"`     // "toner", "ink", "drum", "fuser", "waste_toner", ...
+	Level       int64  `json:"level"`    // In the supply's own units; -2 unknown, -3 "some remaining"
+	Capacity    int64  `json:"capacity"` // -2 unknown
+	Percent     int    `json:"percent"`  // 0-100, or -1 when the printer reports no measurable level
+}
+
+// UPSStatus comes from the UPS MIB (RFC 1628) or APC's PowerNet MIB.
+type UPSStatus struct {
+	Manufacturer     string `json:"manufacturer,omitempty"`
+	Model            string `json:"model,omitempty"`
+	SerialNumber     string `json:"serial_number,omitempty"`
+	BatteryStatus    string `json:"battery_status,omitempty"` // "normal", "low", "depleted", "fault"
+	ChargePercent    int    `json:"charge_percent"`           // -1 if unknown
+	MinutesRemaining int    `json:"minutes_remaining"`        // -1 if unknown
+	OutputSource     string `json:"output_source,omitempty"`  // "normal", "battery", "bypass", "booster", "reducer", "none"
+}
+
+const (
+	oidSysDescr    = "1.3.6.1.2.1.1.1.0"
+	oidSysObjectID = "1.3.6.1.2.1.1.2.0"
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSysContact  = "1.3.6.1.2.1.1.4.0"
+	oidSysName     = "1.3.6.1.2.1.1.5.0"
+	oidSysLocation = "1.3.6.1.2.1.1.6.0"
+
+	oidEntPhysicalEntry = "1.3.6.1.2.1.47.1.1.1.1"
+	oidHrDeviceDescr    = "1.3.6.1.2.1.25.3.2.1.3"
+
+	oidPrtGeneralSerialNumber = "1.3.6.1.2.1.43.5.1.1.17"
+	oidPrtMarkerLifeCount     = "1.3.6.1.2.1.43.10.2.1.4"
+	oidPrtMarkerSuppliesEntry = "1.3.6.1.2.1.43.11.1.1"
+
+	oidUPSIdent   = "1.3.6.1.2.1.33.1.1"
+	oidUPSBattery = "1.3.6.1.2.1.33.1.2"
+	oidUPSOutput  = "1.3.6.1.2.1.33.1.4"
+	oidAPCUPS     = "1.3.6.1.4.1.318.1.1.1"
+
+	maxEntities = 64 // Enough for a stack of switches with their PSUs and modules
+)
+
+var entityClasses = map[int64]string{
+	1: "other", 2: "unknown", 3: "chassis", 4: "backplane", 5: "container", 6: "powerSupply",
+	7: "fan", 8: "sensor", 9: "module", 10: "port", 11: "stack", 12: "cpu",
+}
+
+// prtMarkerSuppliesType (RFC 3805)
+var supplyTypes = map[int64]string{
+	3: "toner", 4: "waste_toner", 5: "ink", 6: "ink_cartridge", 7: "ink_ribbon", 8: "waste_ink",
+	9: "drum", 10: "developer", 11: "fuser_oil", 12: "solid_wax", 13: "ribbon_wax", 14: "waste_wax",
+	15: "fuser", 16: "corona_wire", 17: "fuser_oil_wick", 18: "cleaner_unit", 19: "fuser_cleaning_pad",
+	20: "transfer_unit", 21: "toner_cartridge", 22: "fuser_oiler", 32: "staples",
+}
+
+// Private enterprise numbers of common makers, for devices that don't
+// name their manufacturer anywhere else.
+var enterprises = map[string]string{
+	"9":     "Cisco",
+	"11":    "HP",
+	"43":    "3Com",
+	"171":   "D-Link",
+	"253":   "Xerox",
+	"311":   "Microsoft",
+	"318":   "APC",
+	"367":   "Ricoh",
+	"534":   "Eaton",
+	"641":   "Lexmark",
+	"1248":  "Epson",
+	"1347":  "Kyocera",
+	"1602":  "Canon",
+	"1916":  "Extreme Networks",
+	"2011":  "Huawei",
+	"2435":  "Brother",
+	"2636":  "Juniper",
+	"4526":  "Netgear",
+	"6574":  "Synology",
+	"8072":  "Net-SNMP",
+	"11863": "TP-Link",
+	"12356": "Fortinet",
+	"14823": "Aruba",
+	"14988": "MikroTik",
+	"18334": "Konica Minolta",
+	"24681": "QNAP",
+	"25461": "Palo Alto Networks",
+	"25506": "H3C",
+	"30065": "Arista",
+	"41112": "Ubiquiti",
+}
+
+// querySNMP reads a device's SNMP agent at address (host:port), trying each
+// set of credentials until one gets an answer.
+func (p prober) querySNMP(address string, creds []SNMPCredentials) (*SNMPInfo, error) {
+	var errs []error
+	for _, c := range creds {
+		s, err := dialSNMP(address, c, p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		info, err := s.query()
+		s.Close()
+		if err == nil {
+			return info, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no SNMP credentials")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// query reads the system group and then whatever else the agent has. Only
+// the system group is required; the rest is best effort.
+func (s *snmpSession) query() (*SNMPInfo, error) {
+	vbs, err := s.get(oidSysDescr, oidSysObjectID, oidSysUpTime, oidSysContact, oidSysName, oidSysLocation)
+	if err != nil {
+		return nil, err
+	}
+	info := &SNMPInfo{
+		Version:       s.version,
+		SysDescr:      vbs[0].text(),
+		SysObjectID:   vbs[1].text(),
+		UptimeSeconds: vbs[2].int() / 100,
+		SysContact:    vbs[3].text(),
+		SysName:       vbs[4].text(),
+		SysLocation:   vbs[5].text(),
+	}
+	info.Entities = s.entities()
+	info.Printer = s.printer()
+	info.UPS = s.ups(info.SysObjectID)
+	return info, nil
+}
+
+// entities lists the entPhysicalTable rows that carry a serial number.
+// Only the serial column is walked; the other columns are fetched for
+// those rows, since a switch has a row per port.
+func (s *snmpSession) entities() []PhysicalEntity {
+	serials, order := s.column(oidEntPhysicalEntry + ".11")
+	var entities []PhysicalEntity
+	for _, index := range order {
+		serial := serials[index].text()
+		if serial == "" {
+			continue
+		}
+		if len(entities) == maxEntities {
+			break
+		}
+		e := PhysicalEntity{SerialNumber: serial}
+		e.Index, _ = strconv.Atoi(index)
+		col := func(n int) string { return oidEntPhysicalEntry + "." + strconv.Itoa(n) + "." + index }
+		// entPhysicalDescr, Class, Name, SoftwareRev, MfgName, ModelName
+		if vbs, err := s.get(col(2), col(5), col(7), col(10), col(12), col(13)); err == nil {
+			e.Description = vbs[0].text()
+			e.Class = entityClasses[vbs[1].int()]
+			e.Name = vbs[2].text()
+			e.SoftwareRevision = vbs[3].text()
+			e.Manufacturer = vbs[4].text()
+			e.Model = vbs[5].text()
+		}
+		entities = append(entities, e)
+	}
+	return entities
+}
+
+// printer reads the Printer MIB; nil if the device has none.
+func (s *snmpSession) printer() *PrinterStatus {
+	counts, markers := s.column(oidPrtMarkerLifeCount)
+	descriptions, supplies := s.column(oidPrtMarkerSuppliesEntry + ".6")
+	if len(markers) == 0 && len(supplies) == 0 {
+		return nil
+	}
+
+	p := &PrinterStatus{}
+	for _, vb := range counts {
+		p.PageCount += vb.int()
+	}
+
+	// Both tables are indexed by hrDeviceIndex first
+	first := supplies
+	if len(markers) > 0 {
+		first = markers
+	}
+	device, _, _ := strings.Cut(first[0], ".")
+	if vbs, err := s.get(oidHrDeviceDescr+"."+device, oidPrtGeneralSerialNumber+"."+device); err == nil {
+		p.Model = vbs[0].text()
+		p.SerialNumber = vbs[1].text()
+	}
+
+	if len(supplies) > 0 {
+		types, _ := s.column(oidPrtMarkerSuppliesEntry + ".5")
+		capacities, _ := s.column(oidPrtMarkerSuppliesEntry + ".8")
+		levels, _ := s.column(oidPrtMarkerSuppliesEntry + ".9")
+		for _, index := range supplies {
+			supply := PrinterSupply{
+				Description: descriptions[index].text(),
+				Type:        supplyTypes[types[index].int()],
+				Level:       -2,
+				Capacity:    -2,
+				Percent:     -1,
+			}
+			if supply.Type == "" {
+				supply.Type = "other"
+			}
+			if vb, ok := levels[index]; ok && vb.exists() {
+				supply.Level = vb.int()
+			}
+			if vb, ok := capacities[index]; ok && vb.exists() {
+				supply.Capacity = vb.int()
+			}
+			if supply.Level >= 0 && supply.Capacity > 0 {
+				supply.Percent = int(min(supply.Level*100/supply.Capacity, 100))
+			}
+			p.Supplies = append(p.Supplies, supply)
+		}
+	}
+	return p
+}
+
+// ups reads the standard UPS MIB, then APC's own MIB on APC devices, which
+// often implement only the latter; nil if neither answers.
+func (s *snmpSession) ups(sysObjectID string) *UPSStatus {
+	u := &UPSStatus{ChargePercent: -1, MinutesRemaining: -1}
+	found := false
+
+	// upsIdentManufacturer, upsIdentModel, upsBatteryStatus,
+	// upsEstimatedMinutesRemaining, upsEstimatedChargeRemaining,
+	// upsOutputSource
+	vbs, err := s.get(oidUPSIdent+".1.0", oidUPSIdent+".2.0", oidUPSBattery+".1.0", oidUPSBattery+".3.0", oidUPSBattery+".4.0", oidUPSOutput+".1.0")
+	if err == nil && (vbs[0].exists() || vbs[2].exists()) {
+		found = true
+		u.Manufacturer = vbs[0].text()
+		u.Model = vbs[1].text()
+		u.BatteryStatus = map[int64]string{2: "normal", 3: "low", 4: "depleted"}[vbs[2].int()]
+		if vbs[3].exists() {
+			u.MinutesRemaining = int(vbs[3].int())
+		}
+		if vbs[4].exists() {
+			u.ChargePercent = int(vbs[4].int())
+		}
+		u.OutputSource = map[int64]string{2: "none", 3: "normal", 4: "bypass", 5: "battery", 6: "booster", 7: "reducer"}[vbs[5].int()]
+	}
+
+	if strings.HasPrefix(sysObjectID, "1.3.6.1.4.1.318.") {
+		// upsBasicIdentModel, upsAdvIdentSerialNumber, upsBasicBatteryStatus,
+		// upsAdvBatteryCapacity, upsAdvBatteryRunTimeRemaining,
+		// upsBasicOutputStatus
+		vbs, err := s.get(oidAPCUPS+".1.1.1.0", oidAPCUPS+".1.2.3.0", oidAPCUPS+".2.1.1.0", oidAPCUPS+".2.2.1.0", oidAPCUPS+".2.2.3.0", oidAPCUPS+".4.1.1.0")
+		if err == nil && (vbs[0].exists() || vbs[2].exists()) {
+			found = true
+			u.Manufacturer = "APC"
+			if model := vbs[0].text(); model != "" {
+				u.Model = model
+			}
+			u.SerialNumber = vbs[1].text()
+			if status, ok := map[int64]string{2: "normal", 3: "low", 4: "fault"}[vbs[2].int()]; ok {
+				u.BatteryStatus = status
+			}
+			if vbs[3].exists() {
+				u.ChargePercent = int(vbs[3].int())
+			}
+			if vbs[4].exists() {
+				u.MinutesRemaining = int(vbs[4].int() / 6000) // TimeTicks
+			}
+			if source, ok := map[int64]string{2: "normal", 3: "battery", 4: "booster", 6: "bypass", 9: "bypass", 10: "bypass", 12: "reducer"}[vbs[5].int()]; ok {
+				u.OutputSource = source
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	return u
+}
+
+// identity picks the make, model and serial number that best describe the
+// whole device: the chassis entity, then the printer or UPS MIB, then the
+// enterprise that registered sysObjectID.
+func (info *SNMPInfo) identity() (manufacturer, model, serial string) {
+	for _, e := range info.Entities {
+		if e.Class == "chassis" {
+			manufacturer, model, serial = e.Manufacturer, e.Model, e.SerialNumber
+			break
+		}
+	}
+	if p := info.Printer; p != nil {
+		model = firstNonEmpty(model, p.Model)
+		serial = firstNonEmpty(serial, p.SerialNumber)
+	}
+	if u := info.UPS; u != nil {
+		manufacturer = firstNonEmpty(manufacturer, u.Manufacturer)
+		model = firstNonEmpty(model, u.Model)
+		serial = firstNonEmpty(serial, u.SerialNumber)
+	}
+	manufacturer = firstNonEmpty(manufacturer, enterprises[enterpriseNumber(info.SysObjectID)])
+	return manufacturer, model, serial
+}
+
+// enterpriseNumber returns N from a sysObjectID under 1.3.6.1.4.1.N.
+func enterpriseNumber(oid string) string {
+	rest, ok := strings.CutPrefix(oid, "1.3.6.1.4.1.")
+	if !ok {
+		return ""
+	}
+	n, _, _ := strings.Cut(rest, ".")
+	return n
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
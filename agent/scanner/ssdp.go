@@ -0,0 +1,202 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UPnPDevice is a UPnP root device's description.
+type UPnPDevice struct {
+	DeviceType   string `json:"device_type"` // e.g. "urn:schemas-upnp-org:device:MediaRenderer:1"
+	FriendlyName string `json:"friendly_name,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	ModelName    string `json:"model_name,omitempty"`
+	ModelNumber  string `json:"model_number,omitempty"`
+	SerialNumber string `json:"serial_number,omitempty"`
+	UDN          string `json:"udn,omitempty"`
+	Server       string `json:"server,omitempty"` // SSDP SERVER header: OS, UPnP version and product
+	Location     string `json:"location"`
+}
+
+var ssdpGroup = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+// ssdpAnnouncement is the LOCATION and SERVER of an M-SEARCH response or
+// NOTIFY.
+type ssdpAnnouncement struct {
+	location string
+	server   string
+	root     bool // The root device's own announcement, not an embedded device or service
+}
+
+// searchSSDP multicasts an M-SEARCH for every device and listens for the
+// responses and for NOTIFY announcements for window, then fetches each
+// responding address's device description. Only addresses the targets cover
+// and do not exclude are contacted. Results are keyed by IPv4 address.
+func (p prober) searchSSDP(window time.Duration, targets *Targets) map[string]*UPnPDevice {
+	devices := map[string]*UPnPDevice{}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return devices
+	}
+	conns := []*net.UDPConn{conn}
+	if passive, err := net.ListenMulticastUDP("udp4", nil, ssdpGroup); err == nil {
+		conns = append(conns, passive)
+	}
+
+	var mu sync.Mutex
+	found := map[string]ssdpAnnouncement{}
+	deadline := time.Now().Add(window)
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		c.SetReadDeadline(deadline)
+		wg.Add(1)
+		go func(c *net.UDPConn) {
+			defer wg.Done()
+			buf := make([]byte, 4096)
+			for {
+				n, from, err := c.ReadFromUDP(buf)
+				if err != nil {
+					return
+				}
+				ip := from.IP.To4()
+				a, ok := parseSSDP(buf[:n])
+				if ip == nil || !ok {
+					continue
+				}
+				mu.Lock()
+				if prev, seen := found[ip.String()]; !seen || (a.root && !prev.root) {
+					found[ip.String()] = a
+				}
+				mu.Unlock()
+			}
+		}(c)
+	}
+
+	mx := max(int(window/time.Second)-1, 1)
+	search := fmt.Sprintf("M-SEARCH * HTTP/1.1\r\nHOST: %s\r\nMAN: \"ssdp:discover\"\r\nMX: %d\r\nST: ssdp:all\r\nUSER-AGENT: assetronics-agent UPnP/1.1\r\n\r\n", ssdpGroup, mx)
+	// UDP is lossy; ask twice
+	for i := 0; i < 2; i++ {
+		p.global.wait()
+		conn.WriteToUDP([]byte(search), ssdpGroup)
+		time.Sleep(100 * time.Millisecond)
+	}
+	wg.Wait()
+	for _, c := range conns {
+		c.Close()
+	}
+
+	var fetch sync.WaitGroup
+	for ip, a := range found {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil || targets.excluded(addr) || !targets.covers(addr) {
+			continue
+		}
+		fetch.Add(1)
+		go func(ip string, a ssdpAnnouncement) {
+			defer fetch.Done()
+			d, err := p.describeUPnP(a.location, ip)
+			if err != nil {
+				d = &UPnPDevice{Location: a.location}
+			}
+			d.Server = a.server
+			mu.Lock()
+			devices[ip] = d
+			mu.Unlock()
+		}(ip, a)
+	}
+	fetch.Wait()
+	return devices
+}
+
+// parseSSDP reads an M-SEARCH response or a NOTIFY ssdp:alive.
+func parseSSDP(b []byte) (ssdpAnnouncement, bool) {
+	var header http.Header
+	target := ""
+	reader := bufio.NewReader(bytes.NewReader(b))
+	if bytes.HasPrefix(b, []byte("HTTP/")) {
+		resp, err := http.ReadResponse(reader, nil)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return ssdpAnnouncement{}, false
+		}
+		header, target = resp.Header, resp.Header.Get("ST")
+	} else {
+		req, err := http.ReadRequest(reader)
+		if err != nil || req.Method != "NOTIFY" || req.Header.Get("NTS") != "ssdp:alive" {
+			return ssdpAnnouncement{}, false
+		}
+		header, target = req.Header, req.Header.Get("NT")
+	}
+	a := ssdpAnnouncement{
+		location: header.Get("Location"),
+		server:   header.Get("Server"),
+		root:     target == "upnp:rootdevice",
+	}
+	return a, a.location != ""
+}
+
+// describeUPnP fetches and parses a device description. The description
+// must be served by ip itself, so a spoofed announcement can't point the
+// scanner at another host.
+func (p prober) describeUPnP(location, ip string) (*UPnPDevice, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" || u.Hostname() != ip {
+		return nil, fmt.Errorf("description %s not served by %s", location, ip)
+	}
+	client := &http.Client{
+		Timeout: exchangeTimeouts * p.timeout,
+		// A redirect could send the request to a host outside the scan
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		Transport: &http.Transport{
+			DialContext:       func(_ context.Context, _, address string) (net.Conn, error) { return p.dial(address) },
+			DisableKeepAlives: true,
+		},
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device description: %s", resp.Status)
+	}
+
+	var desc struct {
+		Device struct {
+			DeviceType   string `xml:"deviceType"`
+			FriendlyName string `xml:"friendlyName"`
+			Manufacturer string `xml:"manufacturer"`
+			ModelName    string `xml:"modelName"`
+			ModelNumber  string `xml:"modelNumber"`
+			SerialNumber string `xml:"serialNumber"`
+			UDN          string `xml:"UDN"`
+		} `xml:"device"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 256<<10)).Decode(&desc); err != nil {
+		return nil, err
+	}
+	d := desc.Device
+	return &UPnPDevice{
+		DeviceType:   strings.TrimSpace(d.DeviceType),
+		FriendlyName: strings.TrimSpace(d.FriendlyName),
+		Manufacturer: strings.TrimSpace(d.Manufacturer),
+		ModelName:    strings.TrimSpace(d.ModelName),
+		ModelNumber:  strings.TrimSpace(d.ModelNumber),
+		SerialNumber: strings.TrimSpace(d.SerialNumber),
+		UDN:          strings.TrimSpace(d.UDN),
+		Location:     location,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package scanner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const testDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0"><device>
+<deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+<friendlyName>Living Room TV</friendlyName>
+<manufacturer>Samsung Electronics</manufacturer>
+</device></root>`
+
+func TestDescribeUPnP(t *testing.T) {
+	var followed atomic.Bool
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed.Store(true)
+	}))
+	defer elsewhere.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved.xml" {
+			http.Redirect(w, r, elsewhere.URL+"/desc.xml", http.StatusFound)
+			return
+		}
+		w.Write([]byte(testDescription))
+	}))
+	defer srv.Close()
+
+	d, err := testProber().describeUPnP(srv.URL+"/desc.xml", "127.0.0.1")
+	if err != nil || d.FriendlyName != "Living Room TV" || d.Manufacturer != "Samsung Electronics" {
+		t.Fatalf("describeUPnP = %+v, %v", d, err)
+	}
+	if _, err := testProber().describeUPnP(srv.URL+"/moved.xml", "127.0.0.1"); err == nil || followed.Load() {
+		t.Errorf("redirect: err = %v, followed = %v; want an error without following", err, followed.Load())
+	}
+	if _, err := testProber().describeUPnP(srv.URL+"/desc.xml", "192.0.2.1"); err == nil {
+		t.Error("description served by another host: want an error")
+	}
+}
@@ -0,0 +1,154 @@
+package scanner
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// AutoOptions tunes the choice of subnets for an automatic scan.
+type AutoOptions struct {
+	MaxPrefix int  // Larger subnets are narrowed to the prefix of this length around this host's address; 0 uses 22
+	Virtual   bool // Also scan Docker, VM bridge and VPN interfaces and ranges
+}
+
+// onLinkRoute is a routing table entry for a network reached directly
+// through an interface rather than via a gateway.
+type onLinkRoute struct {
+	prefix netip.Prefix
+	iface  string
+}
+
+// Interface name prefixes of container, VM and VPN interfaces
+var virtualInterfacePrefixes = []string{
+	"docker", "br-", "veth", "virbr", "vnet", "vboxnet", "vmnet", "lxcbr", "lxdbr", "cni", "flannel", "cali", "weave", "kube", "podman",
+	"tun", "tap", "wg", "tailscale", "zt", "ppp", "ipsec", "vti", "gre", "nordlynx",
+	"utun", "bridge", "awdl", "llw", "gif", "stf", "anpi",
+}
+
+// Windows friendly names of the same
+var virtualInterfaceWords = []string{
+	"vethernet", "hyper-v", "virtualbox", "vmware", "docker", "wsl",
+	"vpn", "tap-windows", "wireguard", "tailscale", "zerotier", "wintun", "loopback",
+}
+
+// Ranges that belong to container networks and overlay VPNs rather than
+// the office LAN
+var virtualRanges = []netip.Prefix{
+	netip.MustParsePrefix("172.17.0.0/16"), // Docker's default bridge, also seen from inside containers
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT, used by Tailscale
+}
+
+// Never scanned
+var nonLANRanges = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"), // Link-local
+	netip.MustParsePrefix("224.0.0.0/4"),    // Multicast
+}
+
+// AutoSubnets lists the directly attached IPv4 subnets to scan: those of
+// the interfaces' addresses plus on-link routes, skipping loopback and,
+// unless opts.Virtual is set, container, VM and VPN interfaces and ranges.
+func AutoSubnets(opts AutoOptions) []netip.Prefix {
+	if opts.MaxPrefix <= 0 {
+		opts.MaxPrefix = 22
+	}
+	usable := func(iface net.Interface) bool {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			return false
+		}
+		return opts.Virtual || (iface.Flags&net.FlagPointToPoint == 0 && !virtualInterface(iface.Name))
+	}
+	wanted := func(prefix netip.Prefix) bool {
+		if prefix.Bits() >= 32 || overlapsAny(prefix, nonLANRanges) {
+			return false
+		}
+		return opts.Virtual || !overlapsAny(prefix, virtualRanges)
+	}
+
+	seen := map[netip.Prefix]bool{}
+	var subnets []netip.Prefix
+	add := func(prefix netip.Prefix, local netip.Addr) {
+		if !wanted(prefix) {
+			return
+		}
+		if prefix.Bits() < opts.MaxPrefix {
+			if !local.IsValid() {
+				fmt.Printf("Warning: not scanning %s: larger than /%d and this host has no address in it\n", prefix, opts.MaxPrefix)
+				return
+			}
+			narrowed := netip.PrefixFrom(local, opts.MaxPrefix).Masked()
+			fmt.Printf("Warning: %s is larger than /%d; scanning %s only\n", prefix, opts.MaxPrefix, narrowed)
+			prefix = narrowed
+		}
+		if !seen[prefix] {
+			seen[prefix] = true
+			subnets = append(subnets, prefix)
+		}
+	}
+
+	ifaces, _ := net.Interfaces()
+	byName := map[string]net.Interface{}
+	for _, iface := range ifaces {
+		byName[iface.Name] = iface
+		if !usable(iface) {
+			continue
+		}
+		addrs, _ := iface.Addrs()
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok || ipnet.IP.To4() == nil {
+				continue
+			}
+			local, _ := netip.AddrFromSlice(ipnet.IP.To4())
+			ones, _ := ipnet.Mask.Size()
+			add(netip.PrefixFrom(local, ones).Masked(), local)
+		}
+	}
+	// Extra subnets routed onto a link without an address of their own
+	for _, route := range onLinkRoutes() {
+		iface, ok := byName[route.iface]
+		if !ok || !usable(iface) || !route.prefix.Addr().Is4() {
+			continue
+		}
+		covered := false
+		for _, subnet := range subnets {
+			if subnet.Overlaps(route.prefix) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			add(route.prefix.Masked(), netip.Addr{})
+		}
+	}
+
+	slices.SortFunc(subnets, func(a, b netip.Prefix) int { return a.Addr().Compare(b.Addr()) })
+	return subnets
+}
+
+func virtualInterface(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range virtualInterfacePrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	for _, w := range virtualInterfaceWords {
+		if strings.Contains(name, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapsAny(prefix netip.Prefix, ranges []netip.Prefix) bool {
+	for _, r := range ranges {
+		if r.Overlaps(prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package scanner
+
+import (
+	"fmt"
+	"iter"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+const (
+	// Host bits of the largest IPv6 prefix or range that is swept address
+	// by address; larger prefixes are searched by neighbor discovery
+	maxSweepBits6 = 16
+	// Host bits of the largest IPv4 prefix or range accepted (a /8)
+	maxSweepBits4 = 24
+)
+
+// Targets is the set of addresses a scan probes: CIDRs, address ranges,
+// single addresses and hostnames, less the exclusions.
+type Targets struct {
+	ranges   []addrRange           // Sorted and disjoint, exclusions removed
+	exclude  []addrRange           // Sorted and disjoint
+	discover []netip.Prefix        // IPv6 prefixes too large to sweep
+	names    map[netip.Addr]string // Hostname each resolved target was given as
+}
+
+type addrRange struct {
+	first, last netip.Addr
+}
+
+func (r addrRange) contains(addr netip.Addr) bool {
+	return r.first.Compare(addr) <= 0 && addr.Compare(r.last) <= 0
+}
+
+// ParseTargets parses a comma-separated target list, e.g.
+// "10.0.0.0/24,10.0.1.5-10.0.1.50,printer.example.com,fd00::/64", and
+// removes the exclusions, which take the same forms.
+//
+// IPv4 prefixes up to /30 skip their network and broadcast addresses and
+// IPv6 prefixes their subnet-router anycast address; /31 and /127
+// point-to-point links and single-host prefixes keep every address.
+func ParseTargets(spec string, exclude []string) (*Targets, error) {
+	t := &Targets{names: map[netip.Addr]string{}}
+	var include []addrRange
+	for _, item := range strings.Split(spec, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		ranges, large, err := t.parseTarget(item, false)
+		if err != nil {
+			return nil, err
+		}
+		include = append(include, ranges...)
+		if large.IsValid() {
+			t.discover = append(t.discover, large)
+		}
+	}
+	for _, item := range exclude {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		ranges, _, err := t.parseTarget(item, true)
+		if err != nil {
+			return nil, fmt.Errorf("exclusion: %v", err)
+		}
+		t.exclude = append(t.exclude, ranges...)
+	}
+	if len(include) == 0 && len(t.discover) == 0 {
+		return nil, fmt.Errorf("no targets in %q", spec)
+	}
+	t.exclude = mergeRanges(t.exclude)
+	t.ranges = subtractRanges(mergeRanges(include), t.exclude)
+	return t, nil
+}
+
+// parseTarget expands one target into address ranges, or returns an
+// IPv6 prefix too large to sweep. Exclusions keep whole prefixes.
+func (t *Targets) parseTarget(item string, exclude bool) ([]addrRange, netip.Prefix, error) {
+	if strings.Contains(item, "/") {
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %v", item, err)
+		}
+		prefix = prefix.Masked()
+		if prefix.Addr().Is4In6() {
+			return nil, netip.Prefix{}, fmt.Errorf("invalid CIDR %q: IPv4-mapped prefix", item)
+		}
+		r := addrRange{prefix.Addr(), lastAddr(prefix)}
+		if exclude {
+			return []addrRange{r}, netip.Prefix{}, nil
+		}
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		switch {
+		case prefix.Addr().Is6() && hostBits > maxSweepBits6:
+			return nil, prefix, nil
+		case prefix.Addr().Is4() && hostBits > maxSweepBits4:
+			return nil, netip.Prefix{}, fmt.Errorf("CIDR %q is too large to scan", item)
+		case hostBits >= 2:
+			r.first = r.first.Next()
+			if prefix.Addr().Is4() {
+				r.last = r.last.Prev()
+			}
+		}
+		return []addrRange{r}, netip.Prefix{}, nil
+	}
+
+	if lo, hi, ok := strings.Cut(item, "-"); ok {
+		first, err1 := netip.ParseAddr(strings.TrimSpace(lo))
+		last, err2 := netip.ParseAddr(strings.TrimSpace(hi))
+		// Otherwise it is a hostname with a dash in it
+		if err1 == nil && err2 == nil {
+			first, last = first.Unmap(), last.Unmap()
+			if first.Is4() != last.Is4() || last.Less(first) {
+				return nil, netip.Prefix{}, fmt.Errorf("invalid address range %q", item)
+			}
+			maxBits := maxSweepBits4
+			if first.Is6() {
+				maxBits = maxSweepBits6
+			}
+			if !exclude && rangeSpan(first, last) > 1<<maxBits {
+				return nil, netip.Prefix{}, fmt.Errorf("address range %q is too large to scan", item)
+			}
+			return []addrRange{{first, last}}, netip.Prefix{}, nil
+		}
+	}
+
+	if addr, err := netip.ParseAddr(item); err == nil {
+		addr = addr.Unmap()
+		return []addrRange{{addr, addr}}, netip.Prefix{}, nil
+	}
+
+	ips, err := net.LookupIP(item)
+	if err != nil {
+		return nil, netip.Prefix{}, fmt.Errorf("invalid target %q: %v", item, err)
+	}
+	var ranges []addrRange
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		if !exclude {
+			t.names[addr] = item
+		}
+		ranges = append(ranges, addrRange{addr, addr})
+	}
+	return ranges, netip.Prefix{}, nil
+}
+
+// all yields every target address once, in order, without expanding the
+// ranges in memory.
+func (t *Targets) all() iter.Seq[netip.Addr] {
+	return func(yield func(netip.Addr) bool) {
+		for _, r := range t.ranges {
+			for addr := r.first; addr.IsValid() && addr.Compare(r.last) <= 0; addr = addr.Next() {
+				if !yield(addr) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// excluded reports whether addr is on the exclusion list.
+func (t *Targets) excluded(addr netip.Addr) bool {
+	return inRanges(t.exclude, addr)
+}
+
+// covers reports whether addr is among the swept targets.
+func (t *Targets) covers(addr netip.Addr) bool {
+	return inRanges(t.ranges, addr)
+}
+
+func inRanges(ranges []addrRange, addr netip.Addr) bool {
+	i, _ := slices.BinarySearchFunc(ranges, addr, func(r addrRange, addr netip.Addr) int {
+		return r.last.Compare(addr)
+	})
+	return i < len(ranges) && ranges[i].contains(addr)
+}
+
+// lastAddr is the highest address in prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// rangeSpan counts the addresses from first to last, saturating at 2^64.
+func rangeSpan(first, last netip.Addr) uint64 {
+	a, b := first.As16(), last.As16()
+	for i := 0; i < 8; i++ {
+		if a[i] != b[i] {
+			return 1<<64 - 1
+		}
+	}
+	var lo, hi uint64
+	for i := 8; i < 16; i++ {
+		lo, hi = lo<<8|uint64(a[i]), hi<<8|uint64(b[i])
+	}
+	if hi-lo == 1<<64-1 {
+		return hi - lo
+	}
+	return hi - lo + 1
+}
+
+// mergeRanges sorts ranges and joins the overlapping and adjacent ones.
+func mergeRanges(ranges []addrRange) []addrRange {
+	slices.SortFunc(ranges, func(a, b addrRange) int { return a.first.Compare(b.first) })
+	var merged []addrRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 {
+			prev := &merged[n-1]
+			next := prev.last.Next()
+			if prev.last.Is4() == r.first.Is4() && (!next.IsValid() || r.first.Compare(next) <= 0) {
+				if prev.last.Less(r.last) {
+					prev.last = r.last
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// subtractRanges removes the sorted, disjoint exclusions from the sorted,
+// disjoint ranges.
+func subtractRanges(ranges, exclude []addrRange) []addrRange {
+	var out []addrRange
+	for _, r := range ranges {
+		keep := true
+		for _, x := range exclude {
+			if x.last.Less(r.first) || r.last.Less(x.first) {
+				continue
+			}
+			if r.first.Less(x.first) {
+				out = append(out, addrRange{r.first, x.first.Prev()})
+			}
+			if !x.last.Less(r.last) {
+				keep = false
+				break
+			}
+			r.first = x.last.Next()
+		}
+		if keep {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,180 @@
+package scanner
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// rangesString renders ranges as "first-last,first-last".
+func rangesString(ranges []addrRange) string {
+	var parts []string
+	for _, r := range ranges {
+		parts = append(parts, r.first.String()+"-"+r.last.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseRanges(t *testing.T, spec string) []addrRange {
+	t.Helper()
+	var ranges []addrRange
+	for _, item := range strings.Split(spec, ",") {
+		first, last, _ := strings.Cut(item, "-")
+		ranges = append(ranges, addrRange{netip.MustParseAddr(first), netip.MustParseAddr(last)})
+	}
+	return ranges
+}
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		spec     string
+		exclude  []string
+		ranges   string
+		discover string
+		err      string
+	}{
+		{spec: "10.0.0.0/30", ranges: "10.0.0.1-10.0.0.2"},
+		{spec: "10.0.0.0/31", ranges: "10.0.0.0-10.0.0.1"},
+		{spec: "10.0.0.7/32", ranges: "10.0.0.7-10.0.0.7"},
+		{spec: "10.0.0.77/24", ranges: "10.0.0.1-10.0.0.254"},
+		{spec: "10.0.0.0/8", ranges: "10.0.0.1-10.255.255.254"},
+		{spec: "10.0.0.0/7", err: "too large"},
+		{spec: "2001:db8::/127", ranges: "2001:db8::-2001:db8::1"},
+		{spec: "2001:db8::/126", ranges: "2001:db8::1-2001:db8::3"},
+		{spec: "2001:db8::/112", ranges: "2001:db8::1-2001:db8::ffff"},
+		{spec: "2001:db8::/64", discover: "2001:db8::/64"},
+		{spec: "2001:db8::/64,10.0.0.1", ranges: "10.0.0.1-10.0.0.1", discover: "2001:db8::/64"},
+		{spec: "::ffff:10.0.0.0/120", err: "IPv4-mapped"},
+		{spec: "::ffff:10.0.0.1", ranges: "10.0.0.1-10.0.0.1"},
+		{spec: "10.0.0.10-10.0.0.20", ranges: "10.0.0.10-10.0.0.20"},
+		{spec: " 10.0.0.10 - 10.0.0.20 ", ranges: "10.0.0.10-10.0.0.20"},
+		{spec: "10.0.0.20-10.0.0.10", err: "invalid address range"},
+		{spec: "10.0.0.1-2001:db8::1", err: "invalid address range"},
+		{spec: "10.0.0.0-11.0.0.0", err: "too large"},
+		{spec: "2001:db8::-2001:db8::1:0", err: "too large"},
+		{spec: "2001:db8::-2001:db8::ffff", ranges: "2001:db8::-2001:db8::ffff"},
+		// Overlapping and adjacent targets merge; families stay apart
+		{spec: "10.0.0.1-10.0.0.10,10.0.0.5-10.0.0.20,10.0.0.21", ranges: "10.0.0.1-10.0.0.21"},
+		{spec: "2001:db8::1,10.0.0.2,10.0.0.1", ranges: "10.0.0.1-10.0.0.2,2001:db8::1-2001:db8::1"},
+		{spec: "255.255.255.255,::", ranges: "255.255.255.255-255.255.255.255,::-::"},
+		// Overlapping exclusions, whole prefixes even when larger than a sweep
+		{spec: "10.0.0.0/24", exclude: []string{"10.0.0.10-10.0.0.20", "10.0.0.8/29", "10.0.0.254"},
+			ranges: "10.0.0.1-10.0.0.7,10.0.0.21-10.0.0.253"},
+		{spec: "10.0.0.0/24", exclude: []string{"0.0.0.0/0"}, ranges: ""},
+		{spec: "10.0.0.1,2001:db8::1", exclude: []string{"::/0"}, ranges: "10.0.0.1-10.0.0.1"},
+		{spec: "10.0.0.0/24", exclude: []string{"10.0.0.9-10.0.0.1"}, err: "exclusion"},
+		{spec: "", err: "no targets"},
+		{spec: " , ", err: "no targets"},
+		{spec: "localhost", ranges: "127.0.0.1-127.0.0.1"},
+		// A dash that doesn't join two addresses is part of a hostname
+		{spec: "no-such-host.invalid", err: "invalid target"},
+	}
+	for _, tt := range tests {
+		targets, err := ParseTargets(tt.spec, tt.exclude)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseTargets(%q, %q) error = %v, want %q", tt.spec, tt.exclude, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTargets(%q, %q): %v", tt.spec, tt.exclude, err)
+			continue
+		}
+		var discover []string
+		for _, p := range targets.discover {
+			discover = append(discover, p.String())
+		}
+		if got := rangesString(targets.ranges); got != tt.ranges {
+			t.Errorf("ParseTargets(%q, %q) ranges = %q, want %q", tt.spec, tt.exclude, got, tt.ranges)
+		}
+		if got := strings.Join(discover, ","); got != tt.discover {
+			t.Errorf("ParseTargets(%q, %q) discover = %q, want %q", tt.spec, tt.exclude, got, tt.discover)
+		}
+	}
+}
+
+func TestTargetsLookups(t *testing.T) {
+	targets, err := ParseTargets("10.0.0.0/29,localhost", []string{"10.0.0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all []string
+	for addr := range targets.all() {
+		all = append(all, addr.String())
+	}
+	if got := strings.Join(all, ","); got != "10.0.0.1,10.0.0.2,10.0.0.4,10.0.0.5,10.0.0.6,127.0.0.1" {
+		t.Errorf("all() = %s", got)
+	}
+	if name := targets.names[netip.MustParseAddr("127.0.0.1")]; name != "localhost" {
+		t.Errorf("name of 127.0.0.1 = %q, want localhost", name)
+	}
+	for addr, want := range map[string][2]bool{
+		"10.0.0.0": {false, false},
+		"10.0.0.1": {false, true},
+		"10.0.0.3": {true, false},
+		"10.0.0.7": {false, false},
+		"10.0.1.1": {false, false},
+	} {
+		a := netip.MustParseAddr(addr)
+		if targets.excluded(a) != want[0] || targets.covers(a) != want[1] {
+			t.Errorf("%s: excluded = %v, covers = %v; want %v, %v", addr, targets.excluded(a), targets.covers(a), want[0], want[1])
+		}
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"10.0.0.5-10.0.0.9,10.0.0.1-10.0.0.4", "10.0.0.1-10.0.0.9"},
+		{"10.0.0.1-10.0.0.9,10.0.0.3-10.0.0.4", "10.0.0.1-10.0.0.9"},
+		{"10.0.0.1-10.0.0.4,10.0.0.6-10.0.0.9", "10.0.0.1-10.0.0.4,10.0.0.6-10.0.0.9"},
+		{"255.255.255.0-255.255.255.255,10.0.0.1-10.0.0.1", "10.0.0.1-10.0.0.1,255.255.255.0-255.255.255.255"},
+		// The IPv4 and IPv6 spaces don't join at 255.255.255.255 and ::
+		{"::-::1,255.255.255.254-255.255.255.255", "255.255.255.254-255.255.255.255,::-::1"},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fff0-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff,ffff:ffff:ffff:ffff:ffff:ffff:ffff:ff00-ffff:ffff:ffff:ffff:ffff:ffff:ffff:fff1",
+			"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ff00-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+	}
+	for _, tt := range tests {
+		if got := rangesString(mergeRanges(parseRanges(t, tt.in))); got != tt.want {
+			t.Errorf("mergeRanges(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSubtractRanges(t *testing.T) {
+	tests := []struct{ ranges, exclude, want string }{
+		{"10.0.0.1-10.0.0.9", "10.0.0.1-10.0.0.2", "10.0.0.3-10.0.0.9"},
+		{"10.0.0.1-10.0.0.9", "10.0.0.8-10.0.0.20", "10.0.0.1-10.0.0.7"},
+		{"10.0.0.1-10.0.0.9", "10.0.0.3-10.0.0.3,10.0.0.5-10.0.0.6", "10.0.0.1-10.0.0.2,10.0.0.4-10.0.0.4,10.0.0.7-10.0.0.9"},
+		{"10.0.0.1-10.0.0.9", "10.0.0.0-10.0.0.255", ""},
+		{"10.0.0.1-10.0.0.4,10.0.0.6-10.0.0.9", "10.0.0.3-10.0.0.7", "10.0.0.1-10.0.0.2,10.0.0.8-10.0.0.9"},
+		{"10.0.0.1-10.0.0.9,2001:db8::1-2001:db8::9", "2001:db8::5-2001:db8::5", "10.0.0.1-10.0.0.9,2001:db8::1-2001:db8::4,2001:db8::6-2001:db8::9"},
+	}
+	for _, tt := range tests {
+		got := rangesString(subtractRanges(parseRanges(t, tt.ranges), parseRanges(t, tt.exclude)))
+		if got != tt.want {
+			t.Errorf("subtractRanges(%s, %s) = %s, want %s", tt.ranges, tt.exclude, got, tt.want)
+		}
+	}
+}
+
+func TestRangeSpan(t *testing.T) {
+	const saturated = 1<<64 - 1
+	tests := []struct {
+		first, last string
+		want        uint64
+	}{
+		{"10.0.0.1", "10.0.0.1", 1},
+		{"10.0.0.0", "10.0.0.255", 256},
+		{"0.0.0.0", "255.255.255.255", 1 << 32},
+		{"2001:db8::", "2001:db8::ffff", 1 << 16},
+		{"2001:db8::", "2001:db8::ffff:ffff:ffff:fffe", saturated},
+		{"2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", saturated},
+		{"2001:db8::", "2001:db8:0:1::", saturated},
+	}
+	for _, tt := range tests {
+		if got := rangeSpan(netip.MustParseAddr(tt.first), netip.MustParseAddr(tt.last)); got != tt.want {
+			t.Errorf("rangeSpan(%s, %s) = %d, want %d", tt.first, tt.last, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package scanner
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+// SNMPv3 user-based security model: RFC 3414 (HMAC-MD5/SHA, DES), RFC 3826
+// (AES-128) and RFC 7860 (HMAC-SHA-2).
+
+const (
+	usmFlagAuth       = 0x01
+	usmFlagPriv       = 0x02
+	usmFlagReportable = 0x04
+)
+
+type authProtocol struct {
+	hash   func() hash.Hash
+	macLen int // Truncated HMAC length carried in msgAuthenticationParameters
+}
+
+// Keyed by upper-case name without dashes
+var authProtocols = map[string]authProtocol{
+	"MD5":    {md5.New, 12},
+	"SHA":    {sha1.New, 12},
+	"SHA1":   {sha1.New, 12},
+	"SHA224": {sha256.New224, 16},
+	"SHA256": {sha256.New, 24},
+	"SHA384": {sha512.New384, 32},
+	"SHA512": {sha512.New, 48},
+}
+
+var privProtocols = map[string]string{
+	"DES":    "DES",
+	"AES":    "AES",
+	"AES128": "AES",
+}
+
+func protocolKey(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", ""))
+}
+
+// usm holds one user's keys, localized to the engine it is talking to.
+type usm struct {
+	user     string
+	auth     *authProtocol // nil for noAuthNoPriv
+	priv     string        // "DES", "AES" or "" for no privacy
+	authPass string
+	privPass string
+
+	engineID   []byte // Empty until discovered
+	boots      int32
+	engineTime int32
+	syncedAt   time.Time
+	authKey    []byte
+	privKey    []byte
+	salt       uint64
+}
+
+func newUSM(c SNMPCredentials) (*usm, error) {
+	u := &usm{user: c.User, authPass: c.AuthPassword, privPass: c.PrivPassword}
+	if c.AuthPassword == "" {
+		if c.PrivPassword != "" {
+			return nil, errors.New("SNMPv3 privacy requires authentication")
+		}
+		return u, nil
+	}
+	name := c.AuthProtocol
+	if name == "" {
+		name = "SHA"
+	}
+	auth, ok := authProtocols[protocolKey(name)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SNMPv3 auth protocol %q", c.AuthProtocol)
+	}
+	u.auth = &auth
+	if c.PrivPassword != "" {
+		name := c.PrivProtocol
+		if name == "" {
+			name = "AES"
+		}
+		if u.priv, ok = privProtocols[protocolKey(name)]; !ok {
+			return nil, fmt.Errorf("unsupported SNMPv3 privacy protocol %q", c.PrivProtocol)
+		}
+	}
+	var salt [8]byte
+	rand.Read(salt[:])
+	u.salt = binary.BigEndian.Uint64(salt[:])
+	return u, nil
+}
+
+// flags is the security level for requests after discovery.
+func (u *usm) flags() byte {
+	var f byte
+	if u.auth != nil {
+		f |= usmFlagAuth
+	}
+	if u.priv != "" {
+		f |= usmFlagPriv
+	}
+	return f
+}
+
+// discovered records the authoritative engine's ID, boot count and time
+// from a report, localizing the keys on first contact.
+func (u *usm) discovered(engineID []byte, boots, engineTime int32) {
+	if u.auth != nil && string(engineID) != string(u.engineID) {
+		u.authKey = localizeKey(u.auth.hash, u.authPass, engineID)
+		if u.priv != "" {
+			u.privKey = localizeKey(u.auth.hash, u.privPass, engineID)
+		}
+	}
+	u.engineID = append([]byte{}, engineID...)
+	u.boots, u.engineTime, u.syncedAt = boots, engineTime, time.Now()
+}
+
+// now estimates the engine's clock for the time window check.
+func (u *usm) now() (int32, int32) {
+	if u.syncedAt.IsZero() {
+		return 0, 0
+	}
+	return u.boots, u.engineTime + int32(time.Since(u.syncedAt)/time.Second)
+}
+
+// localizeKey turns a password into a key bound to one engine (RFC 3414
+// A.2): hash a megabyte of the repeated password, then hash that key
+// around the engine ID.
+func localizeKey(newHash func() hash.Hash, password string, engineID []byte) []byte {
+	h := newHash()
+	if password != "" {
+		buf := make([]byte, 64)
+		for i, n := 0, 0; n < 1<<20; n += len(buf) {
+			for j := range buf {
+				buf[j] = password[i%len(password)]
+				i++
+			}
+			h.Write(buf)
+		}
+	}
+	ku := h.Sum(nil)
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+// sign fills field, the zeroed msgAuthenticationParameters inside msg,
+// with the message's truncated HMAC.
+func (u *usm) sign(msg, field []byte) {
+	copy(field, u.mac(msg))
+}
+
+// verify checks a received message's HMAC. field is the
+// msgAuthenticationParameters slice of msg.
+func (u *usm) verify(msg, field []byte) bool {
+	if u.authKey == nil || len(field) != u.auth.macLen {
+		return false
+	}
+	off := offsetIn(msg, field)
+	if off < 0 {
+		return false
+	}
+	zeroed := append([]byte{}, msg...)
+	clear(zeroed[off : off+len(field)])
+	return hmac.Equal(u.mac(zeroed), field)
+}
+
+func (u *usm) mac(msg []byte) []byte {
+	m := hmac.New(u.auth.hash, u.authKey)
+	m.Write(msg)
+	return m.Sum(nil)[:u.auth.macLen]
+}
+
+// offsetIn returns where sub starts inside msg, given that sub was sliced
+// from msg by the BER parser.
+func offsetIn(msg, sub []byte) int {
+	off := cap(msg) - cap(sub)
+	if off < 0 || off+len(sub) > len(msg) {
+		return -1
+	}
+	return off
+}
+
+// encrypt returns the encrypted scoped PDU and its msgPrivacyParameters.
+func (u *usm) encrypt(plain []byte, boots, engineTime int32) ([]byte, []byte, error) {
+	u.salt++
+	switch u.priv {
+	case "DES":
+		// RFC 3414 8.1.1.1: salt is the boot count and a local counter,
+		// IV is the salt XOR the key's second half
+		salt := binary.BigEndian.AppendUint32(nil, uint32(boots))
+		salt = binary.BigEndian.AppendUint32(salt, uint32(u.salt))
+		block, err := des.NewCipher(u.privKey[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = u.privKey[8+i] ^ salt[i]
+		}
+		padded := append([]byte{}, plain...)
+		for len(padded)%des.BlockSize != 0 {
+			padded = append(padded, 0)
+		}
+		out := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, padded)
+		return out, salt, nil
+	case "AES":
+		// RFC 3826 3.1.2.1: IV is boots, time and a 64-bit salt
+		salt := binary.BigEndian.AppendUint64(nil, u.salt)
+		block, err := aes.NewCipher(u.privKey[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+		out := make([]byte, len(plain))
+		cipher.NewCFBEncrypter(block, aesIV(boots, engineTime, salt)).XORKeyStream(out, plain)
+		return out, salt, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported SNMPv3 privacy protocol %q", u.priv)
+}
+
+func (u *usm) decrypt(data, privParams []byte, boots, engineTime int32) ([]byte, error) {
+	if len(privParams) != 8 {
+		return nil, errors.New("bad SNMPv3 privacy parameters")
+	}
+	switch u.priv {
+	case "DES":
+		if len(data)%des.BlockSize != 0 {
+			return nil, errors.New("bad SNMPv3 DES ciphertext length")
+		}
+		block, err := des.NewCipher(u.privKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = u.privKey[8+i] ^ privParams[i]
+		}
+		out := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+		return out, nil
+	case "AES":
+		block, err := aes.NewCipher(u.privKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCFBDecrypter(block, aesIV(boots, engineTime, privParams)).XORKeyStream(out, data)
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported SNMPv3 privacy protocol %q", u.priv)
+}
+
+func aesIV(boots, engineTime int32, salt []byte) []byte {
+	iv := binary.BigEndian.AppendUint32(nil, uint32(boots))
+	iv = binary.BigEndian.AppendUint32(iv, uint32(engineTime))
+	return append(iv, salt...)
+}
@@ -0,0 +1,88 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04 Mon", s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"30 2 * * *", "2024-03-10 01:00 Sun", "2024-03-10 02:30 Sun"},
+		{"30 2 * * *", "2024-03-10 02:30 Sun", "2024-03-11 02:30 Mon"},
+		{"*/15 * * * *", "2024-03-10 10:07 Sun", "2024-03-10 10:15 Sun"},
+		{"*/15 * * * *", "2024-03-10 23:45 Sun", "2024-03-11 00:00 Mon"},
+		{"5/20 * * * *", "2024-03-10 10:26 Sun", "2024-03-10 10:45 Sun"},
+		{"0-30/10 8 * * *", "2024-03-10 08:31 Sun", "2024-03-11 08:00 Mon"},
+		{"0 9 * * mon-fri", "2024-06-15 10:00 Sat", "2024-06-17 09:00 Mon"},
+		{"0 9 * * 1,3,5", "2024-06-17 09:00 Mon", "2024-06-19 09:00 Wed"},
+		{"0 0 * * 7", "2024-06-15 10:00 Sat", "2024-06-16 00:00 Sun"},
+		{"0 0 * * SUN", "2024-06-15 10:00 Sat", "2024-06-16 00:00 Sun"},
+		// Day of month and day of week both restricted: either matches
+		{"0 0 13 * 5", "2024-06-01 00:00 Sat", "2024-06-07 00:00 Fri"},
+		{"0 0 13 * 5", "2024-06-07 00:00 Fri", "2024-06-13 00:00 Thu"},
+		// A stepped "*" still counts as unrestricted
+		{"0 0 */2 * 1", "2024-06-01 00:00 Sat", "2024-06-03 00:00 Mon"},
+		{"0 0 1 jan,jul *", "2024-02-01 00:00 Thu", "2024-07-01 00:00 Mon"},
+		// Across month and year ends
+		{"0 12 1 * *", "2024-12-15 08:00 Sun", "2025-01-01 12:00 Wed"},
+		{"59 23 31 12 *", "2024-12-31 23:59 Tue", "2025-12-31 23:59 Wed"},
+		{"0 0 31 * *", "2024-04-01 00:00 Mon", "2024-05-31 00:00 Fri"},
+		{"0 0 29 feb *", "2025-03-01 00:00 Sat", "2028-02-29 00:00 Tue"},
+		{"@hourly", "2024-03-10 10:30 Sun", "2024-03-10 11:00 Sun"},
+		{"@daily", "2024-02-28 10:30 Wed", "2024-02-29 00:00 Thu"},
+		{"@weekly", "2024-06-17 10:30 Mon", "2024-06-23 00:00 Sun"},
+		{"@monthly", "2024-01-31 10:30 Wed", "2024-02-01 00:00 Thu"},
+		{"@yearly", "2024-06-17 10:30 Mon", "2025-01-01 00:00 Wed"},
+		{"@every 90m", "2024-03-10 23:00 Sun", "2024-03-11 00:30 Mon"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q from %s: got %s, want %s", tt.spec, tt.from, got.Format("2006-01-02 15:04 Mon"), tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct{ spec, err string }{
+		{"", "want 5 fields"},
+		{"* * * *", "want 5 fields"},
+		{"* * * * * *", "want 5 fields"},
+		{"60 * * * *", "minute"},
+		{"* 24 * * *", "hour"},
+		{"* * 0 * *", "day of month"},
+		{"* * * 13 *", "month"},
+		{"* * * * 8", "day of week"},
+		{"5-1 * * * *", "invalid range"},
+		{"0 0 * * fri-mon", "invalid range"},
+		{"*/0 * * * *", "invalid step"},
+		{"*/x * * * *", "invalid step"},
+		{"0 0 * foo *", "month"},
+		{"0 0 30 feb *", "never matches"},
+		{"0 0 31 apr,jun,sep,nov *", "never matches"},
+		{"@every 30s", "at least 1m"},
+		{"@every soon", "at least 1m"},
+		{"@fortnightly", "want 5 fields"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.spec); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.spec, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package vuln
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Debian release codenames by major version, as keyed in the security
+// tracker dump.
+var debianCodenames = map[string]string{
+	"10": "buster",
+	"11": "bullseye",
+	"12": "bookworm",
+	"13": "trixie",
+	"14": "forky",
+}
+
+// trackerIssue is one entry of the Debian security tracker's JSON dump
+// (https://security-tracker.debian.org/tracker/data/json), which maps
+// source package -> issue ID -> issue.
+type trackerIssue struct {
+	Description string `json:"description"`
+	Releases    map[string]struct {
+		Status       string `json:"status"` // "resolved", "open" or "undetermined"
+		FixedVersion string `json:"fixed_version"`
+		Urgency      string `json:"urgency"` // "low", "medium", "high", "unimportant", "not yet assigned"
+	} `json:"releases"`
+}
+
+// parseTrackerPackage decodes a member of the security tracker dump,
+// issue ID -> issue. Members of an OSV record never carry releases.
+func parseTrackerPackage(value []byte) (map[string]trackerIssue, bool) {
+	var issues map[string]trackerIssue
+	if err := json.Unmarshal(value, &issues); err != nil {
+		return nil, false
+	}
+	for _, issue := range issues {
+		if issue.Releases != nil {
+			return issues, true
+		}
+	}
+	return nil, false
+}
+
+// loadDebianTracker indexes the host release's entries of a security
+// tracker dump, given its first package. The dump is large, so the rest
+// is decoded from dec one package at a time.
+func (l *loader) loadDebianTracker(dec *json.Decoder, pkg string, issues map[string]trackerIssue) error {
+	if l.debianRelease == "" || !l.ecosystems[l.debianEcosystem] {
+		return nil
+	}
+	for {
+		l.addTrackerPackage(pkg, issues)
+		if !dec.More() {
+			return nil
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		pkg, _ = tok.(string)
+		issues = nil
+		if err := dec.Decode(&issues); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *loader) addTrackerPackage(pkg string, issues map[string]trackerIssue) {
+	for id, issue := range issues {
+		release, ok := issue.Releases[l.debianRelease]
+		if !ok {
+			continue
+		}
+		events := []osvEvent{{Introduced: "0"}}
+		switch release.Status {
+		case "resolved":
+			// Fixed in version 0 means the release was never affected
+			if release.FixedVersion == "" || release.FixedVersion == "0" {
+				continue
+			}
+			events = append(events, osvEvent{Fixed: release.FixedVersion})
+		case "open":
+			// Issues the security team rates as not affecting security
+			// support (e.g. a crash in a debugging tool) are never fixed
+			if release.Urgency == "unimportant" {
+				continue
+			}
+		default:
+			continue
+		}
+
+		var cves []string
+		if strings.HasPrefix(id, "CVE-") {
+			cves = []string{id}
+		}
+		l.add(l.debianEcosystem, pkg, &advisory{
+			id:       id,
+			cves:     cves,
+			summary:  issue.Description,
+			severity: normalizeSeverity(release.Urgency),
+			ranges:   []osvRange{{Type: "ECOSYSTEM", Events: events}},
+		})
+	}
+}
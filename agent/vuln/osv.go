@@ -0,0 +1,336 @@
+package vuln
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// osvRecord is the subset of the OSV schema (https://ossf.github.io/osv-schema/)
+// used for matching.
+type osvRecord struct {
+	ID               string          `json:"id"`
+	Aliases          []string        `json:"aliases"`
+	Upstream         []string        `json:"upstream"`
+	Summary          string          `json:"summary"`
+	Details          string          `json:"details"`
+	Withdrawn        string          `json:"withdrawn"`
+	Severity         []osvSeverity   `json:"severity"`
+	Affected         []osvAffected   `json:"affected"`
+	DatabaseSpecific osvSpecificData `json:"database_specific"`
+}
+
+type osvSeverity struct {
+	Type  string `json:"type"` // "CVSS_V3", "CVSS_V4", "Ubuntu"
+	Score string `json:"score"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges            []osvRange      `json:"ranges"`
+	Versions          []string        `json:"versions"`
+	Severity          []osvSeverity   `json:"severity"`
+	EcosystemSpecific osvSpecificData `json:"ecosystem_specific"`
+	DatabaseSpecific  osvSpecificData `json:"database_specific"`
+}
+
+type osvRange struct {
+	Type   string     `json:"type"` // "ECOSYSTEM", "SEMVER" or "GIT"
+	Events []osvEvent `json:"events"`
+}
+
+type osvEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// osvSpecificData holds the free-form database/ecosystem fields. Only a
+// textual severity ("HIGH", "Moderate", "Important") is used; databases
+// that put something else there are ignored.
+type osvSpecificData struct {
+	Severity any `json:"severity"`
+}
+
+func (d osvSpecificData) severity() string {
+	s, _ := d.Severity.(string)
+	return normalizeSeverity(s)
+}
+
+// loader builds the advisory index for a set of ecosystems.
+type loader struct {
+	ecosystems      map[string]bool
+	debianRelease   string // Codename for the security tracker dump, e.g. "bookworm"
+	debianEcosystem string
+	index           map[string][]*advisory
+	skipped         int
+}
+
+// loadFile reads a database file: a single OSV record, a JSON array of
+// records, the Debian security tracker dump, or a zip of OSV records as
+// published per ecosystem by osv.dev.
+func (l *loader) loadFile(path string) {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			l.skipped++
+			return
+		}
+		defer archive.Close()
+		for _, f := range archive.File {
+			if !strings.EqualFold(filepath.Ext(f.Name), ".json") {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				l.skipped++
+				continue
+			}
+			l.loadJSON(rc)
+			rc.Close()
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		l.skipped++
+		return
+	}
+	defer f.Close()
+	l.loadJSON(f)
+}
+
+// loadJSON decodes records as it reads them rather than reading the whole
+// file first, since the security tracker dump runs to hundreds of MB.
+func (l *loader) loadJSON(r io.Reader) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return
+	}
+	if err != nil {
+		l.skipped++
+		return
+	}
+	switch tok {
+	case json.Delim('['):
+		for dec.More() {
+			var record osvRecord
+			if err := dec.Decode(&record); err != nil {
+				l.skipped++
+				return
+			}
+			l.addRecord(&record)
+		}
+		return
+	case json.Delim('{'):
+	default:
+		l.skipped++
+		return
+	}
+
+	// An object is a single OSV record or the security tracker's package
+	// map, which is recognised by its first member
+	fields := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			l.skipped++
+			return
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			l.skipped++
+			return
+		}
+		if len(fields) == 0 {
+			if issues, ok := parseTrackerPackage(value); ok {
+				if err := l.loadDebianTracker(dec, key, issues); err != nil {
+					l.skipped++
+				}
+				return
+			}
+		}
+		fields[key] = value
+	}
+
+	var record osvRecord
+	if content, err := json.Marshal(fields); err != nil || json.Unmarshal(content, &record) != nil {
+		l.skipped++
+		return
+	}
+	if record.ID != "" {
+		l.addRecord(&record)
+	}
+}
+
+func (l *loader) addRecord(r *osvRecord) {
+	if r.Withdrawn != "" {
+		return
+	}
+	cves := []string{}
+	for _, id := range append(append([]string{r.ID}, r.Aliases...), r.Upstream...) {
+		if strings.HasPrefix(id, "CVE-") {
+			cves = appendUnique(cves, id)
+		}
+	}
+	sort.Strings(cves)
+	summary := r.Summary
+	if summary == "" {
+		summary, _, _ = strings.Cut(strings.TrimSpace(r.Details), "\n")
+	}
+
+	for i := range r.Affected {
+		a := &r.Affected[i]
+		for eco := range l.ecosystems {
+			if !inEcosystem(a.Package.Ecosystem, eco) {
+				continue
+			}
+			severity, score := a.rating(r)
+			l.add(eco, a.Package.Name, &advisory{
+				id:       r.ID,
+				cves:     cves,
+				summary:  summary,
+				severity: severity,
+				score:    score,
+				ranges:   a.Ranges,
+				versions: a.Versions,
+			})
+		}
+	}
+}
+
+func (l *loader) add(ecosystem, name string, adv *advisory) {
+	key := ecosystem + "|" + normalizeName(ecosystem, name)
+	l.index[key] = append(l.index[key], adv)
+}
+
+// rating prefers a CVSS v3 vector, which also gives a score, and falls back
+// to the database's own severity rating.
+func (a *osvAffected) rating(r *osvRecord) (string, float64) {
+	severities := append(append([]osvSeverity{}, a.Severity...), r.Severity...)
+	for _, s := range severities {
+		if s.Type == "CVSS_V3" {
+			if score, ok := cvss3BaseScore(s.Score); ok {
+				return severityRating(score), score
+			}
+		}
+	}
+	for _, s := range []string{a.EcosystemSpecific.severity(), a.DatabaseSpecific.severity(), r.DatabaseSpecific.severity()} {
+		if s != "" {
+			return s, 0
+		}
+	}
+	for _, s := range severities {
+		if s.Type == "Ubuntu" {
+			if rating := normalizeSeverity(s.Score); rating != "" {
+				return rating, 0
+			}
+		}
+	}
+	return "", 0
+}
+
+// affects evaluates the advisory against an installed version, returning
+// the versions that fix it.
+func (adv *advisory) affects(version string, cmp compareFunc) (bool, []string) {
+	hit := false
+	for _, v := range adv.versions {
+		if v == version {
+			hit = true
+		}
+	}
+
+	var fixed []string
+	for _, r := range adv.ranges {
+		c := cmp
+		switch r.Type {
+		case "ECOSYSTEM":
+		case "SEMVER":
+			c = compareSemver
+		default:
+			continue // GIT ranges need commit history
+		}
+		affected, fix := evaluateRange(version, r.Events, c)
+		if affected {
+			hit = true
+			if fix != "" {
+				fixed = appendUnique(fixed, fix)
+			}
+		}
+	}
+	return hit, fixed
+}
+
+// evaluateRange walks the events in version order as the OSV spec
+// describes: an introduced at or below the version makes it affected, a
+// fixed at or below (or a last_affected below) it clears that again. The
+// first fixed event above an affected version is the one that fixes it.
+func evaluateRange(version string, events []osvEvent, cmp compareFunc) (bool, string) {
+	sorted := append([]osvEvent{}, events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareEvents(sorted[i], sorted[j], cmp) < 0
+	})
+
+	affected := false
+	fix := ""
+	for _, e := range sorted {
+		switch {
+		case e.Introduced != "":
+			if e.Introduced == "0" || cmp(version, e.Introduced) >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if cmp(version, e.Fixed) >= 0 {
+				affected = false
+			} else if affected && fix == "" {
+				fix = e.Fixed
+			}
+		case e.LastAffected != "":
+			if cmp(version, e.LastAffected) > 0 {
+				affected = false
+			}
+		}
+	}
+	if !affected {
+		return false, ""
+	}
+	return true, fix
+}
+
+func compareEvents(a, b osvEvent, cmp compareFunc) int {
+	va, vb := a.version(), b.version()
+	// introduced "0" is below every version
+	switch {
+	case a.Introduced == "0" && b.Introduced == "0":
+		return 0
+	case a.Introduced == "0":
+		return -1
+	case b.Introduced == "0":
+		return 1
+	}
+	return cmp(va, vb)
+}
+
+func (e osvEvent) version() string {
+	switch {
+	case e.Introduced != "":
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	case e.LastAffected != "":
+		return e.LastAffected
+	default:
+		return e.Limit
+	}
+}
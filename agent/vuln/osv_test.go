@@ -0,0 +1,48 @@
+package vuln
+
+import "testing"
+
+func TestEvaluateRange(t *testing.T) {
+	introduced := func(v string) osvEvent { return osvEvent{Introduced: v} }
+	fixed := func(v string) osvEvent { return osvEvent{Fixed: v} }
+	lastAffected := func(v string) osvEvent { return osvEvent{LastAffected: v} }
+
+	tests := []struct {
+		name     string
+		version  string
+		events   []osvEvent
+		affected bool
+		fix      string
+	}{
+		{"before introduced", "1.0.0", []osvEvent{introduced("1.1.0"), fixed("1.2.0")}, false, ""},
+		{"at introduced", "1.1.0", []osvEvent{introduced("1.1.0"), fixed("1.2.0")}, true, "1.2.0"},
+		{"inside range", "1.1.5", []osvEvent{introduced("1.1.0"), fixed("1.2.0")}, true, "1.2.0"},
+		{"at fixed", "1.2.0", []osvEvent{introduced("1.1.0"), fixed("1.2.0")}, false, ""},
+		{"after fixed", "2.0.0", []osvEvent{introduced("1.1.0"), fixed("1.2.0")}, false, ""},
+		{"introduced zero", "0.0.1", []osvEvent{introduced("0"), fixed("1.0.0")}, true, "1.0.0"},
+		{"no fix yet", "9.9.9", []osvEvent{introduced("0")}, true, ""},
+		{"at last_affected", "1.4.0", []osvEvent{introduced("1.0.0"), lastAffected("1.4.0")}, true, ""},
+		{"after last_affected", "1.4.1", []osvEvent{introduced("1.0.0"), lastAffected("1.4.0")}, false, ""},
+		{"unsorted events", "1.5.0", []osvEvent{fixed("1.6.0"), introduced("1.0.0")}, true, "1.6.0"},
+		{"second range", "2.1.0",
+			[]osvEvent{introduced("1.0.0"), fixed("1.2.0"), introduced("2.0.0"), fixed("2.2.0")}, true, "2.2.0"},
+		{"between ranges", "1.5.0",
+			[]osvEvent{introduced("1.0.0"), fixed("1.2.0"), introduced("2.0.0"), fixed("2.2.0")}, false, ""},
+	}
+	for _, tt := range tests {
+		affected, fix := evaluateRange(tt.version, tt.events, compareSemver)
+		if affected != tt.affected || fix != tt.fix {
+			t.Errorf("%s: evaluateRange(%s) = %v, %q; want %v, %q", tt.name, tt.version, affected, fix, tt.affected, tt.fix)
+		}
+	}
+
+	// Distro ranges compare with the ecosystem's ordering
+	events := []osvEvent{introduced("0"), fixed("1:2.3-4.el9")}
+	if affected, fix := evaluateRange("1:2.3-3.el9", events, compareRPM); !affected || fix != "1:2.3-4.el9" {
+		t.Errorf("rpm range = %v, %q; want affected with fix 1:2.3-4.el9", affected, fix)
+	}
+	events = []osvEvent{introduced("0"), fixed("1.2-r0")}
+	if affected, _ := evaluateRange("1.2_rc1-r0", events, compareAPK); !affected {
+		t.Error("apk 1.2_rc1-r0 is before the fix 1.2-r0 and must be affected")
+	}
+}
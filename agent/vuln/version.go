@@ -0,0 +1,522 @@
+package vuln
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// compareFunc returns <0, 0 or >0 as a is older than, equal to or newer
+// than b.
+type compareFunc func(a, b string) int
+
+// comparatorFor picks the version ordering an OSV ecosystem uses.
+func comparatorFor(ecosystem string) compareFunc {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	switch base {
+	case "Debian", "Ubuntu":
+		return compareDeb
+	case "Red Hat", "AlmaLinux", "Rocky Linux", "openSUSE", "SUSE":
+		return compareRPM
+	case "Alpine":
+		return compareAPK
+	case "PyPI":
+		return comparePEP440
+	default:
+		// npm, crates.io, Go, RubyGems
+		return compareSemver
+	}
+}
+
+// compareDeb implements Debian's version ordering (deb-version(7)):
+// [epoch:]upstream[-revision], where letters sort before non-letters and
+// '~' before everything, even the end of the string.
+func compareDeb(a, b string) int {
+	ea, ua, ra := splitDebVersion(a)
+	eb, ub, rb := splitDebVersion(b)
+	if ea != eb {
+		if ea < eb {
+			return -1
+		}
+		return 1
+	}
+	if c := compareDebPart(ua, ub); c != 0 {
+		return c
+	}
+	return compareDebPart(ra, rb)
+}
+
+func splitDebVersion(v string) (int, string, string) {
+	epoch := 0
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch, _ = strconv.Atoi(v[:i])
+		v = v[i+1:]
+	}
+	revision := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		v, revision = v[:i], v[i+1:]
+	}
+	return epoch, v, revision
+}
+
+func debOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func compareDebPart(a, b string) int {
+	for a != "" || b != "" {
+		// Non-digit prefix, compared character by character
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			ca, cb := 0, 0
+			if a != "" && !isDigit(a[0]) {
+				ca = debOrder(a[0])
+			}
+			if b != "" && !isDigit(b[0]) {
+				cb = debOrder(b[0])
+			}
+			if ca != cb {
+				if ca < cb {
+					return -1
+				}
+				return 1
+			}
+			if a != "" && !isDigit(a[0]) {
+				a = a[1:]
+			}
+			if b != "" && !isDigit(b[0]) {
+				b = b[1:]
+			}
+		}
+		// Digit run, compared numerically
+		var da, db string
+		da, a = leadingDigits(a)
+		db, b = leadingDigits(b)
+		if c := compareNumeric(da, db); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareRPM implements rpm's [epoch:]version-release ordering with
+// rpmvercmp for each part.
+func compareRPM(a, b string) int {
+	ea, va, ra := splitRPMVersion(a)
+	eb, vb, rb := splitRPMVersion(b)
+	if c := compareNumeric(ea, eb); c != 0 {
+		return c
+	}
+	if c := rpmvercmp(va, vb); c != 0 {
+		return c
+	}
+	// A missing release matches any release
+	if ra == "" || rb == "" {
+		return 0
+	}
+	return rpmvercmp(ra, rb)
+}
+
+func splitRPMVersion(v string) (string, string, string) {
+	epoch := "0"
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch, v = v[:i], v[i+1:]
+	}
+	release := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		v, release = v[:i], v[i+1:]
+	}
+	return epoch, v, release
+}
+
+// rpmvercmp compares alternating alphabetic and numeric segments; other
+// characters only separate segments. '~' sorts before anything (pre-release)
+// and '^' after the base version but before any further segment.
+func rpmvercmp(a, b string) int {
+	for a != "" || b != "" {
+		a = strings.TrimLeftFunc(a, func(r rune) bool { return !isAlnum(r) && r != '~' && r != '^' })
+		b = strings.TrimLeftFunc(b, func(r rune) bool { return !isAlnum(r) && r != '~' && r != '^' })
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		var sa, sb string
+		if isDigit(a[0]) {
+			sa, a = leadingDigits(a)
+			sb, b = leadingDigits(b)
+			// Numeric segments are newer than alphabetic ones
+			if sb == "" {
+				return 1
+			}
+			if c := compareNumeric(sa, sb); c != 0 {
+				return c
+			}
+			continue
+		}
+		sa, a = leadingLetters(a)
+		sb, b = leadingLetters(b)
+		if sb == "" {
+			return -1
+		}
+		if c := strings.Compare(sa, sb); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// apk version token kinds, in the order apk-tools ranks them when two
+// versions differ in kind at the same position: the earlier kind is newer,
+// so 1.2.1 > 1.2a > 1.2_p1 > 1.2-r1 > 1.2.
+const (
+	apkDigit = iota
+	apkLetter
+	apkSuffix
+	apkRevision
+	apkEnd
+)
+
+// Suffix ranks: pre-release suffixes are below the release (0), patch-level
+// ones above it.
+var apkSuffixRank = map[string]int{
+	"alpha": -4, "beta": -3, "pre": -2, "rc": -1,
+	"cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5,
+}
+
+type apkToken struct {
+	kind  int
+	value string // digits, the letter, or the suffix number
+	rank  int    // suffix rank
+}
+
+// tokenizeAPK splits an apk version (1.2.3a_rc1_p2~hash-r4) into tokens.
+// The ~commit hash does not take part in ordering and is dropped.
+func tokenizeAPK(v string) []apkToken {
+	var tokens []apkToken
+	digits, v := leadingDigits(v)
+	tokens = append(tokens, apkToken{kind: apkDigit, value: digits})
+	for v != "" {
+		switch {
+		case v[0] == '.' && len(v) > 1 && isDigit(v[1]):
+			digits, v = leadingDigits(v[1:])
+			tokens = append(tokens, apkToken{kind: apkDigit, value: digits})
+		case v[0] >= 'a' && v[0] <= 'z' && tokens[len(tokens)-1].kind == apkDigit:
+			tokens = append(tokens, apkToken{kind: apkLetter, value: v[:1]})
+			v = v[1:]
+		case v[0] == '_':
+			var name, num string
+			name, v = leadingLetters(v[1:])
+			num, v = leadingDigits(v)
+			tokens = append(tokens, apkToken{kind: apkSuffix, value: num, rank: apkSuffixRank[name]})
+		case v[0] == '~':
+			if i := strings.Index(v, "-r"); i >= 0 {
+				v = v[i:]
+			} else {
+				v = ""
+			}
+		case strings.HasPrefix(v, "-r"):
+			digits, v = leadingDigits(v[2:])
+			tokens = append(tokens, apkToken{kind: apkRevision, value: digits})
+		default:
+			// Anything else is outside the apk grammar
+			return tokens
+		}
+	}
+	return tokens
+}
+
+// compareAPK implements apk-tools' version ordering:
+// _alpha < _beta < _pre < _rc < release < _cvs < _svn < _git < _hg < _p,
+// then the -rN package revision.
+func compareAPK(a, b string) int {
+	ta, tb := tokenizeAPK(a), tokenizeAPK(b)
+	for i := 0; ; i++ {
+		x, y := apkToken{kind: apkEnd}, apkToken{kind: apkEnd}
+		if i < len(ta) {
+			x = ta[i]
+		}
+		if i < len(tb) {
+			y = tb[i]
+		}
+		if x.kind != y.kind {
+			// A pre-release suffix is older than whatever the other
+			// version has in its place
+			switch {
+			case x.kind == apkSuffix && x.rank < 0:
+				return -1
+			case y.kind == apkSuffix && y.rank < 0:
+				return 1
+			}
+			return cmpInt(y.kind, x.kind)
+		}
+
+		var c int
+		switch x.kind {
+		case apkEnd:
+			return 0
+		case apkDigit:
+			// Components after the first with a leading zero compare as
+			// fractions: 1.01 < 1.1
+			if i > 0 && (strings.HasPrefix(x.value, "0") || strings.HasPrefix(y.value, "0")) {
+				c = strings.Compare(x.value, y.value)
+			} else {
+				c = compareNumeric(x.value, y.value)
+			}
+		case apkLetter:
+			c = strings.Compare(x.value, y.value)
+		case apkSuffix:
+			if c = cmpInt(x.rank, y.rank); c == 0 {
+				c = compareNumeric(x.value, y.value)
+			}
+		case apkRevision:
+			c = compareNumeric(x.value, y.value)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+}
+
+// compareSemver orders semantic versions, tolerating a "v" prefix, any
+// number of numeric segments and RubyGems-style pre-releases ("1.0.0.rc1").
+// Build metadata is ignored.
+func compareSemver(a, b string) int {
+	ca, pa := splitSemver(a)
+	cb, pb := splitSemver(b)
+	for i := 0; i < len(ca) || i < len(cb); i++ {
+		sa, sb := "0", "0"
+		if i < len(ca) {
+			sa = ca[i]
+		}
+		if i < len(cb) {
+			sb = cb[i]
+		}
+		if c := compareNumeric(sa, sb); c != 0 {
+			return c
+		}
+	}
+
+	// A pre-release is older than the release itself
+	switch {
+	case pa == nil && pb == nil:
+		return 0
+	case pa == nil:
+		return 1
+	case pb == nil:
+		return -1
+	}
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1 // Numeric identifiers sort before alphanumeric ones
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(pa[i], pb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(pa) - len(pb)
+}
+
+func splitSemver(v string) ([]string, []string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+")
+	core, pre, hasPre := strings.Cut(v, "-")
+
+	segments := strings.Split(core, ".")
+	var numeric []string
+	for i, s := range segments {
+		if _, err := strconv.Atoi(s); err != nil {
+			// RubyGems: the first non-numeric segment starts the pre-release
+			pre = strings.Join(append(segments[i:], strings.Split(pre, ".")...), ".")
+			pre = strings.TrimSuffix(pre, ".")
+			hasPre = true
+			break
+		}
+		numeric = append(numeric, s)
+	}
+	if !hasPre {
+		return numeric, nil
+	}
+	return numeric, strings.Split(pre, ".")
+}
+
+var pep440Re = regexp.MustCompile(`^v?(?:(\d+)!)?(\d+(?:\.\d+)*)` +
+	`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d*))?` +
+	`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d*))?` +
+	`(?:[-_.]?(dev)[-_.]?(\d*))?` +
+	`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+// pep440Key is a parsed PEP 440 version. Missing pre/post/dev parts use
+// sentinels so plain comparison gives the spec's ordering:
+// 1.0.dev0 < 1.0a1 < 1.0 < 1.0.post1.
+type pep440Key struct {
+	epoch   int
+	release []string
+	pre     [2]int // phase (a=0, b=1, rc=2), number
+	post    int
+	dev     int
+	local   string
+}
+
+const (
+	pepNone = -1 << 30
+	pepMax  = 1 << 30
+)
+
+func parsePEP440(v string) (pep440Key, bool) {
+	m := pep440Re.FindStringSubmatch(strings.ToLower(strings.TrimSpace(v)))
+	if m == nil {
+		return pep440Key{}, false
+	}
+	k := pep440Key{release: strings.Split(m[2], "."), post: pepNone, dev: pepMax, local: m[10]}
+	k.epoch, _ = strconv.Atoi(m[1])
+
+	switch m[3] {
+	case "":
+		k.pre = [2]int{pepMax, 0}
+	case "a", "alpha":
+		k.pre[0] = 0
+	case "b", "beta":
+		k.pre[0] = 1
+	default:
+		k.pre[0] = 2
+	}
+	if m[3] != "" {
+		k.pre[1], _ = strconv.Atoi(m[4])
+	}
+	if m[5] != "" {
+		k.post, _ = strconv.Atoi(m[5])
+	} else if m[6] != "" {
+		k.post, _ = strconv.Atoi(m[7])
+	}
+	if m[8] != "" {
+		k.dev, _ = strconv.Atoi(m[9])
+		// A dev release of a final version sorts before its pre-releases
+		if m[3] == "" && m[5] == "" && m[6] == "" {
+			k.pre = [2]int{pepNone, 0}
+		}
+	}
+	return k, true
+}
+
+// comparePEP440 orders Python versions per PEP 440. Strings that do not
+// parse fall back to semver ordering.
+func comparePEP440(a, b string) int {
+	ka, okA := parsePEP440(a)
+	kb, okB := parsePEP440(b)
+	if !okA || !okB {
+		return compareSemver(a, b)
+	}
+	if ka.epoch != kb.epoch {
+		return cmpInt(ka.epoch, kb.epoch)
+	}
+	for i := 0; i < len(ka.release) || i < len(kb.release); i++ {
+		sa, sb := "0", "0"
+		if i < len(ka.release) {
+			sa = ka.release[i]
+		}
+		if i < len(kb.release) {
+			sb = kb.release[i]
+		}
+		if c := compareNumeric(sa, sb); c != 0 {
+			return c
+		}
+	}
+	for _, pair := range [][2]int{{ka.pre[0], kb.pre[0]}, {ka.pre[1], kb.pre[1]}, {ka.post, kb.post}, {ka.dev, kb.dev}} {
+		if c := cmpInt(pair[0], pair[1]); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(ka.local, kb.local)
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareNumeric compares digit strings of any length.
+func compareNumeric(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return cmpInt(len(a), len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+func leadingDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func leadingLetters(s string) (string, string) {
+	i := 0
+	for i < len(s) && isLetter(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isAlnum(r rune) bool  { return r < 128 && (isDigit(byte(r)) || isLetter(byte(r))) }
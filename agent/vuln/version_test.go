@@ -0,0 +1,152 @@
+package vuln
+
+import "testing"
+
+// checkOrder asserts that each version in order is strictly older than
+// every later one, in both argument orders.
+func checkOrder(t *testing.T, name string, cmp compareFunc, order []string) {
+	t.Helper()
+	for i := range order {
+		for j := range order {
+			got := cmp(order[i], order[j])
+			want := cmpInt(i, j)
+			if got < 0 {
+				got = -1
+			} else if got > 0 {
+				got = 1
+			}
+			if got != want {
+				t.Errorf("%s(%q, %q) = %d, want %d", name, order[i], order[j], got, want)
+			}
+		}
+	}
+}
+
+func TestCompareDeb(t *testing.T) {
+	checkOrder(t, "compareDeb", compareDeb, []string{
+		"1.0~rc1-1",
+		"1.0-1",
+		"1.0-1+deb12u1",
+		"1.0-2",
+		"1.0a-1",
+		"1.0+dfsg-1",
+		"1.0.1~~-1",
+		"1.0.1~-1",
+		"1.0.1-1",
+		"1.10-1",
+		"1:0.9-1",
+		"2:0.1",
+	})
+	for _, eq := range [][2]string{{"0:1.2-3", "1.2-3"}, {"1.02", "1.2"}} {
+		if c := compareDeb(eq[0], eq[1]); c != 0 {
+			t.Errorf("compareDeb(%q, %q) = %d, want 0", eq[0], eq[1], c)
+		}
+	}
+}
+
+func TestCompareRPM(t *testing.T) {
+	checkOrder(t, "compareRPM", compareRPM, []string{
+		"1.0~rc1-1.el9",
+		"1.0-1.el9",
+		"1.0-1.el9_2",
+		"1.0^20230101git1234-1",
+		"1.0a-1",
+		"1.0.1~beta-1",
+		"1.0.1-1",
+		"1.10-1",
+		"1:0.5-1",
+	})
+	// A range bound without a release matches every release
+	if c := compareRPM("2.3-4.el9", "2.3"); c != 0 {
+		t.Errorf("compareRPM(2.3-4.el9, 2.3) = %d, want 0", c)
+	}
+}
+
+func TestComparePEP440(t *testing.T) {
+	checkOrder(t, "comparePEP440", comparePEP440, []string{
+		"1.0.dev0",
+		"1.0a1.dev1",
+		"1.0a1",
+		"1.0b2",
+		"1.0rc1",
+		"1.0",
+		"1.0.post1.dev0",
+		"1.0.post1",
+		"1.0.1",
+		"1.1.dev1",
+		"1.1",
+		"1!0.1",
+	})
+	for _, eq := range [][2]string{{"1.0", "1.0.0"}, {"1.0-1", "1.0.post1"}, {"1.0RC1", "1.0c1"}, {"v2.0", "2.0"}} {
+		if c := comparePEP440(eq[0], eq[1]); c != 0 {
+			t.Errorf("comparePEP440(%q, %q) = %d, want 0", eq[0], eq[1], c)
+		}
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	checkOrder(t, "compareSemver", compareSemver, []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1.rc1",
+		"1.0.1",
+		"v1.2.0",
+		"1.10.0",
+	})
+	if c := compareSemver("1.0.0+build.5", "1.0.0"); c != 0 {
+		t.Errorf("compareSemver ignores build metadata: got %d", c)
+	}
+}
+
+func TestCompareAPK(t *testing.T) {
+	checkOrder(t, "compareAPK", compareAPK, []string{
+		"1.2_alpha1-r0",
+		"1.2_alpha2-r0",
+		"1.2_beta-r0",
+		"1.2_pre1-r0",
+		"1.2_rc1-r0",
+		"1.2_rc1-r1",
+		"1.2-r0",
+		"1.2-r1",
+		"1.2-r10",
+		"1.2_git20230101-r0",
+		"1.2_p1-r0",
+		"1.2_p2-r0",
+		"1.2a-r0",
+		"1.2.0-r0",
+		"1.2.1_rc2-r0",
+		"1.2.1-r0",
+		"1.10-r0",
+	})
+	if c := compareAPK("1.2.3~a1b2c3-r1", "1.2.3-r1"); c != 0 {
+		t.Errorf("compareAPK ignores the commit hash: got %d", c)
+	}
+	if c := compareAPK("1.01-r0", "1.1-r0"); c >= 0 {
+		t.Errorf("compareAPK(1.01, 1.1) = %d, want < 0", c)
+	}
+}
+
+func TestComparatorFor(t *testing.T) {
+	tests := []struct {
+		ecosystem string
+		older     string
+		newer     string
+	}{
+		{"Alpine:v3.19", "1.2_rc1-r0", "1.2-r0"},
+		{"Debian:12", "1.0~rc1-1", "1.0-1"},
+		{"Red Hat", "1.0~rc1-1", "1.0-1"},
+		{"PyPI", "1.0rc1", "1.0"},
+		{"npm", "1.0.0-rc.1", "1.0.0"},
+	}
+	for _, tt := range tests {
+		if c := comparatorFor(tt.ecosystem)(tt.older, tt.newer); c >= 0 {
+			t.Errorf("%s: %s vs %s = %d, want < 0", tt.ecosystem, tt.older, tt.newer, c)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// Package vuln matches the installed software inventory against a
+// vulnerability database the backend syncs to disk (OSV records or the
+// Debian security tracker dump). Matching runs entirely offline.
+package vuln
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"assetronics-agent/collector"
+)
+
+// Scanner holds the advisories for the host's ecosystems, indexed by
+// package. The index is rebuilt when the database files change.
+type Scanner struct {
+	dir   string
+	mu    sync.Mutex
+	stamp string                 // Database state and ecosystems the index was built for
+	index map[string][]*advisory // Keyed by ecosystem + "|" + package name
+}
+
+// advisory is the part of an advisory needed to match one package.
+type advisory struct {
+	id       string
+	cves     []string
+	summary  string
+	severity string
+	score    float64
+	ranges   []osvRange
+	versions []string // Explicitly affected versions
+}
+
+// Directory under the agent data directory the backend syncs the database
+// into. Any layout of .json and .zip files below it is accepted.
+const databaseDir = "osv"
+
+// New returns a Scanner for the database in dataDir. Nothing is loaded
+// until the first Scan.
+func New(dataDir string) *Scanner {
+	return &Scanner{dir: filepath.Join(dataDir, databaseDir)}
+}
+
+// Scan returns the advisories affecting info.InstalledSoftware. It returns
+// nil when no database has been synced yet.
+func (s *Scanner) Scan(info *collector.SystemInfo) []collector.Vulnerability {
+	type target struct {
+		sw        collector.Software
+		ecosystem string
+	}
+	var targets []target
+	ecosystems := map[string]bool{}
+	for _, sw := range info.InstalledSoftware {
+		if eco := ecosystemFor(sw.Source, info.DistroID, info.DistroVersion); eco != "" {
+			targets = append(targets, target{sw, eco})
+			ecosystems[eco] = true
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(ecosystems, info.DistroID, info.DistroVersion); err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Warning: failed to load vulnerability database: %v\n", err)
+		}
+		return nil
+	}
+
+	found := map[string]*collector.Vulnerability{}
+	var order []string
+	for _, t := range targets {
+		cmp := comparatorFor(t.ecosystem)
+		// Distro advisories are filed against the source package
+		names := []string{normalizeName(t.ecosystem, t.sw.Name)}
+		if t.sw.SourcePackage != "" {
+			names = append(names, t.sw.SourcePackage)
+		}
+		for _, name := range names {
+			for _, adv := range s.index[t.ecosystem+"|"+name] {
+				affected, fixed := adv.affects(t.sw.Version, cmp)
+				if !affected {
+					continue
+				}
+				key := t.sw.Name + "|" + t.sw.Version + "|" + t.sw.Architecture + "|" + adv.id
+				v, ok := found[key]
+				if !ok {
+					v = &collector.Vulnerability{
+						Package:  t.sw.Name,
+						Version:  t.sw.Version,
+						Source:   t.sw.Source,
+						ID:       adv.id,
+						CVEs:     adv.cves,
+						Summary:  adv.summary,
+						Severity: adv.severity,
+						Score:    adv.score,
+					}
+					found[key] = v
+					order = append(order, key)
+				}
+				v.FixedVersions = appendUnique(v.FixedVersions, fixed...)
+			}
+		}
+	}
+
+	vulns := make([]collector.Vulnerability, 0, len(order))
+	for _, key := range order {
+		vulns = append(vulns, *found[key])
+	}
+	sort.SliceStable(vulns, func(i, j int) bool {
+		if ri, rj := severityRank(vulns[i].Severity), severityRank(vulns[j].Severity); ri != rj {
+			return ri > rj
+		}
+		if vulns[i].Package != vulns[j].Package {
+			return vulns[i].Package < vulns[j].Package
+		}
+		return vulns[i].ID < vulns[j].ID
+	})
+	return vulns
+}
+
+// refresh rebuilds the index when the database files or the wanted
+// ecosystems changed since the last load.
+func (s *Scanner) refresh(ecosystems map[string]bool, distroID, distroVersion string) error {
+	var files []string
+	var newest time.Time
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if d.IsDir() || (ext != ".json" && ext != ".zip") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	wanted := make([]string, 0, len(ecosystems))
+	for eco := range ecosystems {
+		wanted = append(wanted, eco)
+	}
+	sort.Strings(wanted)
+	stamp := fmt.Sprintf("%d|%d|%s", len(files), newest.UnixNano(), strings.Join(wanted, ","))
+	if stamp == s.stamp {
+		return nil
+	}
+
+	l := &loader{
+		ecosystems: ecosystems,
+		index:      map[string][]*advisory{},
+	}
+	if distroID == "debian" {
+		major, _, _ := strings.Cut(distroVersion, ".")
+		l.debianRelease = debianCodenames[major]
+		l.debianEcosystem = "Debian:" + major
+	}
+	for _, path := range files {
+		l.loadFile(path)
+	}
+	if l.skipped > 0 {
+		fmt.Printf("Warning: skipped %d unreadable vulnerability database entries in %s\n", l.skipped, s.dir)
+	}
+	s.index, s.stamp = l.index, stamp
+	return nil
+}
+
+// ecosystemFor maps a package manager and distribution to the OSV ecosystem
+// its advisories are published under, or "" if there is none.
+func ecosystemFor(source, distroID, distroVersion string) string {
+	major, _, _ := strings.Cut(distroVersion, ".")
+	switch source {
+	case collector.SourceDpkg:
+		switch {
+		case distroVersion == "":
+		case distroID == "debian":
+			return "Debian:" + major
+		case distroID == "ubuntu":
+			return "Ubuntu:" + distroVersion
+		}
+	case collector.SourceRPM:
+		switch {
+		case distroID == "opensuse-tumbleweed":
+			return "openSUSE:Tumbleweed"
+		case distroVersion == "":
+		case distroID == "almalinux":
+			return "AlmaLinux:" + major
+		case distroID == "rocky":
+			return "Rocky Linux:" + major
+		case distroID == "rhel":
+			return "Red Hat:enterprise_linux:" + major
+		case distroID == "opensuse-leap":
+			return "openSUSE:Leap " + distroVersion
+		case distroID == "sles":
+			// VERSION_ID "15.5" is service pack 5
+			return "SUSE:Linux Enterprise Server " + strings.Replace(distroVersion, ".", " SP", 1)
+		}
+	case collector.SourceApk:
+		if parts := strings.SplitN(distroVersion, ".", 3); distroID == "alpine" && len(parts) >= 2 {
+			return "Alpine:v" + parts[0] + "." + parts[1]
+		}
+	case collector.SourcePyPI:
+		return "PyPI"
+	case collector.SourceNpm:
+		return "npm"
+	case collector.SourceGem:
+		return "RubyGems"
+	case collector.SourceCargo:
+		return "crates.io"
+	case collector.SourceGo:
+		return "Go"
+	}
+	return ""
+}
+
+// inEcosystem reports whether an advisory's ecosystem falls under a wanted
+// one: "Ubuntu:22.04:LTS" under "Ubuntu:22.04", and
+// "Red Hat:enterprise_linux:9::appstream" under "Red Hat:enterprise_linux:9".
+func inEcosystem(ecosystem, wanted string) bool {
+	return ecosystem == wanted || strings.HasPrefix(ecosystem, wanted+":")
+}
+
+var pypiSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizeName applies the ecosystem's package name equivalence rules.
+func normalizeName(ecosystem, name string) string {
+	if ecosystem == "PyPI" {
+		return pypiSeparators.ReplaceAllString(strings.ToLower(name), "-")
+	}
+	return name
+}
+
+// normalizeSeverity maps the ratings used by the various databases to the
+// CVSS qualitative scale.
+func normalizeSeverity(s string) string {
+	switch strings.ToUpper(strings.TrimRight(strings.TrimSpace(s), "*")) {
+	case "CRITICAL":
+		return "CRITICAL"
+	case "HIGH", "IMPORTANT":
+		return "HIGH"
+	case "MEDIUM", "MODERATE":
+		return "MEDIUM"
+	case "LOW", "NEGLIGIBLE", "UNIMPORTANT":
+		return "LOW"
+	default:
+		return ""
+	}
+}
+
+func severityRank(s string) int {
+	switch s {
+	case "CRITICAL":
+		return 4
+	case "HIGH":
+		return 3
+	case "MEDIUM":
+		return 2
+	case "LOW":
+		return 1
+	default:
+		return 0
+	}
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		dup := false
+		for _, existing := range list {
+			if existing == v {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, v)
+		}
+	}
+	return list
+}